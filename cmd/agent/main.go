@@ -1,12 +1,17 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"os"
 
 	"github.com/gostuding/go-metrics/internal/agent"
-	"go.uber.org/zap"
+	"github.com/gostuding/go-metrics/internal/agent/metrics"
+	"github.com/gostuding/go-metrics/internal/config"
+	"github.com/gostuding/go-metrics/internal/tracing"
 )
 
 var (
@@ -16,17 +21,40 @@ var (
 )
 
 func main() {
-	fmt.Fprintf(os.Stdout, "Build version: %s\n", buildVersion)
-	fmt.Fprintf(os.Stdout, "Build date: %s\n", buildDate)
-	fmt.Fprintf(os.Stdout, "Build commit: %s\n", buildCommit)
 	cfg, err := agent.NewConfig()
+	if errors.Is(err, config.ErrPrinted) || errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
 		log.Fatalln(err)
 	}
-	logger, err := zap.NewDevelopment()
+	// Stdout is used for metrics output, so build info is written to stderr.
+	info := os.Stdout
+	if cfg.Output == metrics.OutputStdout {
+		info = os.Stderr
+	}
+	fmt.Fprintf(info, "Build version: %s\n", buildVersion)
+	fmt.Fprintf(info, "Build date: %s\n", buildDate)
+	fmt.Fprintf(info, "Build commit: %s\n", buildCommit)
+	logger, err := agent.NewLogger(cfg.Logging())
 	if err != nil {
 		log.Fatalln("create logger error:", err)
 	}
+	shutdown, err := tracing.Setup(context.Background(), "agent", cfg.Tracing())
+	if err != nil {
+		log.Fatalln("setup tracing error:", err)
+	}
+	defer func() {
+		if err := shutdown(context.Background()); err != nil {
+			logger.Sugar().Warnf("shutdown tracing error: %v", err)
+		}
+	}()
 	agent := agent.NewAgent(cfg, logger)
+	if cfg.Once {
+		if err = agent.RunOnce(); err != nil {
+			logger.Sugar().Errorf("run once error: %v", err)
+		}
+		return
+	}
 	agent.StartAgent()
 }
@@ -1,43 +1,178 @@
+// Keys is RSA keys management command for messages encryption.
+//
+// Usage:
+//
+//	keys generate [-size 4096] [-dir .] [-name ""] [-force] - creates private_key (0600) and public_key (0644) files;
+//	keys inspect file... - prints keys types, sizes and fingerprints;
+//	keys rotate [-size 4096] [-dir .] [-name ""] [-force] - moves current keys to files with '.old' suffix
+//	and creates new keys.
+//
+// '-name' is files names prefix, for example 'agent' for agent_private_key and agent_public_key files.
+// Without subcommand keys are generated, for example 'keys -size 2048'.
 package main
 
 import (
-	"crypto/rand"
-	"crypto/rsa"
-	"crypto/x509"
-	"encoding/pem"
+	"flag"
+	"fmt"
+	"io"
 	"log"
 	"os"
+	"strings"
+
+	"github.com/gostuding/go-metrics/internal/rsakeys"
 )
 
-var (
-	keySize              = 4096
-	fileBits os.FileMode = 0644
+// Subcommands names.
+const (
+	cmdGenerate = "generate"
+	cmdInspect  = "inspect"
+	cmdRotate   = "rotate"
 )
 
+// options is private struct. Generate and rotate options.
+type options struct {
+	dir   string // keys files directory
+	name  string // keys files names prefix
+	size  int    // key size in bits
+	force bool   // flag to overwrite existing files
+}
+
 func main() {
-	key, err := rsa.GenerateKey(rand.Reader, keySize)
+	args := os.Args[1:]
+	command := cmdGenerate
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+	var err error
+	switch command {
+	case cmdGenerate:
+		err = generate(os.Stdout, args)
+	case cmdRotate:
+		err = rotate(os.Stdout, args)
+	case cmdInspect:
+		err = inspect(os.Stdout, args)
+	default:
+		err = fmt.Errorf("unknown command '%s', use '%s', '%s' or '%s'", command, cmdGenerate, cmdInspect, cmdRotate)
+	}
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// parseOptions is private func. Parses generate and rotate options.
+func parseOptions(command string, args []string) (*options, error) {
+	var o options
+	fs := flag.NewFlagSet(command, flag.ContinueOnError)
+	fs.StringVar(&o.dir, "dir", ".", "keys files directory")
+	fs.StringVar(&o.name, "name", "", "keys files names prefix, for example 'agent'")
+	fs.IntVar(&o.size, "size", rsakeys.DefaultSize, "key size in bits")
+	fs.BoolVar(&o.force, "force", false, "overwrite existing files")
+	if err := fs.Parse(args); err != nil {
+		return nil, err //nolint:wrapcheck //<-senselessly
+	}
+	return &o, nil
+}
+
+// generate is private func. Creates new keys files.
+func generate(w io.Writer, args []string) error {
+	o, err := parseOptions(cmdGenerate, args)
+	if err != nil {
+		return err
+	}
+	key, err := rsakeys.Generate(o.size)
+	if err != nil {
+		return err //nolint:wrapcheck //<-senselessly
+	}
+	pair := rsakeys.NewPair(o.dir, o.name)
+	if err = pair.Save(key, o.force); err != nil {
+		return err //nolint:wrapcheck //<-senselessly
+	}
+	return printPair(w, "new", pair)
+}
+
+// rotate is private func. Moves current keys files and creates new ones.
+func rotate(w io.Writer, args []string) error {
+	o, err := parseOptions(cmdRotate, args)
+	if err != nil {
+		return err
+	}
+	key, err := rsakeys.Generate(o.size)
+	if err != nil {
+		return err //nolint:wrapcheck //<-senselessly
+	}
+	pair := rsakeys.NewPair(o.dir, o.name)
+	old, err := pair.Rotate(key, o.force)
+	if err != nil {
+		return err //nolint:wrapcheck //<-senselessly
+	}
+	if err = printPair(w, "old", old); err != nil {
+		return err
+	}
+	if err = printPair(w, "new", pair); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "use both private keys on server until agents get the new public key: -crypto-key %s,%s\n",
+		pair.Private, old.Private)
+	return err //nolint:wrapcheck //<-senselessly
+}
+
+// inspect is private func. Prints keys files descriptions.
+func inspect(w io.Writer, files []string) error {
+	if len(files) == 0 {
+		return fmt.Errorf("usage: keys %s file...", cmdInspect)
+	}
+	for _, path := range files {
+		if err := printFile(w, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printPair is private func. Prints keys pair fingerprint.
+func printPair(w io.Writer, title string, pair rsakeys.Pair) error {
+	key, err := rsakeys.ReadPrivate(pair.Private)
+	if err != nil {
+		return err //nolint:wrapcheck //<-senselessly
+	}
+	fingerprint, err := rsakeys.Fingerprint(&key.PublicKey)
 	if err != nil {
-		log.Fatalf("generate keys error: %v", err)
+		return err //nolint:wrapcheck //<-senselessly
 	}
-	pubASN1, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	_, err = fmt.Fprintf(w, "%s keys: %s, %s (RSA %d, %s)\n",
+		title, pair.Private, pair.Public, key.N.BitLen(), fingerprint)
+	return err //nolint:wrapcheck //<-senselessly
+}
+
+// printFile is private func. Prints all keys in file and warnings about permissions and PEM types.
+func printFile(w io.Writer, path string) error {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		log.Fatalf("marshal public key error: %v", err)
+		return fmt.Errorf("file read error: %w", err)
 	}
-	pubBytes := pem.EncodeToMemory(&pem.Block{
-		Type:  "AGENT PUBLIC KEY",
-		Bytes: pubASN1,
-	})
-	if err = os.WriteFile("public_key", pubBytes, fileBits); err != nil {
-		log.Fatalf("save public key error: %v", err)
+	items, err := rsakeys.Inspect(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("file info error: %w", err)
 	}
-	prvBytes := x509.MarshalPKCS1PrivateKey(key)
-	prvPem := pem.EncodeToMemory(
-		&pem.Block{
-			Type:  "SERVER PRIVATE KEY",
-			Bytes: prvBytes,
-		},
-	)
-	if err = os.WriteFile("private_key", prvPem, fileBits); err != nil {
-		log.Fatalf("save private key error: %v", err)
+	for _, item := range items {
+		kind := "public"
+		if item.Private {
+			kind = "private"
+		}
+		line := fmt.Sprintf("%s: %s key '%s' RSA %d %s", path, kind, item.Type, item.Bits, item.Fingerprint)
+		if item.Legacy {
+			line += ", non-standard PEM type"
+		}
+		if item.Private && info.Mode().Perm()&0o077 != 0 {
+			line += fmt.Sprintf(", insecure permissions %04o", info.Mode().Perm())
+		}
+		if _, err = fmt.Fprintln(w, line); err != nil {
+			return err //nolint:wrapcheck //<-senselessly
+		}
 	}
+	return nil
 }
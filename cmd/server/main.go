@@ -1,12 +1,17 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"os"
 
+	"github.com/gostuding/go-metrics/internal/config"
 	"github.com/gostuding/go-metrics/internal/server"
 	"github.com/gostuding/go-metrics/internal/server/storage"
+	"github.com/gostuding/go-metrics/internal/tracing"
 	"go.uber.org/zap"
 )
 
@@ -21,14 +26,19 @@ type Server interface {
 	StopServer() error
 }
 
-func run(logger *zap.SugaredLogger) error {
+func run(cfg *server.Config, logger *zap.SugaredLogger) error {
 	var strg server.Storage
 	var strErr error
 
-	cfg, err := server.NewConfig()
+	shutdown, err := tracing.Setup(context.Background(), "server", cfg.Tracing())
 	if err != nil {
-		return fmt.Errorf("create config error: %w", err)
+		return fmt.Errorf("setup tracing error: %w", err)
 	}
+	defer func() {
+		if err := shutdown(context.Background()); err != nil {
+			logger.Warnf("shutdown tracing error: %v", err)
+		}
+	}()
 	if cfg.ConnectDBString == "" {
 		strg, strErr = storage.NewMemStorage(cfg.Restore, cfg.FileStorePath, cfg.StoreInterval)
 	} else {
@@ -50,11 +60,18 @@ func main() {
 	fmt.Fprintf(os.Stdout, "Build version: %s\n", buildVersion)
 	fmt.Fprintf(os.Stdout, "Build date: %s\n", buildDate)
 	fmt.Fprintf(os.Stdout, "Build commit: %s\n", buildCommit)
-	logger, err := server.NewLogger()
+	cfg, err := server.NewConfig()
+	if errors.Is(err, config.ErrPrinted) || errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("create config error: %v", err)
+	}
+	logger, err := server.NewLogger(cfg.Logging())
 	if err != nil {
 		log.Fatal(err)
 	}
-	err = run(logger)
+	err = run(cfg, logger)
 	if err != nil {
 		logger.Fatalln(err)
 	}
@@ -15,6 +15,10 @@
 // Stylecheck - analyzes  that enforce style rules (STxxxx).
 // Unused - contains code for finding unused code (U1000).
 // Osexit - privent using os.Exit function in main package.
+// Sqlrows - find not closed *sql.Rows, rows.Err() not checked after rows.Next() and stale error variables.
+// Ctxprop - find context.Background(), context.TODO() and http.NewRequest where a context is available.
+// Wrapverb - privent using %w verb in printf-like functions except fmt.Errorf (zap.SugaredLogger's
+// formatting methods, functions from -wrapverb.funcs flag and their wrappers).
 package analyzers
 
 import (
@@ -24,7 +28,10 @@ import (
 	"golang.org/x/tools/go/analysis/passes/shift"
 	"golang.org/x/tools/go/analysis/passes/structtag"
 
+	"github.com/gostuding/go-metrics/cmd/staticlint/analyzers/ctxprop"
 	"github.com/gostuding/go-metrics/cmd/staticlint/analyzers/osexit"
+	"github.com/gostuding/go-metrics/cmd/staticlint/analyzers/sqlrows"
+	"github.com/gostuding/go-metrics/cmd/staticlint/analyzers/wrapverb"
 	"honnef.co/go/tools/quickfix"
 	"honnef.co/go/tools/simple"
 	"honnef.co/go/tools/staticcheck"
@@ -34,11 +41,12 @@ import (
 
 // GetAnalyzers creates []*analysis.Analyzer for multichecker.
 func GetAnalyzers() []*analysis.Analyzer {
-	count := 6 + len(staticcheck.Analyzers) + len(simple.Analyzers) +
+	count := 9 + len(staticcheck.Analyzers) + len(simple.Analyzers) +
 		len(quickfix.Analyzers) + len(stylecheck.Analyzers)
 	checks := make([]*analysis.Analyzer, 0, count)
 	checks = append(checks, osexit.OsExitAnalyzer, printf.Analyzer, shadow.Analyzer,
-		structtag.Analyzer, shift.Analyzer, unused.Analyzer.Analyzer)
+		structtag.Analyzer, shift.Analyzer, unused.Analyzer.Analyzer, wrapverb.WrapVerbAnalyzer,
+		sqlrows.SQLRowsAnalyzer, ctxprop.CtxPropAnalyzer)
 	for _, v := range staticcheck.Analyzers {
 		checks = append(checks, v.Analyzer)
 	}
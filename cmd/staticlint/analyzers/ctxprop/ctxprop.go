@@ -0,0 +1,180 @@
+// Package ctxprop contains an Analyzer that find broken context propagation:
+// functions, which receive context.Context, but pass context.Background() or context.TODO()
+// to functions accepting context, and http.NewRequest calls, where a context is in scope.
+package ctxprop
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+const (
+	contextPkg      = "context"               // context package path
+	contextType     = "Context"               // context type name
+	backgroundFunc  = "context.Background"    // empty context func
+	todoFunc        = "context.TODO"          // empty context func
+	newRequestFunc  = "net/http.NewRequest"   // request func without context
+	withContextName = "NewRequestWithContext" // request func with context
+	backgroundMsg   = "%s() is used, but function receives context '%s'"
+	newRequestMsg   = "http.NewRequest is used, but context '%s' is in scope: use http.NewRequestWithContext"
+	backgroundFix   = "Replace %s() with %s"
+	newRequestFix   = "Replace http.NewRequest with http.NewRequestWithContext(%s, ...)"
+)
+
+// CtxPropAnalyzer is an analyzer.
+var CtxPropAnalyzer = &analysis.Analyzer{
+	Name:     "ctxprop",
+	Doc:      "analyze to find context.Background(), context.TODO() and http.NewRequest where a context is available",
+	Run:      CheckCtxProp,
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+}
+
+// CheckCtxProp checks calls in all functions.
+func CheckCtxProp(pass *analysis.Pass) (interface{}, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector) //nolint:forcetypeassert //<-senselessly
+	nodeTypes := []ast.Node{(*ast.CallExpr)(nil)}
+	inspect.WithStack(nodeTypes, func(node ast.Node, push bool, stack []ast.Node) bool {
+		if !push {
+			return true
+		}
+		call := node.(*ast.CallExpr) //nolint:forcetypeassert //<-senselessly
+		fn := typeutil.StaticCallee(pass.TypesInfo, call)
+		if fn != nil && fn.FullName() == newRequestFunc {
+			if ctx := contextInScope(pass, call); ctx != nil {
+				reportNewRequest(pass, call, ctx.Name())
+			}
+			return true
+		}
+		ctx := receivedContext(pass, stack)
+		if ctx == nil {
+			return true
+		}
+		sig, ok := pass.TypesInfo.TypeOf(call.Fun).(*types.Signature)
+		if !ok {
+			return true
+		}
+		for i, arg := range call.Args {
+			if !isContext(paramType(sig, i)) {
+				continue
+			}
+			if name := emptyContext(pass, arg); name != "" {
+				pass.Report(analysis.Diagnostic{
+					Pos:     arg.Pos(),
+					End:     arg.End(),
+					Message: fmt.Sprintf(backgroundMsg, name, ctx.Name()),
+					SuggestedFixes: []analysis.SuggestedFix{{
+						Message:   fmt.Sprintf(backgroundFix, name, ctx.Name()),
+						TextEdits: []analysis.TextEdit{{Pos: arg.Pos(), End: arg.End(), NewText: []byte(ctx.Name())}},
+					}},
+				})
+			}
+		}
+		return true
+	})
+	return nil, nil //nolint:all //<-senselessly
+}
+
+// isContext is private func. Checks if type is context.Context.
+func isContext(t types.Type) bool {
+	named, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == contextPkg && obj.Name() == contextType
+}
+
+// paramType is private func. Returns type of the call's argument with index i.
+func paramType(sig *types.Signature, i int) types.Type {
+	params := sig.Params()
+	if sig.Variadic() && i >= params.Len()-1 {
+		if slice, ok := params.At(params.Len() - 1).Type().(*types.Slice); ok {
+			return slice.Elem()
+		}
+		return nil
+	}
+	if i >= params.Len() {
+		return nil
+	}
+	return params.At(i).Type()
+}
+
+// emptyContext is private func. Returns function name if expression is context.Background() or context.TODO() call.
+func emptyContext(pass *analysis.Pass, expr ast.Expr) string {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return ""
+	}
+	fn := typeutil.StaticCallee(pass.TypesInfo, call)
+	if fn == nil {
+		return ""
+	}
+	if name := fn.FullName(); name == backgroundFunc || name == todoFunc {
+		return name
+	}
+	return ""
+}
+
+// receivedContext is private func. Returns context param of the nearest function in stack, which receives context.
+// Nested function literals without context params use context of the outer function.
+func receivedContext(pass *analysis.Pass, stack []ast.Node) *types.Var {
+	for i := len(stack) - 1; i >= 0; i-- {
+		var ftype *ast.FuncType
+		switch fn := stack[i].(type) {
+		case *ast.FuncDecl:
+			ftype = fn.Type
+		case *ast.FuncLit:
+			ftype = fn.Type
+		default:
+			continue
+		}
+		for _, field := range ftype.Params.List {
+			for _, name := range field.Names {
+				v, ok := pass.TypesInfo.Defs[name].(*types.Var)
+				if ok && name.Name != "_" && isContext(v.Type()) {
+					return v
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// contextInScope is private func. Returns context variable, which is declared before the call
+// in the innermost scope. Package level variables are skipped.
+func contextInScope(pass *analysis.Pass, call *ast.CallExpr) *types.Var {
+	for scope := pass.Pkg.Scope().Innermost(call.Pos()); scope != nil && scope != pass.Pkg.Scope(); scope = scope.Parent() {
+		for _, name := range scope.Names() {
+			v, ok := scope.Lookup(name).(*types.Var)
+			if ok && name != "_" && v.Pos() < call.Pos() && isContext(v.Type()) {
+				return v
+			}
+		}
+	}
+	return nil
+}
+
+// reportNewRequest is private func. Reports http.NewRequest call with fix to http.NewRequestWithContext.
+func reportNewRequest(pass *analysis.Pass, call *ast.CallExpr, ctx string) {
+	diagnostic := analysis.Diagnostic{
+		Pos:     call.Pos(),
+		End:     call.End(),
+		Message: fmt.Sprintf(newRequestMsg, ctx),
+	}
+	if sel, ok := call.Fun.(*ast.SelectorExpr); ok && len(call.Args) > 0 {
+		diagnostic.SuggestedFixes = []analysis.SuggestedFix{{
+			Message: fmt.Sprintf(newRequestFix, ctx),
+			TextEdits: []analysis.TextEdit{
+				{Pos: sel.Sel.Pos(), End: sel.Sel.End(), NewText: []byte(withContextName)},
+				{Pos: call.Args[0].Pos(), End: call.Args[0].Pos(), NewText: []byte(ctx + ", ")},
+			},
+		}}
+	}
+	pass.Report(diagnostic)
+}
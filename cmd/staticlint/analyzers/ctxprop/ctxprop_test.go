@@ -0,0 +1,11 @@
+package ctxprop
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestCheckCtxProp(t *testing.T) {
+	analysistest.RunWithSuggestedFixes(t, analysistest.TestData(), CtxPropAnalyzer, "pkg1")
+}
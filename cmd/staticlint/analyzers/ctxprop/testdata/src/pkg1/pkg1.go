@@ -0,0 +1,55 @@
+package pkg1
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+func save(ctx context.Context, name string) error {
+	return ctx.Err()
+}
+
+func saveAll(ctx context.Context, names ...string) error {
+	return ctx.Err()
+}
+
+func background(ctx context.Context) error {
+	if err := save(context.Background(), "name"); err != nil { // want "context.Background\\(\\) is used, but function receives context 'ctx'"
+		return err
+	}
+	timeout, cancel := context.WithTimeout(context.TODO(), time.Second) // want "context.TODO\\(\\) is used, but function receives context 'ctx'"
+	defer cancel()
+	return save(timeout, "name")
+}
+
+func closure(ctx context.Context) error {
+	send := func() error {
+		return saveAll(context.Background(), "a", "b") // want "context.Background\\(\\) is used, but function receives context 'ctx'"
+	}
+	return send()
+}
+
+func withoutContext() error {
+	return save(context.Background(), "name")
+}
+
+func ignoredContext(_ context.Context) error {
+	return save(context.Background(), "name")
+}
+
+func newRequest(ctx context.Context, url string) (*http.Request, error) {
+	return http.NewRequest(http.MethodPost, url, nil) // want "http.NewRequest is used, but context 'ctx' is in scope: use http.NewRequestWithContext"
+}
+
+func localContext(url string) (*http.Request, error) {
+	if _, err := http.NewRequest(http.MethodGet, url, nil); err != nil {
+		return nil, err
+	}
+	reqCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := save(reqCtx, url); err != nil {
+		return nil, err
+	}
+	return http.NewRequest(http.MethodGet, url, nil) // want "http.NewRequest is used, but context 'reqCtx' is in scope: use http.NewRequestWithContext"
+}
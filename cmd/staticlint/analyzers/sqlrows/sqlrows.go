@@ -0,0 +1,332 @@
+// Package sqlrows contains an Analyzer that find misuses of *sql.Rows:
+// rows which are not closed on all paths, rows.Err() which is not checked after rows.Next()
+// and stale error variable, which is wrapped or returned instead of rows.Err() result.
+package sqlrows
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/ctrlflow"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/cfg"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+const (
+	sqlPkg          = "database/sql" // rows package path
+	rowsType        = "Rows"         // rows type name
+	closeMethod     = "Close"        // rows close method
+	nextMethod      = "Next"         // rows iteration method
+	errMethod       = "Err"          // rows iteration error method
+	errorfFunc      = "fmt.Errorf"   // wrapping function
+	notClosedMsg    = "%s.Close() is not called on all paths (possible connection leak)"
+	notCheckedMsg   = "%s.Err() is not checked after %s.Next() returns false"
+	staleErrorMsg   = "stale error variable '%s' is used instead of %s.Err() result"
+	notClosedReturn = "this return statement may be reached without %s.Close() call"
+)
+
+// SQLRowsAnalyzer is an analyzer.
+var SQLRowsAnalyzer = &analysis.Analyzer{
+	Name:     "sqlrows",
+	Doc:      "analyze to find not closed *sql.Rows, unchecked rows.Err() and stale error variables",
+	Run:      CheckSQLRows,
+	Requires: []*analysis.Analyzer{inspect.Analyzer, ctrlflow.Analyzer},
+}
+
+// rowsVar contains *sql.Rows variable and its definition.
+type rowsVar struct {
+	stmt ast.Node   // defining statement
+	err  *types.Var // error variable from the same statement
+}
+
+// CheckSQLRows checks all functions in package.
+func CheckSQLRows(pass *analysis.Pass) (interface{}, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector) //nolint:forcetypeassert //<-senselessly
+	cfgs := pass.ResultOf[ctrlflow.Analyzer].(*ctrlflow.CFGs)         //nolint:forcetypeassert //<-senselessly
+	nodeTypes := []ast.Node{(*ast.FuncDecl)(nil), (*ast.FuncLit)(nil)}
+	inspect.Preorder(nodeTypes, func(node ast.Node) {
+		var body *ast.BlockStmt
+		var g *cfg.CFG
+		switch fn := node.(type) {
+		case *ast.FuncDecl:
+			body, g = fn.Body, cfgs.FuncDecl(fn)
+		case *ast.FuncLit:
+			body, g = fn.Body, cfgs.FuncLit(fn)
+		}
+		if body == nil || g == nil {
+			return
+		}
+		checkStaleErrors(pass, body)
+		for v, item := range findRows(pass, body) {
+			if ret := notClosedPath(pass, g, v, item); ret != nil {
+				pass.ReportRangef(item.stmt, notClosedMsg, v.Name())
+				pass.ReportRangef(ret, notClosedReturn, v.Name())
+			}
+			if next := uncheckedNext(pass, body, v); next != nil {
+				pass.ReportRangef(next, notCheckedMsg, v.Name(), v.Name())
+			}
+		}
+	})
+	return nil, nil //nolint:all //<-senselessly
+}
+
+// isRows is private func. Checks if type is *sql.Rows.
+func isRows(t types.Type) bool {
+	ptr, ok := t.(*types.Pointer)
+	if !ok {
+		return false
+	}
+	named, ok := ptr.Elem().(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == sqlPkg && obj.Name() == rowsType
+}
+
+// isError is private func. Checks if type is error.
+func isError(t types.Type) bool {
+	return types.Identical(t, types.Universe.Lookup("error").Type())
+}
+
+// findRows is private func. Returns *sql.Rows variables defined by calls in function body.
+// Nested functions are skipped, they are checked separately.
+func findRows(pass *analysis.Pass, body *ast.BlockStmt) map[*types.Var]rowsVar {
+	vars := make(map[*types.Var]rowsVar)
+	ast.Inspect(body, func(node ast.Node) bool {
+		var names []ast.Expr
+		var values []ast.Expr
+		switch stmt := node.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.AssignStmt:
+			names, values = stmt.Lhs, stmt.Rhs
+		case *ast.ValueSpec:
+			for _, name := range stmt.Names {
+				names = append(names, name)
+			}
+			values = stmt.Values
+		default:
+			return true
+		}
+		if len(values) != 1 {
+			return true
+		}
+		if _, ok := values[0].(*ast.CallExpr); !ok {
+			return true
+		}
+		var item rowsVar
+		rows := make([]*types.Var, 0, 1)
+		for _, name := range names {
+			v := identVar(pass, name)
+			switch {
+			case v == nil:
+			case isRows(v.Type()):
+				rows = append(rows, v)
+			case isError(v.Type()):
+				item.err = v
+			}
+		}
+		item.stmt = node
+		for _, v := range rows {
+			vars[v] = item
+		}
+		return true
+	})
+	return vars
+}
+
+// identVar is private func. Returns variable of identifier.
+func identVar(pass *analysis.Pass, expr ast.Expr) *types.Var {
+	ident, ok := expr.(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	if v, ok := pass.TypesInfo.Defs[ident].(*types.Var); ok {
+		return v
+	}
+	v, _ := pass.TypesInfo.Uses[ident].(*types.Var)
+	return v
+}
+
+// methodCall is private func. Checks if node is call of the variable's method.
+func methodCall(pass *analysis.Pass, node ast.Node, v *types.Var, method string) bool {
+	call, ok := node.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != method {
+		return false
+	}
+	return v == nil && isRows(pass.TypesInfo.TypeOf(sel.X)) || v != nil && identVar(pass, sel.X) == v
+}
+
+// releases is private func. Checks if nodes close rows or pass rows to another owner:
+// return rows, assign rows to another variable or field, send rows to channel.
+func releases(pass *analysis.Pass, v *types.Var, nodes []ast.Node) bool {
+	found := false
+	uses := func(exprs ...ast.Expr) {
+		for _, expr := range exprs {
+			if identVar(pass, expr) == v {
+				found = true
+			}
+		}
+	}
+	for _, node := range nodes {
+		ast.Inspect(node, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.CallExpr:
+				found = found || methodCall(pass, n, v, closeMethod)
+			case *ast.ReturnStmt:
+				uses(n.Results...)
+			case *ast.AssignStmt:
+				uses(n.Rhs...)
+			case *ast.CompositeLit:
+				uses(n.Elts...)
+			case *ast.KeyValueExpr:
+				uses(n.Value)
+			case *ast.SendStmt:
+				uses(n.Value)
+			}
+			return !found
+		})
+	}
+	return found
+}
+
+// notClosedPath is private func. Searches CFG path from rows definition to return statement,
+// where rows are not closed. Returns the return statement or nil.
+// The branch, where error from the same statement is not nil, is skipped: rows are nil there.
+func notClosedPath(pass *analysis.Pass, g *cfg.CFG, v *types.Var, item rowsVar) *ast.ReturnStmt {
+	var defblock *cfg.Block
+	var rest []ast.Node
+	for _, b := range g.Blocks {
+		for i, n := range b.Nodes {
+			if n == item.stmt {
+				defblock, rest = b, b.Nodes[i+1:]
+			}
+		}
+	}
+	if defblock == nil || releases(pass, v, rest) {
+		return nil
+	}
+	if ret := defblock.Return(); ret != nil {
+		return ret
+	}
+	succs := defblock.Succs
+	if len(rest) > 0 && len(succs) == 2 { //nolint:gomnd //<-senselessly
+		switch nilCheck(pass, rest[len(rest)-1], item.err) {
+		case token.NEQ:
+			succs = succs[1:]
+		case token.EQL:
+			succs = succs[:1]
+		}
+	}
+	seen := make(map[*cfg.Block]bool)
+	var search func(blocks []*cfg.Block) *ast.ReturnStmt
+	search = func(blocks []*cfg.Block) *ast.ReturnStmt {
+		for _, b := range blocks {
+			if seen[b] {
+				continue
+			}
+			seen[b] = true
+			if releases(pass, v, b.Nodes) {
+				continue
+			}
+			if ret := b.Return(); ret != nil {
+				return ret
+			}
+			if ret := search(b.Succs); ret != nil {
+				return ret
+			}
+		}
+		return nil
+	}
+	return search(succs)
+}
+
+// nilCheck is private func. Returns comparison operator if node is 'err != nil' or 'err == nil'.
+func nilCheck(pass *analysis.Pass, node ast.Node, err *types.Var) token.Token {
+	cond, ok := node.(*ast.BinaryExpr)
+	if !ok || err == nil || (cond.Op != token.NEQ && cond.Op != token.EQL) {
+		return token.ILLEGAL
+	}
+	if identVar(pass, cond.X) == err && pass.TypesInfo.Types[cond.Y].IsNil() ||
+		identVar(pass, cond.Y) == err && pass.TypesInfo.Types[cond.X].IsNil() {
+		return cond.Op
+	}
+	return token.ILLEGAL
+}
+
+// uncheckedNext is private func. Returns the last rows.Next() call if rows.Err() is not called after it.
+func uncheckedNext(pass *analysis.Pass, body *ast.BlockStmt, v *types.Var) *ast.CallExpr {
+	var next *ast.CallExpr
+	var checked token.Pos
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch {
+		case methodCall(pass, node, v, nextMethod):
+			next = node.(*ast.CallExpr) //nolint:forcetypeassert //<-senselessly
+		case methodCall(pass, node, v, errMethod):
+			checked = node.Pos()
+		}
+		return true
+	})
+	if next == nil || checked > next.Pos() {
+		return nil
+	}
+	return next
+}
+
+// checkStaleErrors is private func. Reports error variables, which are wrapped or returned
+// in 'if rows.Err() != nil' body: the result of rows.Err() is lost there.
+func checkStaleErrors(pass *analysis.Pass, body *ast.BlockStmt) {
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.IfStmt:
+			cond, ok := n.Cond.(*ast.BinaryExpr)
+			if !ok || cond.Op != token.NEQ || !pass.TypesInfo.Types[cond.Y].IsNil() ||
+				!methodCall(pass, cond.X, nil, errMethod) {
+				return true
+			}
+			rows := cond.X.(*ast.CallExpr).Fun.(*ast.SelectorExpr).X //nolint:forcetypeassert //<-senselessly
+			for _, ident := range staleErrors(pass, n.Body) {
+				pass.ReportRangef(ident, staleErrorMsg, ident.Name, types.ExprString(rows))
+			}
+		}
+		return true
+	})
+}
+
+// staleErrors is private func. Returns error identifiers, which are wrapped by fmt.Errorf or returned.
+func staleErrors(pass *analysis.Pass, body *ast.BlockStmt) []*ast.Ident {
+	idents := make([]*ast.Ident, 0)
+	add := func(exprs []ast.Expr) {
+		for _, expr := range exprs {
+			v := identVar(pass, expr)
+			if v != nil && isError(v.Type()) {
+				idents = append(idents, expr.(*ast.Ident)) //nolint:forcetypeassert //<-senselessly
+			}
+		}
+	}
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.ReturnStmt:
+			add(n.Results)
+		case *ast.CallExpr:
+			if fn := typeutil.StaticCallee(pass.TypesInfo, n); fn != nil && fn.FullName() == errorfFunc {
+				add(n.Args)
+			}
+		}
+		return true
+	})
+	return idents
+}
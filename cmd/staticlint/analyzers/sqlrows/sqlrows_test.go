@@ -0,0 +1,11 @@
+package sqlrows
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestCheckSQLRows(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), SQLRowsAnalyzer, "pkg1")
+}
@@ -0,0 +1,144 @@
+package pkg1
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+func closed(ctx context.Context, db *sql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx, "select name from metrics;")
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+	names := make([]string, 0)
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("scan error: %w", err)
+		}
+		names = append(names, name)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return names, nil
+}
+
+func notClosed(ctx context.Context, db *sql.DB) (int, error) {
+	rows, err := db.QueryContext(ctx, "select name from metrics;") // want "rows.Close\\(\\) is not called on all paths"
+	if err != nil {
+		return 0, fmt.Errorf("query error: %w", err)
+	}
+	count := 0
+	for rows.Next() {
+		count++
+		if count > 100 {
+			return count, errors.New("too many rows") // want "this return statement may be reached without rows.Close\\(\\) call"
+		}
+	}
+	err = rows.Err()
+	rows.Close()
+	return count, err
+}
+
+func closedInLoop(ctx context.Context, db *sql.DB, tables []string) (int, error) {
+	count := 0
+	for _, table := range tables {
+		rows, err := db.QueryContext(ctx, "select name from "+table+";")
+		if err != nil {
+			return 0, fmt.Errorf("query error: %w", err)
+		}
+		for rows.Next() {
+			count++
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return 0, fmt.Errorf("rows error: %w", err)
+		}
+	}
+	return count, nil
+}
+
+func returned(ctx context.Context, db *sql.DB) (*sql.Rows, error) {
+	rows, err := db.QueryContext(ctx, "select name from metrics;")
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	return rows, nil
+}
+
+func notChecked(ctx context.Context, db *sql.DB) (int, error) {
+	rows, err := db.QueryContext(ctx, "select name from metrics;")
+	if err != nil {
+		return 0, fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+	count := 0
+	for rows.Next() { // want "rows.Err\\(\\) is not checked after rows.Next\\(\\) returns false"
+		count++
+	}
+	return count, nil
+}
+
+func staleError(ctx context.Context, db *sql.DB) (int64, error) {
+	rows, err := db.QueryContext(ctx, "select value from counters where name=$1;", "name")
+	if err != nil {
+		return 0, fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+	if rows.Err() != nil {
+		return 0, fmt.Errorf("rows error: %w", err) // want "stale error variable 'err' is used instead of rows.Err\\(\\) result"
+	}
+	if !rows.Next() { // want "rows.Err\\(\\) is not checked after rows.Next\\(\\) returns false"
+		return 0, errors.New("value is absent")
+	}
+	var value int64
+	if err = rows.Scan(&value); err != nil {
+		return 0, fmt.Errorf("scan error: %w", err)
+	}
+	return value, nil
+}
+
+func staleReturn(rows *sql.Rows, err error) error {
+	if rows.Err() != nil {
+		return err // want "stale error variable 'err' is used instead of rows.Err\\(\\) result"
+	}
+	return nil
+}
+
+func singleRow(ctx context.Context, db *sql.DB) (int64, error) {
+	rows, err := db.QueryContext(ctx, "select value from counters where name=$1;", "name")
+	if err != nil {
+		return 0, fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+	var value int64
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return 0, fmt.Errorf("rows error: %w", err)
+		}
+		return 0, errors.New("value is absent")
+	}
+	if err = rows.Scan(&value); err != nil {
+		return 0, fmt.Errorf("scan error: %w", err)
+	}
+	return value, nil
+}
+
+func inFuncLit(ctx context.Context, db *sql.DB) error {
+	read := func() error {
+		rows, err := db.QueryContext(ctx, "select name from metrics;") // want "rows.Close\\(\\) is not called on all paths"
+		if err != nil {
+			return fmt.Errorf("query error: %w", err)
+		}
+		if rows.Next() { // want "rows.Err\\(\\) is not checked after rows.Next\\(\\) returns false"
+			return nil // want "this return statement may be reached without rows.Close\\(\\) call"
+		}
+		return rows.Close()
+	}
+	return read()
+}
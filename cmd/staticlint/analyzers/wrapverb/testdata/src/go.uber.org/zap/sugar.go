@@ -0,0 +1,13 @@
+// Package zap is a stub of go.uber.org/zap for analyzer tests.
+package zap
+
+// SugaredLogger is a stub of zap.SugaredLogger.
+type SugaredLogger struct{}
+
+func (s *SugaredLogger) Debugf(template string, args ...interface{})  {}
+func (s *SugaredLogger) Infof(template string, args ...interface{})   {}
+func (s *SugaredLogger) Warnf(template string, args ...interface{})   {}
+func (s *SugaredLogger) Errorf(template string, args ...interface{})  {}
+func (s *SugaredLogger) DPanicf(template string, args ...interface{}) {}
+func (s *SugaredLogger) Panicf(template string, args ...interface{})  {}
+func (s *SugaredLogger) Fatalf(template string, args ...interface{})  {}
@@ -0,0 +1,45 @@
+package pkg1
+
+import (
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+const wrapFormat = "constant error: %w"
+
+type logger struct {
+	log *zap.SugaredLogger
+}
+
+func (l *logger) Warnf(format string, args ...interface{}) { // want Warnf:"printfWrapper"
+	l.log.Warnf(format, args...)
+}
+
+func logf(l *logger, format string, args ...interface{}) { // want logf:"printfWrapper"
+	l.Warnf(format, args...)
+}
+
+func Extraf(format string, args ...interface{}) string {
+	return format
+}
+
+func errorf(format string, args ...interface{}) error {
+	return fmt.Errorf(format, args...)
+}
+
+func example(log *zap.SugaredLogger) error {
+	err := errors.New("error")
+	log.Warnf("save error: %w", err)         // want "call of Warnf does not support error-wrapping directive %w"
+	log.Infof("retry %d: %w, %[1]w", 1, err) // want "call of Infof does not support error-wrapping directive %w"
+	log.Errorf(wrapFormat, err)              // want "call of Errorf does not support error-wrapping directive %w"
+	log.Debugf("100%% %v", err)
+	log.Warnf("percent: %%w", err)
+	l := &logger{log: log}
+	l.Warnf("wrapper error: %+w", err)      // want "call of Warnf does not support error-wrapping directive %w"
+	logf(l, "func wrapper error: %w", err)  // want "call of logf does not support error-wrapping directive %w"
+	_ = Extraf("extra func error: %w", err) // want "call of Extraf does not support error-wrapping directive %w"
+	_ = errorf("error: %w", err)
+	return fmt.Errorf("error: %w", err)
+}
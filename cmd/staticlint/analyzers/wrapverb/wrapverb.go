@@ -0,0 +1,240 @@
+// Package wrapverb contains an Analyzer that find using %w verb in printf-like functions.
+// The verb is supported by fmt.Errorf only, other functions print it as '%!w(...)'.
+// Analyzer checks zap.SugaredLogger's formatting methods, functions from 'funcs' flag
+// and wrappers, which pass their format and arguments to these functions.
+package wrapverb
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+const (
+	wrapVerb     = 'w' // search verb
+	fixVerb      = 'v' // verb for suggested fix
+	formatParams = 2   // count of format and arguments params in printf-like signature
+	errorMessage = "call of %s does not support error-wrapping directive %%w"
+	fixMessage   = "Replace %w with %v"
+)
+
+// defaultFuncs are printf-like functions, which are checked always.
+// Names are in types.Func.FullName() format.
+var defaultFuncs = []string{
+	"(*go.uber.org/zap.SugaredLogger).Debugf",
+	"(*go.uber.org/zap.SugaredLogger).Infof",
+	"(*go.uber.org/zap.SugaredLogger).Warnf",
+	"(*go.uber.org/zap.SugaredLogger).Errorf",
+	"(*go.uber.org/zap.SugaredLogger).DPanicf",
+	"(*go.uber.org/zap.SugaredLogger).Panicf",
+	"(*go.uber.org/zap.SugaredLogger).Fatalf",
+}
+
+// funcs is value of analyzer's 'funcs' flag.
+var funcs string
+
+// WrapVerbAnalyzer is an analyzer.
+var WrapVerbAnalyzer = &analysis.Analyzer{
+	Name:      "wrapverb",
+	Doc:       "analyze to privent using %w verb in printf-like functions except fmt.Errorf",
+	Run:       CheckWrapVerb,
+	FactTypes: []analysis.Fact{new(isWrapper)},
+}
+
+func init() {
+	WrapVerbAnalyzer.Flags.StringVar(&funcs, "funcs", "",
+		"comma-separated list of extra printf-like functions like 'pkg/path.Func' or '(*pkg/path.Type).Method'")
+}
+
+// isWrapper is a fact about function, which passes its format and arguments to printf-like function.
+type isWrapper struct{}
+
+// AFact implements analysis.Fact.
+func (*isWrapper) AFact() {}
+
+// String implements analysis.Fact.
+func (*isWrapper) String() string {
+	return "printfWrapper"
+}
+
+// CheckWrapVerb checks calls of printf-like functions.
+func CheckWrapVerb(pass *analysis.Pass) (interface{}, error) {
+	known := knownFuncs()
+	findWrappers(pass, known)
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(node ast.Node) bool {
+			call, ok := node.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			fn := printfFunc(pass, known, call)
+			if fn == nil {
+				return true
+			}
+			checkCall(pass, fn, call)
+			return true
+		})
+	}
+	return nil, nil //nolint:all //<-senselessly
+}
+
+// knownFuncs is private func. Returns default functions and functions from 'funcs' flag.
+func knownFuncs() map[string]bool {
+	known := make(map[string]bool, len(defaultFuncs))
+	for _, name := range defaultFuncs {
+		known[name] = true
+	}
+	for _, name := range strings.Split(funcs, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			known[name] = true
+		}
+	}
+	return known
+}
+
+// printfParams is private func. Returns format and arguments params of printf-like signature:
+// the last two params must be 'format string, args ...interface{}'.
+func printfParams(sig *types.Signature) (*types.Var, *types.Var, bool) {
+	params := sig.Params()
+	if !sig.Variadic() || params.Len() < formatParams {
+		return nil, nil, false
+	}
+	format, args := params.At(params.Len()-formatParams), params.At(params.Len()-1)
+	if basic, ok := format.Type().Underlying().(*types.Basic); !ok || basic.Kind() != types.String {
+		return nil, nil, false
+	}
+	slice, ok := args.Type().(*types.Slice)
+	if !ok {
+		return nil, nil, false
+	}
+	if iface, ok := slice.Elem().Underlying().(*types.Interface); !ok || !iface.Empty() {
+		return nil, nil, false
+	}
+	return format, args, true
+}
+
+// printfFunc is private func. Returns called function if it is printf-like.
+func printfFunc(pass *analysis.Pass, known map[string]bool, call *ast.CallExpr) *types.Func {
+	fn := typeutil.StaticCallee(pass.TypesInfo, call)
+	if fn == nil {
+		return nil
+	}
+	sig, ok := fn.Type().(*types.Signature)
+	if !ok {
+		return nil
+	}
+	if _, _, ok := printfParams(sig); !ok {
+		return nil
+	}
+	if known[fn.FullName()] || pass.ImportObjectFact(fn, new(isWrapper)) {
+		return fn
+	}
+	return nil
+}
+
+// findWrappers is private func. Exports isWrapper facts for package's functions,
+// which call printf-like functions with their own format and arguments.
+// Repeats until new wrappers are found because wrappers may call each other.
+func findWrappers(pass *analysis.Pass, known map[string]bool) {
+	for found := true; found; {
+		found = false
+		for _, file := range pass.Files {
+			for _, decl := range file.Decls {
+				fd, ok := decl.(*ast.FuncDecl)
+				if !ok || fd.Body == nil {
+					continue
+				}
+				fn, ok := pass.TypesInfo.Defs[fd.Name].(*types.Func)
+				if !ok || pass.ImportObjectFact(fn, new(isWrapper)) {
+					continue
+				}
+				if isWrapperDecl(pass, known, fn, fd) {
+					pass.ExportObjectFact(fn, new(isWrapper))
+					found = true
+				}
+			}
+		}
+	}
+}
+
+// isWrapperDecl is private func. Checks if function passes its format and arguments to printf-like function.
+func isWrapperDecl(pass *analysis.Pass, known map[string]bool, fn *types.Func, fd *ast.FuncDecl) bool {
+	format, args, ok := printfParams(fn.Type().(*types.Signature)) //nolint:forcetypeassert //<-senselessly
+	if !ok {
+		return false
+	}
+	wrapper := false
+	ast.Inspect(fd.Body, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpr)
+		if wrapper || !ok || !call.Ellipsis.IsValid() || len(call.Args) < formatParams {
+			return !wrapper
+		}
+		if printfFunc(pass, known, call) == nil {
+			return true
+		}
+		wrapper = usesVar(pass, call.Args[len(call.Args)-formatParams], format) &&
+			usesVar(pass, call.Args[len(call.Args)-1], args)
+		return !wrapper
+	})
+	return wrapper
+}
+
+// usesVar is private func. Checks if expression is identifier of the variable.
+func usesVar(pass *analysis.Pass, expr ast.Expr, v *types.Var) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && pass.TypesInfo.Uses[ident] == v
+}
+
+// checkCall is private func. Reports %w verbs in format of the call.
+// Suggested fix is added if the format is string literal.
+func checkCall(pass *analysis.Pass, fn *types.Func, call *ast.CallExpr) {
+	sig := fn.Type().(*types.Signature) //nolint:forcetypeassert //<-senselessly
+	index := sig.Params().Len() - formatParams
+	if call.Ellipsis.IsValid() || index >= len(call.Args) {
+		return
+	}
+	arg := call.Args[index]
+	value := pass.TypesInfo.Types[arg].Value
+	if value == nil || value.Kind() != constant.String || len(wrapVerbs(constant.StringVal(value))) == 0 {
+		return
+	}
+	diagnostic := analysis.Diagnostic{
+		Pos:     arg.Pos(),
+		End:     arg.End(),
+		Message: fmt.Sprintf(errorMessage, fn.Name()),
+	}
+	if lit, ok := arg.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+		edits := make([]analysis.TextEdit, 0)
+		for _, offset := range wrapVerbs(lit.Value) {
+			pos := lit.Pos() + token.Pos(offset)
+			edits = append(edits, analysis.TextEdit{Pos: pos, End: pos + 1, NewText: []byte{fixVerb}})
+		}
+		diagnostic.SuggestedFixes = []analysis.SuggestedFix{{Message: fixMessage, TextEdits: edits}}
+	}
+	pass.Report(diagnostic)
+}
+
+// wrapVerbs is private func. Returns offsets of %w verbs in the format.
+// Flags, width, precision and argument indexes are skipped, '%%' is not a verb.
+func wrapVerbs(format string) []int {
+	offsets := make([]int, 0)
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		i++
+		for i < len(format) && strings.IndexByte("+-# 0123456789.*[]", format[i]) >= 0 {
+			i++
+		}
+		if i < len(format) && format[i] == wrapVerb {
+			offsets = append(offsets, i)
+		}
+	}
+	return offsets
+}
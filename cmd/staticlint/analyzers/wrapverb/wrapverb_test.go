@@ -0,0 +1,14 @@
+package wrapverb
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestCheckWrapVerb(t *testing.T) {
+	if err := WrapVerbAnalyzer.Flags.Set("funcs", "pkg1.Extraf"); err != nil {
+		t.Fatalf("set funcs flag error: %v", err)
+	}
+	analysistest.RunWithSuggestedFixes(t, analysistest.TestData(), WrapVerbAnalyzer, "pkg1")
+}
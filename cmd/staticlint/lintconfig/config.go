@@ -0,0 +1,204 @@
+// Package lintconfig selects staticlint analyzers by config file and formats their diagnostics.
+//
+// Config file is read by internal/config, so JSON, YAML and TOML formats are supported:
+//
+//	{
+//		"checks": ["*", "-ST1000", "-QF*"],
+//		"exclude": [
+//			{"path": "internal/server/example_*_test.go", "checks": ["wrapverb"]},
+//			{"path": "proto/..."}
+//		],
+//		"format": "sarif",
+//		"output": "staticlint.sarif"
+//	}
+//
+// Checks are applied in order: name or prefix pattern like 'SA*' enables analyzers,
+// pattern with '-' disables them. All analyzers are enabled if there are no enabling patterns.
+// Excluded paths are relative to working directory, 'dir/...' matches all files in dir.
+package lintconfig
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/gostuding/go-metrics/internal/config"
+)
+
+// Config values.
+const (
+	FormatText  = "text"              // diagnostics are printed like multichecker does
+	FormatJSON  = "json"              // diagnostics are printed as JSON list
+	FormatSARIF = "sarif"             // diagnostics are printed as SARIF 2.1.0 log
+	EnvConfig   = "STATICLINT_CONFIG" // enviroment with config file path
+	configFlag  = "lint-config"       // flag with config file path
+	formatFlag  = "lint-format"       // flag with output format
+	allDirs     = "/..."              // exclude path suffix to match all files in directory
+)
+
+type (
+	// Config contains analyzers selection, excluded paths and output settings.
+	Config struct {
+		Checks  []string  `json:"checks" env:"STATICLINT_CHECKS"` // analyzers names or prefixes
+		Exclude []Exclude `json:"exclude"`                        // excluded paths
+		Format  string    `json:"format" env:"STATICLINT_FORMAT"` // output format
+		Output  string    `json:"output" env:"STATICLINT_OUTPUT"` // output file, stdout if empty
+		path    string    // config file path
+	}
+
+	// Exclude disables checks for files, which match the path.
+	Exclude struct {
+		Path   string   `json:"path"`   // file path pattern
+		Checks []string `json:"checks"` // analyzers names or prefixes, all analyzers if empty
+	}
+)
+
+// Load reads config and returns args without staticlint's own flags:
+// '-lint-config' with config file path and '-lint-format' with output format.
+// Config file path may be set by 'STATICLINT_CONFIG' environment too.
+// Config is empty if path is not set: all analyzers are used with multichecker's output.
+func Load(args []string) (*Config, []string, error) {
+	cfg := Config{path: os.Getenv(EnvConfig)}
+	rest := make([]string, 0, len(args))
+	format := ""
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		if !strings.HasPrefix(args[i], "-") || (name != configFlag && name != formatFlag) {
+			rest = append(rest, args[i])
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return nil, nil, fmt.Errorf("flag '-%s' needs an argument", name)
+			}
+			i++
+			value = args[i]
+		}
+		if name == configFlag {
+			cfg.path = value
+		} else {
+			format = value
+		}
+	}
+	if cfg.path != "" {
+		if err := config.DecodeFile(cfg.path, &cfg); err != nil {
+			return nil, nil, err //nolint:wrapcheck //<-senselessly
+		}
+	}
+	if err := config.ReadEnv(&cfg); err != nil {
+		return nil, nil, err //nolint:wrapcheck //<-senselessly
+	}
+	if format != "" {
+		cfg.Format = format
+	}
+	if cfg.Format == "" {
+		cfg.Format = FormatText
+	}
+	return &cfg, rest, cfg.Validate()
+}
+
+// Validate checks config values.
+func (c *Config) Validate() error {
+	errs := make([]error, 0)
+	switch c.Format {
+	case FormatText, FormatJSON, FormatSARIF:
+	default:
+		errs = append(errs, fmt.Errorf("format must be '%s', '%s' or '%s'", FormatText, FormatJSON, FormatSARIF))
+	}
+	for _, pattern := range c.Checks {
+		if _, err := path.Match(strings.TrimPrefix(pattern, "-"), ""); err != nil {
+			errs = append(errs, fmt.Errorf("check pattern '%s' error: %w", pattern, err))
+		}
+	}
+	for _, item := range c.Exclude {
+		if item.Path == "" {
+			errs = append(errs, errors.New("exclude path must be set"))
+		} else if _, err := filepath.Match(item.Path, ""); err != nil {
+			errs = append(errs, fmt.Errorf("exclude path '%s' error: %w", item.Path, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Plain checks if multichecker's output can be used as is: text format without excludes.
+func (c *Config) Plain() bool {
+	return c.Format == FormatText && c.Output == "" && len(c.Exclude) == 0
+}
+
+// Select returns analyzers enabled by checks patterns.
+// Error is returned if a pattern doesn't match any analyzer.
+func (c *Config) Select(all []*analysis.Analyzer) ([]*analysis.Analyzer, error) {
+	enabled := make(map[string]bool, len(all))
+	positive := false
+	for _, pattern := range c.Checks {
+		positive = positive || !strings.HasPrefix(pattern, "-")
+	}
+	if !positive {
+		for _, a := range all {
+			enabled[a.Name] = true
+		}
+	}
+	for _, pattern := range c.Checks {
+		disable := strings.HasPrefix(pattern, "-")
+		matched := false
+		for _, a := range all {
+			if match(strings.TrimPrefix(pattern, "-"), a.Name) {
+				enabled[a.Name], matched = !disable, true
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("check pattern '%s' doesn't match any analyzer", pattern)
+		}
+	}
+	selected := make([]*analysis.Analyzer, 0, len(enabled))
+	for _, a := range all {
+		if enabled[a.Name] {
+			selected = append(selected, a)
+		}
+	}
+	return selected, nil
+}
+
+// Excluded checks if the analyzer's diagnostic in the file must be skipped.
+// The file path must be relative to working directory.
+func (c *Config) Excluded(file, analyzer string) bool {
+	file = filepath.ToSlash(file)
+	for _, item := range c.Exclude {
+		if !matchPath(filepath.ToSlash(item.Path), file) {
+			continue
+		}
+		if len(item.Checks) == 0 {
+			return true
+		}
+		for _, pattern := range item.Checks {
+			if match(pattern, analyzer) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// match is private func. Checks if analyzer name matches pattern like 'SA*' or 'shadow'.
+// Pattern '*' and 'all' match all analyzers.
+func match(pattern, name string) bool {
+	if pattern == "all" {
+		return true
+	}
+	ok, err := path.Match(pattern, name)
+	return err == nil && ok
+}
+
+// matchPath is private func. Checks if file matches exclude path: glob pattern or 'dir/...'.
+func matchPath(pattern, file string) bool {
+	if dir, ok := strings.CutSuffix(pattern, allDirs); ok {
+		return dir == "." || strings.HasPrefix(file, dir+"/")
+	}
+	ok, err := path.Match(pattern, file)
+	return err == nil && ok
+}
@@ -0,0 +1,89 @@
+package lintconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/analysis"
+)
+
+func testAnalyzers(names ...string) []*analysis.Analyzer {
+	items := make([]*analysis.Analyzer, 0, len(names))
+	for _, name := range names {
+		items = append(items, &analysis.Analyzer{Name: name, Doc: name + " analyzer"})
+	}
+	return items
+}
+
+func analyzersNames(items []*analysis.Analyzer) []string {
+	names := make([]string, 0, len(items))
+	for _, a := range items {
+		names = append(names, a.Name)
+	}
+	return names
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "staticlint.yaml")
+	data := "checks: ['SA*', '-SA1000']\nexclude:\n  - path: proto/...\nformat: json\n"
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+
+	cfg, args, err := Load([]string{"-lint-config", path, "-lint-format=sarif", "-wrapverb.funcs=a.F", "./..."})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"-wrapverb.funcs=a.F", "./..."}, args)
+	assert.Equal(t, []string{"SA*", "-SA1000"}, cfg.Checks)
+	assert.Equal(t, []Exclude{{Path: "proto/..."}}, cfg.Exclude)
+	assert.Equal(t, FormatSARIF, cfg.Format, "flag must override config file")
+	assert.False(t, cfg.Plain())
+
+	cfg, args, err = Load([]string{"./..."})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"./..."}, args)
+	assert.True(t, cfg.Plain(), "empty config must use multichecker output")
+
+	_, _, err = Load([]string{"-lint-format", "xml", "./..."})
+	assert.Error(t, err, "unknown format error expected")
+	_, _, err = Load([]string{"./...", "-lint-config"})
+	assert.Error(t, err, "flag without value error expected")
+}
+
+func TestConfig_Select(t *testing.T) {
+	all := testAnalyzers("SA1000", "SA1001", "S1000", "ST1000", "ST1003", "wrapverb")
+	tests := []struct {
+		name    string
+		checks  []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "Все анализаторы", want: analyzersNames(all)},
+		{name: "Префикс", checks: []string{"SA*"}, want: []string{"SA1000", "SA1001"}},
+		{name: "Только отключение", checks: []string{"-ST*"}, want: []string{"SA1000", "SA1001", "S1000", "wrapverb"}},
+		{name: "По порядку", checks: []string{"all", "-ST*", "ST1003"}, want: []string{"SA1000", "SA1001", "S1000", "ST1003", "wrapverb"}},
+		{name: "Неизвестный анализатор", checks: []string{"unknown"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := (&Config{Checks: tt.checks}).Select(all)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, analyzersNames(got))
+		})
+	}
+}
+
+func TestConfig_Excluded(t *testing.T) {
+	cfg := Config{Exclude: []Exclude{
+		{Path: "proto/..."},
+		{Path: "internal/server/example_*_test.go", Checks: []string{"wrapverb", "ST*"}},
+	}}
+	assert.True(t, cfg.Excluded("proto/metrics.pb.go", "SA1000"))
+	assert.True(t, cfg.Excluded("internal/server/example_base_test.go", "ST1000"))
+	assert.False(t, cfg.Excluded("internal/server/example_base_test.go", "SA1000"))
+	assert.False(t, cfg.Excluded("internal/server/router.go", "wrapverb"))
+}
@@ -0,0 +1,229 @@
+package lintconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// SARIF values.
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifLevel   = "warning"
+	sarifBaseID  = "%SRCROOT%"
+	toolName     = "staticlint"
+)
+
+type (
+	// Diagnostic is one analyzer's message.
+	Diagnostic struct {
+		Analyzer string `json:"analyzer"` // analyzer name
+		Package  string `json:"package"`  // package ID
+		File     string `json:"file"`     // file path relative to working directory
+		Posn     string `json:"posn"`     // position as printed by multichecker
+		Message  string `json:"message"`  // diagnostic message
+		Line     int    `json:"line"`     // line number
+		Column   int    `json:"column"`   // column number
+	}
+
+	// Report contains diagnostics and analysis errors from multichecker's JSON output.
+	Report struct {
+		Diagnostics []Diagnostic `json:"diagnostics"` // diagnostics sorted by position
+		Errors      []string     `json:"errors"`      // analysis errors
+	}
+
+	// jsonDiagnostic is private type. Diagnostic in multichecker's JSON output.
+	jsonDiagnostic struct {
+		Posn    string `json:"posn"`
+		Message string `json:"message"`
+	}
+
+	// jsonError is private type. Analysis error in multichecker's JSON output.
+	jsonError struct {
+		Err string `json:"error"`
+	}
+)
+
+// NewReport parses multichecker's JSON output: package ID -> analyzer name -> diagnostics or error.
+// Files paths are converted to relative to wd.
+func NewReport(data []byte, wd string) (*Report, error) {
+	var tree map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, fmt.Errorf("analyzers output convert error: %w", err)
+	}
+	report := Report{Diagnostics: make([]Diagnostic, 0), Errors: make([]string, 0)}
+	for pkg, items := range tree {
+		for name, raw := range items {
+			var diagnostics []jsonDiagnostic
+			if err := json.Unmarshal(raw, &diagnostics); err != nil {
+				var jErr jsonError
+				if err = json.Unmarshal(raw, &jErr); err != nil {
+					return nil, fmt.Errorf("analyzer '%s' output convert error: %w", name, err)
+				}
+				report.Errors = append(report.Errors, fmt.Sprintf("%s: %s: %s", pkg, name, jErr.Err))
+				continue
+			}
+			for _, d := range diagnostics {
+				item := Diagnostic{Analyzer: name, Package: pkg, Posn: d.Posn, Message: d.Message}
+				item.File, item.Line, item.Column = splitPosn(d.Posn)
+				if rel, err := filepath.Rel(wd, item.File); err == nil && !strings.HasPrefix(rel, "..") {
+					item.File = rel
+				}
+				item.File = filepath.ToSlash(item.File)
+				report.Diagnostics = append(report.Diagnostics, item)
+			}
+		}
+	}
+	sort.Slice(report.Diagnostics, func(i, j int) bool {
+		a, b := report.Diagnostics[i], report.Diagnostics[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		if a.Column != b.Column {
+			return a.Column < b.Column
+		}
+		if a.Analyzer != b.Analyzer {
+			return a.Analyzer < b.Analyzer
+		}
+		return a.Package < b.Package
+	})
+	sort.Strings(report.Errors)
+	return &report, nil
+}
+
+// splitPosn is private func. Splits position like 'file.go:10:2' to file, line and column.
+func splitPosn(posn string) (string, int, int) {
+	file, column := posn, 0
+	if i := strings.LastIndex(file, ":"); i >= 0 {
+		if n, err := strconv.Atoi(file[i+1:]); err == nil {
+			file, column = file[:i], n
+		}
+	}
+	line := 0
+	if i := strings.LastIndex(file, ":"); i >= 0 {
+		if n, err := strconv.Atoi(file[i+1:]); err == nil {
+			file, line = file[:i], n
+		}
+	}
+	if line == 0 {
+		line, column = column, 0
+	}
+	return file, line, column
+}
+
+// Filter removes diagnostics in excluded paths. Duplicates from test variants of packages are removed too.
+func (r *Report) Filter(cfg *Config) {
+	items := make([]Diagnostic, 0, len(r.Diagnostics))
+	seen := make(map[string]bool)
+	for _, d := range r.Diagnostics {
+		key := d.Analyzer + " " + d.Posn + " " + d.Message
+		if seen[key] || cfg.Excluded(d.File, d.Analyzer) {
+			continue
+		}
+		seen[key] = true
+		items = append(items, d)
+	}
+	r.Diagnostics = items
+}
+
+// Write writes report in the format: text, JSON or SARIF.
+// Analysis errors are written as text lines, JSON list or SARIF tool notifications.
+func (r *Report) Write(w io.Writer, format string, analyzers []*analysis.Analyzer) error {
+	var err error
+	switch format {
+	case FormatText:
+		err = r.writeText(w)
+	case FormatJSON:
+		err = writeJSON(w, r)
+	case FormatSARIF:
+		err = writeJSON(w, r.sarif(analyzers))
+	default:
+		err = fmt.Errorf("format '%s' is not supported", format)
+	}
+	if err != nil {
+		return fmt.Errorf("write report error: %w", err)
+	}
+	return nil
+}
+
+// writeText is private func. Writes errors and diagnostics as text lines, stops on the first write error.
+func (r *Report) writeText(w io.Writer) error {
+	for _, e := range r.Errors {
+		if _, err := fmt.Fprintln(w, e); err != nil {
+			return err //nolint:wrapcheck //<-wrapped by caller
+		}
+	}
+	for _, d := range r.Diagnostics {
+		if _, err := fmt.Fprintf(w, "%s: %s\n", d.Posn, d.Message); err != nil {
+			return err //nolint:wrapcheck //<-wrapped by caller
+		}
+	}
+	return nil
+}
+
+// writeJSON is private func. Writes value as indented JSON.
+func writeJSON(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v) //nolint:wrapcheck //<-wrapped by caller
+}
+
+// sarif is private func. Converts report to SARIF log.
+// Each analyzer is a rule with the first line of its doc as description.
+func (r *Report) sarif(analyzers []*analysis.Analyzer) map[string]any {
+	rules := make([]map[string]any, 0, len(analyzers))
+	for _, a := range analyzers {
+		doc, _, _ := strings.Cut(a.Doc, "\n")
+		rules = append(rules, map[string]any{
+			"id":               a.Name,
+			"shortDescription": map[string]any{"text": doc},
+		})
+	}
+	results := make([]map[string]any, 0, len(r.Diagnostics))
+	for _, d := range r.Diagnostics {
+		location := map[string]any{"uri": d.File, "uriBaseId": sarifBaseID}
+		if filepath.IsAbs(d.File) {
+			location = map[string]any{"uri": "file://" + d.File}
+		}
+		region := map[string]any{"startLine": d.Line}
+		if d.Column > 0 {
+			region["startColumn"] = d.Column
+		}
+		results = append(results, map[string]any{
+			"ruleId":  d.Analyzer,
+			"level":   sarifLevel,
+			"message": map[string]any{"text": d.Message},
+			"locations": []any{map[string]any{
+				"physicalLocation": map[string]any{
+					"artifactLocation": location,
+					"region":           region,
+				},
+			}},
+		})
+	}
+	run := map[string]any{
+		"tool":    map[string]any{"driver": map[string]any{"name": toolName, "rules": rules}},
+		"results": results,
+	}
+	if len(r.Errors) > 0 {
+		notifications := make([]any, 0, len(r.Errors))
+		for _, e := range r.Errors {
+			notifications = append(notifications, map[string]any{"level": "error", "message": map[string]any{"text": e}})
+		}
+		run["invocations"] = []any{map[string]any{
+			"executionSuccessful":        false,
+			"toolExecutionNotifications": notifications,
+		}}
+	}
+	return map[string]any{"version": sarifVersion, "$schema": sarifSchema, "runs": []any{run}}
+}
@@ -0,0 +1,126 @@
+package lintconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testOutput = `{
+	"example/pkg": {
+		"wrapverb": [
+			{"posn": "/src/pkg/b.go:7:2", "message": "call of Warnf does not support error-wrapping directive %w"}
+		],
+		"SA1000": [
+			{"posn": "/src/pkg/a.go:3:10", "message": "invalid regular expression"}
+		]
+	},
+	"example/pkg [example/pkg.test]": {
+		"wrapverb": [
+			{"posn": "/src/pkg/b.go:7:2", "message": "call of Warnf does not support error-wrapping directive %w"}
+		]
+	},
+	"example/gen": {
+		"ST1000": [{"posn": "/src/gen/gen.go:1:1", "message": "at least one file in a package should have a package comment"}],
+		"printf": {"error": "analysis failed"}
+	}
+}`
+
+func TestNewReport(t *testing.T) {
+	report, err := NewReport([]byte(testOutput), "/src")
+	require.NoError(t, err)
+	report.Filter(&Config{Exclude: []Exclude{{Path: "gen/..."}}})
+	require.Len(t, report.Diagnostics, 2, "excluded and duplicated diagnostics must be removed")
+	assert.Equal(t, Diagnostic{
+		Analyzer: "SA1000", Package: "example/pkg", File: "pkg/a.go", Posn: "/src/pkg/a.go:3:10",
+		Message: "invalid regular expression", Line: 3, Column: 10,
+	}, report.Diagnostics[0])
+	assert.Equal(t, "pkg/b.go", report.Diagnostics[1].File)
+	assert.Equal(t, []string{"example/gen: printf: analysis failed"}, report.Errors)
+
+	_, err = NewReport([]byte("not json"), "/src")
+	assert.Error(t, err)
+}
+
+func TestReport_Write(t *testing.T) {
+	report, err := NewReport([]byte(testOutput), "/src")
+	require.NoError(t, err)
+	report.Filter(&Config{})
+
+	var text bytes.Buffer
+	require.NoError(t, report.Write(&text, FormatText, nil))
+	assert.Contains(t, text.String(), "/src/pkg/a.go:3:10: invalid regular expression\n")
+
+	var sarif bytes.Buffer
+	require.NoError(t, report.Write(&sarif, FormatSARIF, testAnalyzers("SA1000", "wrapverb")))
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region struct {
+							StartLine int `json:"startLine"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	require.NoError(t, json.Unmarshal(sarif.Bytes(), &log))
+	assert.Equal(t, "2.1.0", log.Version)
+	require.Len(t, log.Runs, 1)
+	assert.Len(t, log.Runs[0].Tool.Driver.Rules, 2)
+	require.Len(t, log.Runs[0].Results, 3)
+	assert.Equal(t, "ST1000", log.Runs[0].Results[0].RuleID)
+	assert.Equal(t, "gen/gen.go", log.Runs[0].Results[0].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	assert.Equal(t, 1, log.Runs[0].Results[0].Locations[0].PhysicalLocation.Region.StartLine)
+
+	var data bytes.Buffer
+	require.NoError(t, report.Write(&data, FormatJSON, nil))
+	var got Report
+	require.NoError(t, json.Unmarshal(data.Bytes(), &got))
+	assert.Equal(t, *report, got)
+
+	assert.Error(t, report.Write(&data, "xml", nil))
+}
+
+// failingWriter is test writer. The first write fails, next writes succeed.
+type failingWriter struct {
+	writes int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	if w.writes == 1 {
+		return 0, errors.New("write error")
+	}
+	return len(p), nil
+}
+
+func TestReport_WriteError(t *testing.T) {
+	report, err := NewReport([]byte(testOutput), "/src")
+	require.NoError(t, err)
+	report.Filter(&Config{})
+	require.NotEmpty(t, report.Errors)
+	require.NotEmpty(t, report.Diagnostics)
+
+	var w failingWriter
+	assert.Error(t, report.Write(&w, FormatText, nil), "the first write error must be returned")
+	assert.Equal(t, 1, w.writes, "writing must be stopped on the first error")
+}
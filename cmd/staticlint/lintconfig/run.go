@@ -0,0 +1,83 @@
+package lintconfig
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/multichecker"
+)
+
+// envChild is set for staticlint process, which is started to get multichecker's JSON output.
+const envChild = "STATICLINT_CHILD"
+
+// Main runs analyzers and exits. Multichecker is used directly if config is plain.
+// Otherwise staticlint starts itself with '-json' flag, skips excluded diagnostics
+// and writes the rest in config format.
+func Main(cfg *Config, analyzers []*analysis.Analyzer, args []string) {
+	if cfg.Plain() || os.Getenv(envChild) != "" {
+		os.Args = append(os.Args[:1], args...)
+		multichecker.Main(analyzers...)
+	}
+	code, err := run(cfg, analyzers, args)
+	if err != nil {
+		log.Printf("staticlint error: %v", err)
+	}
+	os.Exit(code)
+}
+
+// run is private func. Runs child staticlint process and writes its diagnostics.
+// Returns exit code like multichecker does: 3 if text diagnostics were printed.
+func run(cfg *Config, analyzers []*analysis.Analyzer, args []string) (int, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return 1, fmt.Errorf("get executable path error: %w", err)
+	}
+	var stdout bytes.Buffer
+	cmd := exec.Command(exe, append([]string{"-json"}, args...)...)
+	cmd.Env = append(os.Environ(), envChild+"=1", EnvConfig+"="+cfg.path)
+	cmd.Stdout, cmd.Stderr = &stdout, os.Stderr
+	if err = cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode(), nil
+		}
+		return 1, fmt.Errorf("run analyzers error: %w", err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return 1, fmt.Errorf("get working directory error: %w", err)
+	}
+	report, err := NewReport(stdout.Bytes(), wd)
+	if err != nil {
+		return 1, err
+	}
+	report.Filter(cfg)
+	var out io.Writer = os.Stdout
+	if cfg.Format == FormatText {
+		out = os.Stderr
+	}
+	if cfg.Output != "" {
+		file, err := os.Create(cfg.Output)
+		if err != nil {
+			return 1, fmt.Errorf("create output file error: %w", err)
+		}
+		defer file.Close() //nolint:errcheck //<-senselessly
+		out = file
+	}
+	if err = report.Write(out, cfg.Format, analyzers); err != nil {
+		return 1, err
+	}
+	code := 0
+	if len(report.Errors) > 0 {
+		code = 1
+	} else if cfg.Format == FormatText && len(report.Diagnostics) > 0 {
+		code = 3
+	}
+	return code, nil
+}
@@ -6,16 +6,30 @@
 // After that use command:
 //
 //	staticlint ./...
+//
+// Analyzers, excluded paths and output format (text, JSON or SARIF) can be set by config file:
+//
+//	staticlint -lint-config staticlint.yaml -lint-format sarif ./...
+//
+// See package lintconfig for config file description.
 package main
 
 import (
-	"golang.org/x/tools/go/analysis/multichecker"
+	"log"
+	"os"
 
 	"github.com/gostuding/go-metrics/cmd/staticlint/analyzers"
+	"github.com/gostuding/go-metrics/cmd/staticlint/lintconfig"
 )
 
 func main() {
-	multichecker.Main(
-		analyzers.GetAnalyzers()...,
-	)
+	cfg, args, err := lintconfig.Load(os.Args[1:])
+	if err != nil {
+		log.Fatalf("staticlint config error: %v", err)
+	}
+	checks, err := cfg.Select(analyzers.GetAnalyzers())
+	if err != nil {
+		log.Fatalf("staticlint config error: %v", err)
+	}
+	lintconfig.Main(cfg, checks, args)
 }
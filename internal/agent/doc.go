@@ -5,7 +5,7 @@
 //	 if err != nil {
 //			log.Fatalln(err)
 //	 }
-//	 logger, err := zap.NewDevelopment()
+//	 logger, err := agent.NewLogger(cfg.Logging())
 //	 if err != nil {
 //			log.Fatalln("create logger error:", err)
 //	 }
@@ -2,42 +2,109 @@ package agent
 
 import (
 	"crypto/rsa"
-	"crypto/x509"
-	"encoding/json"
-	"encoding/pem"
 	"errors"
 	"flag"
 	"fmt"
 	"net"
 	"os"
+	"reflect"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gostuding/go-metrics/internal/agent/metrics"
+	"github.com/gostuding/go-metrics/internal/config"
+	"github.com/gostuding/go-metrics/internal/logging"
+	"github.com/gostuding/go-metrics/internal/rsakeys"
+	"github.com/gostuding/go-metrics/internal/signing"
+	"github.com/gostuding/go-metrics/internal/tracing"
+	"google.golang.org/grpc/codes"
 )
 
 // Default values for Config.
 const (
-	defPort           = 8080      // default server port
+	defAddress        = ":8080"   // default server address
 	defPoolInterval   = 2         // default update metrics interval
 	defReportInterval = 10        // default send to server interval
 	defRateLimit      = 5         // default max gorutines to send messages
-	defaultKey        = "default" // Key for hash
-	falseStr          = "false"   // internal value
+	defBufferMaxSize  = 10 << 20  // default outbound queue max size in bytes
+	defBufferMaxAge   = 3600      // default outbound queue batch max age in seconds
+	defOutputMaxSize  = 10 << 20  // default output file max size in bytes
+	defOutputMaxFiles = 5         // default count of rotated output files
+	defLogLevel       = "debug"   // default logger level
+	grpcScheme        = "grpc://" // target prefix for RPC sending
+	httpScheme        = "http://" // target prefix for HTTP sending
 )
 
 // Config contains agent's configuration.
 type (
+	// TargetConfig contains one server's configuration.
+	// Empty Key and PublicKeyPath are taken from Config.
+	TargetConfig struct {
+		PublicKey     *rsa.PublicKey `json:"-"`                           // public key for messages encryption
+		Address       string         `json:"address"`                     // server's address like 'host:port'
+		Key           string         `json:"key,omitempty" secret:"true"` // key for hashing requests body
+		PublicKeyPath string         `json:"crypto_key,omitempty"`        // path to public key
+		SendByRPC     bool           `json:"rpc,omitempty"`               // flag for RPC send using
+		defaultRPC    bool           `json:"-"`                           // flag to take SendByRPC from Config
+	}
+
+	// TargetList is servers list, which is set from string like 'host1:port,grpc://host2:port'.
+	TargetList []TargetConfig
+
+	// RenameRules is metrics rename rules, which are set from string like 'from=to,re:^Heap(.*)=heap_$1'.
+	RenameRules []metrics.RenameRule
+
 	Config struct {
-		PublicKey      *rsa.PublicKey `json:"-"`                         // public key for messages encryption
-		PublicKeyPath  string         `json:"crypto_key,omitempty"`      // path to public key
-		IP             string         `json:"address,omitempty"`         // server's ip address
-		LocalAddress   *net.IP        `json:"-"`                         // agent's local ip address
-		gzipCompress   string         `json:"-"`                         //
-		HashKey        string         `json:"key,omitempty"`             // key for hashing requests body
-		RateLimit      int            `json:"rate_limit,omitempty"`      // max requests in time
-		Port           int            `json:"-"`                         // server's port
-		PollInterval   int            `json:"poll_interval,omitempty"`   // poll requests interval
-		ReportInterval int            `json:"report_interval,omitempty"` // send to server interval
-		GzipCompress   bool           `json:"gzip,omitempty"`            // flag to compress requests or not
-		SendByRPC      bool           `json:"-"`                         // flag for RPC send using
+		PublicKey      *rsa.PublicKey    `json:"-"`                                                     // public key for messages encryption
+		PublicKeyPath  string            `json:"crypto_key,omitempty" env:"CRYPTO_KEY"`                 // path to public key
+		PrivateKey     *rsa.PrivateKey   `json:"-"`                                                     // private key for responses decryption
+		PrivateKeyPath string            `json:"response_key,omitempty" env:"RESPONSE_KEY"`             // path to private key for responses
+		Address        string            `json:"address,omitempty" env:"ADDRESS"`                       // server's address like 'host:port'
+		IP             string            `json:"-"`                                                     // server's ip address
+		LocalAddress   *net.IP           `json:"-"`                                                     // agent's local ip address
+		HashKey        string            `json:"key,omitempty" env:"KEY" secret:"true"`                 // key for hashing requests body
+		AgentID        string            `json:"agent_id,omitempty" env:"AGENT_ID"`                     // agent ID for server's keys registry
+		HashScheme     string            `json:"hash_scheme,omitempty" env:"HASH_SCHEME"`               // requests signature scheme: nonce or body
+		RateLimit      int               `json:"rate_limit,omitempty" env:"RATE_LIMIT"`                 // max requests in time
+		Port           int               `json:"-"`                                                     // server's port
+		PollInterval   int               `json:"poll_interval,omitempty" env:"POLL_INTERVAL"`           // poll requests interval
+		ReportInterval int               `json:"report_interval,omitempty" env:"REPORT_INTERVAL"`       // send to server interval
+		GzipCompress   bool              `json:"gzip,omitempty"`                                        // flag to compress requests or not
+		SendByRPC      bool              `json:"-"`                                                     // flag for RPC send using
+		StatsDAddress  string            `json:"statsd_address,omitempty" env:"STATSD_ADDRESS"`         // UDP address for StatsD listener
+		PushAddress    string            `json:"push_address,omitempty" env:"PUSH_ADDRESS"`             // address for local push API
+		BufferPath     string            `json:"buffer_path,omitempty" env:"BUFFER_PATH"`               // directory for outbound queue
+		BufferMaxSize  int               `json:"buffer_max_size,omitempty" env:"BUFFER_MAX_SIZE"`       // outbound queue max size in bytes
+		BufferMaxAge   int               `json:"buffer_max_age,omitempty" env:"BUFFER_MAX_AGE"`         // outbound queue batch max age in seconds
+		RetryAttempts  int               `json:"retry_max_attempts,omitempty" env:"RETRY_MAX_ATTEMPTS"` // max send attempts
+		RetryBaseDelay int               `json:"retry_base_delay,omitempty" env:"RETRY_BASE_DELAY"`     // delay before first retry in milliseconds
+		RetryMaxDelay  int               `json:"retry_max_delay,omitempty" env:"RETRY_MAX_DELAY"`       // max delay between retries in milliseconds
+		RetryJitter    float64           `json:"retry_jitter,omitempty" env:"RETRY_JITTER"`             // retry delay deviation from 0 to 1
+		RetryHTTPCodes []int             `json:"retry_http_codes,omitempty" env:"RETRY_HTTP_CODES"`     // retryable HTTP status codes
+		RetryGRPCCodes []string          `json:"retry_grpc_codes,omitempty" env:"RETRY_GRPC_CODES"`     // retryable gRPC codes names
+		Targets        TargetList        `json:"targets,omitempty" env:"TARGETS"`                       // servers list instead of address
+		TargetsMode    string            `json:"targets_mode,omitempty" env:"TARGETS_MODE"`             // failover or fanout
+		RuntimeMetrics bool              `json:"runtime_metrics,omitempty" env:"RUNTIME_METRICS"`       // flag to collect runtime stats from runtime/metrics
+		PauseBuckets   []float64         `json:"pause_buckets,omitempty" env:"PAUSE_BUCKETS"`           // GC pauses histogram buckets in seconds
+		Allow          []string          `json:"allow,omitempty" env:"METRICS_ALLOW"`                   // patterns of metrics for sending
+		Deny           []string          `json:"deny,omitempty" env:"METRICS_DENY"`                     // patterns of metrics which are not sent
+		Rename         RenameRules       `json:"rename,omitempty" env:"METRICS_RENAME"`                 // metrics rename rules
+		Prefix         string            `json:"prefix,omitempty" env:"METRICS_PREFIX"`                 // prefix for metrics names
+		Labels         map[string]string `json:"labels,omitempty" env:"METRICS_LABELS"`                 // static labels for every metric
+		Output         string            `json:"output,omitempty" env:"OUTPUT"`                         // server, stdout or file
+		OutputPath     string            `json:"output_path,omitempty" env:"OUTPUT_PATH"`               // output file path
+		OutputMaxSize  int               `json:"output_max_size,omitempty" env:"OUTPUT_MAX_SIZE"`       // output file max size in bytes
+		OutputMaxFiles int               `json:"output_max_files,omitempty" env:"OUTPUT_MAX_FILES"`     // count of rotated output files
+		Once           bool              `json:"once,omitempty" env:"ONCE"`                             // collect metrics once, send them and exit
+		StatusAddress  string            `json:"status_address,omitempty" env:"STATUS_ADDRESS"`         // localhost address for status endpoint
+		TraceExporter  string            `json:"trace_exporter,omitempty" env:"TRACE_EXPORTER"`         // spans exporter: stdout or otlp
+		TraceEndpoint  string            `json:"trace_endpoint,omitempty" env:"TRACE_ENDPOINT"`         // OTLP collector address
+		TraceInsecure  bool              `json:"trace_insecure,omitempty" env:"TRACE_INSECURE"`         // flag to use OTLP without TLS
+		LogLevel       string            `json:"log_level,omitempty" env:"LOG_LEVEL"`                   // logger level: debug, info, warn or error
+		LogFormat      string            `json:"log_format,omitempty" env:"LOG_FORMAT"`                 // logger format: console or json
+		LogFile        string            `json:"log_file,omitempty" env:"LOG_FILE"`                     // logger output file, stderr if empty
+		args           []string          `json:"-"`                                                     // startup variables, used for reload
 	}
 )
 
@@ -47,8 +114,8 @@ func (n *Config) String() string {
 }
 
 func (n *Config) setDefault() {
-	if n.Port == 0 {
-		n.Port = defPort
+	if n.Address == "" {
+		n.Address = defAddress
 	}
 	if n.PollInterval == 0 {
 		n.PollInterval = defPoolInterval
@@ -59,12 +126,90 @@ func (n *Config) setDefault() {
 	if n.RateLimit == 0 {
 		n.RateLimit = defRateLimit
 	}
-	if n.gzipCompress != falseStr {
-		n.GzipCompress = true
+	n.GzipCompress = true
+	if n.BufferMaxSize == 0 {
+		n.BufferMaxSize = defBufferMaxSize
+	}
+	if n.BufferMaxAge == 0 {
+		n.BufferMaxAge = defBufferMaxAge
+	}
+	if n.TargetsMode == "" {
+		n.TargetsMode = metrics.ModeFailover
 	}
-	if n.HashKey == "" {
-		n.HashKey = defaultKey
+	if n.PauseBuckets == nil {
+		n.PauseBuckets = metrics.DefaultPauseBuckets
 	}
+	if n.Output == "" {
+		n.Output = metrics.OutputServer
+	}
+	if n.LogLevel == "" {
+		n.LogLevel = defLogLevel
+	}
+	if n.HashScheme == "" {
+		n.HashScheme = signing.SchemeNonce
+	}
+	if n.OutputMaxSize == 0 {
+		n.OutputMaxSize = defOutputMaxSize
+	}
+	if n.OutputMaxFiles == 0 {
+		n.OutputMaxFiles = defOutputMaxFiles
+	}
+	n.setRetryDefault()
+}
+
+// setRetryDefault is private func. Sets default retry policy values.
+func (n *Config) setRetryDefault() {
+	def := metrics.DefaultRetryPolicy()
+	if n.RetryAttempts == 0 {
+		n.RetryAttempts = def.MaxAttempts
+	}
+	if n.RetryBaseDelay == 0 {
+		n.RetryBaseDelay = int(def.BaseDelay.Milliseconds())
+	}
+	if n.RetryMaxDelay == 0 {
+		n.RetryMaxDelay = int(def.MaxDelay.Milliseconds())
+	}
+	if n.RetryJitter == 0 {
+		n.RetryJitter = def.Jitter
+	}
+	if n.RetryHTTPCodes == nil {
+		n.RetryHTTPCodes = def.HTTPCodes
+	}
+	if n.RetryGRPCCodes == nil {
+		n.RetryGRPCCodes = make([]string, 0, len(def.GRPCCodes))
+		for _, c := range def.GRPCCodes {
+			n.RetryGRPCCodes = append(n.RetryGRPCCodes, c.String())
+		}
+	}
+}
+
+// RetryPolicy creates send retry policy from Config values.
+func (n *Config) RetryPolicy() (*metrics.RetryPolicy, error) {
+	grpcCodes := make([]codes.Code, 0, len(n.RetryGRPCCodes))
+	for _, name := range n.RetryGRPCCodes {
+		c, err := metrics.ParseGRPCCode(name)
+		if err != nil {
+			return nil, fmt.Errorf("retry gRPC codes error: %w", err)
+		}
+		grpcCodes = append(grpcCodes, c)
+	}
+	return &metrics.RetryPolicy{
+		MaxAttempts: n.RetryAttempts,
+		BaseDelay:   time.Duration(n.RetryBaseDelay) * time.Millisecond,
+		MaxDelay:    time.Duration(n.RetryMaxDelay) * time.Millisecond,
+		Jitter:      n.RetryJitter,
+		HTTPCodes:   n.RetryHTTPCodes,
+		GRPCCodes:   grpcCodes,
+	}, nil
+}
+
+// Filter creates metrics filter from Config values.
+func (n *Config) Filter() (*metrics.Filter, error) {
+	f, err := metrics.NewFilter(n.Allow, n.Deny, n.Rename, n.Prefix, n.Labels)
+	if err != nil {
+		return nil, fmt.Errorf("metrics filter error: %w", err)
+	}
+	return f, nil
 }
 
 // Set validates and sets server's address.
@@ -75,6 +220,7 @@ func (n *Config) Set(value string) error {
 		return fmt.Errorf("NetworkAddress ('%s') incorrect. Use value like: 'IP:PORT': %w", value, err)
 	}
 	n.IP = ip
+	n.Address = value
 	val, err := strconv.Atoi(port)
 	if err != nil {
 		return fmt.Errorf("NetworkAddress Port ('%s') convert error: %w. Use integer type", port, err)
@@ -83,140 +229,190 @@ func (n *Config) Set(value string) error {
 	return nil
 }
 
-// validate is private func.
-// Checks Config's arguments.
-func (n *Config) validate() error {
-	if n.Port <= 1 {
-		return errors.New("args error: Port must be greater then 0")
+// Validate checks Config's arguments. All found errors are returned together.
+func (n *Config) Validate() error {
+	errs := make([]error, 0)
+	check := func(failed bool, msg string) {
+		if failed {
+			errs = append(errs, errors.New(msg))
+		}
+	}
+	if _, port, err := net.SplitHostPort(n.Address); err != nil {
+		errs = append(errs, fmt.Errorf("address ('%s') incorrect. Use value like: 'IP:PORT': %w", n.Address, err))
+	} else if p, err := strconv.Atoi(port); err != nil || p <= 1 {
+		errs = append(errs, fmt.Errorf("address port ('%s') must be integer greater then 1", port))
+	}
+	check(n.ReportInterval <= 0, "REPORT_INTERVAL must be greater then 0")
+	check(n.PollInterval <= 0, "POLL_INTERVAL must be greater then 0")
+	check(n.RateLimit <= 0, "rate limit must be greater then 0")
+	check(n.BufferMaxSize < 0 || n.BufferMaxAge < 0, "buffer limits must not be negative")
+	check(n.RetryAttempts <= 0, "retry attempts must be greater then 0")
+	check(n.RetryBaseDelay < 0 || n.RetryMaxDelay < 0, "retry delays must not be negative")
+	check(n.RetryJitter < 0 || n.RetryJitter > 1, "retry jitter must be from 0 to 1")
+	if _, err := n.RetryPolicy(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := n.Filter(); err != nil {
+		errs = append(errs, err)
+	}
+	check(n.HashScheme != signing.SchemeNonce && n.HashScheme != signing.SchemeBody,
+		fmt.Sprintf("hash scheme must be '%s' or '%s'", signing.SchemeNonce, signing.SchemeBody))
+	check(n.TargetsMode != metrics.ModeFailover && n.TargetsMode != metrics.ModeFanOut,
+		fmt.Sprintf("targets mode must be '%s' or '%s'", metrics.ModeFailover, metrics.ModeFanOut))
+	for i := 1; i < len(n.PauseBuckets); i++ {
+		if n.PauseBuckets[i] <= n.PauseBuckets[i-1] {
+			errs = append(errs, errors.New("pause buckets must be in ascending order"))
+			break
+		}
+	}
+	switch n.Output {
+	case metrics.OutputServer, metrics.OutputStdout:
+	case metrics.OutputFile:
+		check(n.OutputPath == "", "output path must be set for file output")
+	default:
+		errs = append(errs, fmt.Errorf("output must be '%s', '%s' or '%s'",
+			metrics.OutputServer, metrics.OutputStdout, metrics.OutputFile))
+	}
+	check(n.OutputMaxSize < 0 || n.OutputMaxFiles < 0, "output file limits must not be negative")
+	if err := n.Tracing().Validate(); err != nil {
+		errs = append(errs, err)
 	}
-	if n.ReportInterval <= 0 {
-		return errors.New("args error: REPORT_INTERVAL must be greater then 0")
+	if err := n.Logging().Validate(); err != nil {
+		errs = append(errs, err)
 	}
-	if n.PollInterval <= 0 {
-		return errors.New("args error: POLL_INTERVAL must be greater then 0")
+	if n.StatusAddress != "" && !isLocalAddress(n.StatusAddress) {
+		errs = append(errs, fmt.Errorf("status address ('%s') must be localhost address", n.StatusAddress))
 	}
-	if n.RateLimit <= 0 {
-		return errors.New("args error: rate limit must be greater then 0")
+	// Pushed metrics are signed by agent's key, so push API must not be available from network.
+	if n.PushAddress != "" && !strings.HasPrefix(n.PushAddress, "unix:") && !isLocalAddress(n.PushAddress) {
+		errs = append(errs, fmt.Errorf("push address ('%s') must be localhost address or unix socket", n.PushAddress))
+	}
+	for _, t := range n.Targets {
+		if _, _, err := net.SplitHostPort(t.Address); err != nil {
+			errs = append(errs, fmt.Errorf("target address ('%s') incorrect: %w", t.Address, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("args error: %w", err)
 	}
 	return nil
 }
 
-// envToInt is private func.
-// Checks enviroment exists the value and convert in to int.
-func envToInt(envName string, def int) (int, error) {
-	value, ok := os.LookupEnv(envName)
-	if !ok {
-		return def, nil
-	}
-	val, err := strconv.Atoi(value)
-	if err != nil {
-		return def, fmt.Errorf("enviroment value '%s' of '%s' type error: '%w'", value, envName, err)
+// Tracing returns tracing options. Stdout exporter writes to stderr if stdout is used for metrics output.
+func (n *Config) Tracing() tracing.Options {
+	o := tracing.Options{Exporter: n.TraceExporter, Endpoint: n.TraceEndpoint, Insecure: n.TraceInsecure}
+	if n.Output == metrics.OutputStdout {
+		o.Writer = os.Stderr
 	}
-	return val, nil
+	return o
 }
 
-// envToString is private func.
-func envToString(envName string, def string) string {
-	if value, ok := os.LookupEnv(envName); !ok {
-		return def
-	} else {
-		return value
-	}
+// Logging returns logger options.
+func (n *Config) Logging() logging.Options {
+	return logging.Options{Level: n.LogLevel, Format: n.LogFormat, File: n.LogFile}
 }
 
-// parcePublicKey reads rsa public key from file.
-func parcePublicKey(filePath string) (*rsa.PublicKey, error) {
-	data, err := os.ReadFile(filePath)
+// isLocalAddress is private func. Checks that address is 'localhost:port' or loopback 'ip:port'.
+func isLocalAddress(address string) bool {
+	host, _, err := net.SplitHostPort(address)
 	if err != nil {
-		return nil, fmt.Errorf("file read error: %w", err)
-	}
-	block, _ := pem.Decode([]byte(data)) //nolint:all //<-senselessly
-	if block == nil {
-		return nil, errors.New("failed to parse PEM block with publick key")
+		return false
 	}
-	pubKey, err := x509.ParsePKIXPublicKey(block.Bytes)
-	if err != nil {
-		return nil, fmt.Errorf("parce public key error: %w", err)
+	if host == "localhost" {
+		return true
 	}
-	pub, ok := pubKey.(*rsa.PublicKey)
-	if !ok {
-		return nil, errors.New("key type is not RSA")
-	}
-	return pub, nil
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
 }
 
-// lookFileConfig gets config values from file with path.
-func lookFileConfig(path string, a *Config) error {
-	defer a.setDefault()
-	if val, ok := os.LookupEnv("CONFIG"); ok {
-		path = val
-	}
-	if path == "" {
-		return nil
-	}
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return fmt.Errorf("config file read error: %w", err)
-	}
-	var c Config
-	err = json.Unmarshal(data, &c)
-	if err != nil {
-		return fmt.Errorf("config file convert error: %w", err)
-	}
-	if a.IP == "" {
-		err = a.Set(c.IP)
-		if err != nil {
-			return fmt.Errorf("config file IP error: %w", err)
+// Set parses servers list like 'host1:port,grpc://host2:port'.
+// Prefix 'grpc://' sets RPC sending, prefix 'http://' sets HTTP sending,
+// without prefix Config.SendByRPC value is used.
+func (t *TargetList) Set(value string) error {
+	items := make(TargetList, 0)
+	for _, item := range config.SplitList(value) {
+		target := TargetConfig{Address: item, defaultRPC: true}
+		if address, ok := strings.CutPrefix(item, grpcScheme); ok {
+			target = TargetConfig{Address: address, SendByRPC: true}
+		} else if address, ok := strings.CutPrefix(item, httpScheme); ok {
+			target = TargetConfig{Address: address}
 		}
+		items = append(items, target)
 	}
-	if a.PollInterval == 0 {
-		a.PollInterval = c.PollInterval
-	}
-	if a.ReportInterval == 0 {
-		a.ReportInterval = c.ReportInterval
-	}
-	if a.RateLimit == 0 {
-		a.RateLimit = c.RateLimit
-	}
-	if a.gzipCompress == "" {
-		a.GzipCompress = c.GzipCompress
-	}
-	if a.HashKey == "" {
-		a.HashKey = c.HashKey
+	*t = items
+	return nil
+}
+
+// String returns servers list like 'host1:port,grpc://host2:port'.
+func (t *TargetList) String() string {
+	items := make([]string, 0, len(*t))
+	for _, target := range *t {
+		switch {
+		case target.defaultRPC:
+			items = append(items, target.Address)
+		case target.SendByRPC:
+			items = append(items, grpcScheme+target.Address)
+		default:
+			items = append(items, httpScheme+target.Address)
+		}
 	}
-	if a.PublicKeyPath == "" {
-		a.PublicKeyPath = c.PublicKeyPath
+	return strings.Join(items, ",")
+}
+
+// Set parses rename rules like 'from=to,re:^Heap(.*)=heap_$1'.
+func (r *RenameRules) Set(value string) error {
+	items := make(RenameRules, 0)
+	for _, item := range config.SplitList(value) {
+		from, to, ok := strings.Cut(item, "=")
+		if !ok {
+			return fmt.Errorf("rename rule '%s' must be like 'from=to'", item)
+		}
+		items = append(items, metrics.RenameRule{From: from, To: to})
 	}
+	*r = items
 	return nil
 }
 
-// lookEnviroment gets config values from Enviroment.
-func lookEnviroment(a *Config) error {
-	if address, ok := os.LookupEnv("ADDRESS"); ok {
-		if err := a.Set(address); err != nil {
-			return fmt.Errorf("enviroment 'ADDRESS' value error: %w", err)
-		}
+// String returns rename rules like 'from=to,re:^Heap(.*)=heap_$1'.
+func (r *RenameRules) String() string {
+	items := make([]string, 0, len(*r))
+	for _, rule := range *r {
+		items = append(items, rule.From+"="+rule.To)
 	}
-	var err error
-	a.ReportInterval, err = envToInt("REPORT_INTERVAL", a.ReportInterval)
-	if err != nil {
+	return strings.Join(items, ",")
+}
+
+// loadKeys is private func. Reads public keys and sets values, which depend on other options.
+func (n *Config) loadKeys() error {
+	if err := n.Set(n.Address); err != nil {
 		return err
 	}
-	a.PollInterval, err = envToInt("POLL_INTERVAL", a.PollInterval)
-	if err != nil {
-		return err
+	if n.PublicKeyPath != "" {
+		key, err := rsakeys.ReadPublic(n.PublicKeyPath)
+		if err != nil {
+			return fmt.Errorf("public key error: %w", err)
+		}
+		n.PublicKey = key
 	}
-	a.RateLimit, err = envToInt("RATE_LIMIT", a.RateLimit)
-	if err != nil {
-		return err
+	if n.PrivateKeyPath != "" {
+		key, err := rsakeys.ReadPrivate(n.PrivateKeyPath)
+		if err != nil {
+			return fmt.Errorf("response key error: %w", err)
+		}
+		n.PrivateKey = key
 	}
-	a.HashKey = envToString("KEY", a.HashKey)
-	pKey := envToString("CRYPTO_KEY", a.PublicKeyPath)
-	if pKey != "" {
-		a.PublicKey, err = parcePublicKey(pKey)
+	for i, t := range n.Targets {
+		if t.defaultRPC {
+			n.Targets[i].SendByRPC = n.SendByRPC
+		}
+		if t.PublicKeyPath == "" {
+			continue
+		}
+		key, err := rsakeys.ReadPublic(t.PublicKeyPath)
 		if err != nil {
-			return err
+			return fmt.Errorf("target '%s' public key error: %w", t.Address, err)
 		}
+		n.Targets[i].PublicKey = key
 	}
 	return nil
 }
@@ -236,7 +432,12 @@ func getLocalIP() (*net.IP, error) {
 }
 
 // NewConfig return's configuration object for agent.
-// The list of parameters are taken from startup variables and environment variables.
+// The list of parameters are taken from startup variables, environment variables and config file.
+// Values priority: startup variables > environment > config file > defaults.
+// Config file path is set by '-c' ('-config') startup variable or CONFIG enviroment,
+// file format is JSON, YAML or TOML by file extension.
+// '-print-config' startup variable prints effective config with redacted keys,
+// in this case config.ErrPrinted is returned.
 //
 // Enviroment values:
 //
@@ -244,33 +445,228 @@ func getLocalIP() (*net.IP, error) {
 //	REPORT_INTERVAL - send request interval in seconds
 //	POLL_INTERVAL - update metrics interval in seconds
 //	RATE_LIMIT - max requests count
+//	KEY - key for requests hash, requests are not hashed if empty
+//	AGENT_ID - agent ID, which is sent to server to select the agent's key
+//	RESPONSE_KEY - path to agent's private key for gRPC responses decryption,
+//	the agent's public key must be registered on server
+//	HASH_SCHEME - requests signature: 'nonce' (default) - hash of timestamp, nonce and body, 'body' - hash of body only
+//	CRYPTO_KEY - path to public key for messages encryption
+//	STATSD_ADDRESS - UDP address for StatsD listener, like ':8125'
+//	PUSH_ADDRESS - local push API address, like 'localhost:8081' or 'unix:/tmp/agent.sock',
+//	only loopback addresses are allowed
+//	BUFFER_PATH - directory for batches which were not delivered to server
+//	BUFFER_MAX_SIZE - max size of not delivered batches in bytes
+//	BUFFER_MAX_AGE - max age of not delivered batch in seconds
+//	RETRY_MAX_ATTEMPTS - max send attempts
+//	RETRY_BASE_DELAY - delay before first retry in milliseconds
+//	RETRY_MAX_DELAY - max delay between retries in milliseconds
+//	RETRY_JITTER - retry delay deviation from 0 to 1
+//	RETRY_HTTP_CODES - retryable HTTP status codes, like '502,503'
+//	RETRY_GRPC_CODES - retryable gRPC codes, like 'Unavailable,ResourceExhausted'
+//	TARGETS - servers list instead of ADDRESS, like 'host1:8080,grpc://host2:3200'
+//	TARGETS_MODE - 'failover' (first healthy server) or 'fanout' (all servers)
+//	RUNTIME_METRICS - 'true' to collect runtime stats from runtime/metrics instead of runtime.MemStats
+//	PAUSE_BUCKETS - GC pauses histogram buckets in seconds, like '0.0001,0.001,0.01'
+//	METRICS_ALLOW - glob or regular expression ('re:' prefix) patterns of metrics for sending, like 'Heap*,PollCount'
+//	METRICS_DENY - patterns of metrics which are not sent, like '*Gauge'
+//	METRICS_RENAME - rename rules, like 'RandomValue=random,re:^Heap(.*)=heap_$1'
+//	METRICS_PREFIX - prefix for metrics names
+//	METRICS_LABELS - static labels for every metric, like 'host=web1,env=prod'
+//	OUTPUT - 'server' (default), 'stdout' or 'file' to write metrics as JSON lines instead of sending
+//	OUTPUT_PATH - output file path for 'file' output
+//	OUTPUT_MAX_SIZE - output file max size in bytes, the file is rotated when it is exceeded
+//	OUTPUT_MAX_FILES - count of rotated output files
+//	ONCE - 'true' to collect metrics once, send them and exit
+//	STATUS_ADDRESS - localhost address for status endpoint, like 'localhost:8082'
+//	TRACE_EXPORTER - spans exporter: 'stdout' or 'otlp', spans are not exported if empty
+//	TRACE_ENDPOINT - OTLP gRPC collector address like 'localhost:4317'
+//	TRACE_INSECURE - 'true' to connect to OTLP collector without TLS
+//	LOG_LEVEL - logger level: debug, info, warn or error
+//	LOG_FORMAT - logger format: 'console' (default) or 'json'
+//	LOG_FILE - logger output file, stderr if empty
 func NewConfig() (*Config, error) {
-	agentArgs := Config{}
+	args := os.Args[1:]
+	if flag.Parsed() {
+		// Command line was parsed by caller, for example by tests.
+		args = flag.Args()
+	}
+	return LoadConfig(args)
+}
+
+// LoadConfig creates configuration object for agent from args, environment and config file.
+func LoadConfig(args []string) (*Config, error) {
 	l, err := getLocalIP()
 	if err != nil {
 		return nil, err
 	}
-	agentArgs.LocalAddress = l
-	cfgPath := ""
-	if !flag.Parsed() {
-		flag.Var(&agentArgs, "a", "Net address like 'host:port'")
-		flag.IntVar(&agentArgs.PollInterval, "p", agentArgs.PollInterval, "Poll metricks interval")
-		flag.IntVar(&agentArgs.ReportInterval, "r", agentArgs.ReportInterval, "Report metricks interval")
-		flag.IntVar(&agentArgs.RateLimit, "l", agentArgs.RateLimit, "Rate limit")
-		flag.StringVar(&agentArgs.gzipCompress, "gzip", agentArgs.gzipCompress, "Use gzip compress in requests")
-		flag.StringVar(&agentArgs.HashKey, "k", "", "Key for HASHSUMM in SHA256")
-		flag.StringVar(&agentArgs.PublicKeyPath, "crypto-key", "", "Path to PUBLIC key file")
-		flag.StringVar(&cfgPath, "c", "", "Path to config file")
-		flag.StringVar(&cfgPath, "config", cfgPath, "Path to config file (the same as -c)")
-		flag.BoolVar(&agentArgs.SendByRPC, "rpc", agentArgs.SendByRPC,
-			"Use RPC for send data to server. Sets only by this arg")
-		flag.Parse()
-	}
-	if err := lookFileConfig(cfgPath, &agentArgs); err != nil {
+	cfg := Config{LocalAddress: l, args: args}
+	cfg.setDefault()
+	loader := config.NewLoader("agent")
+	fs := loader.Flags
+	fs.StringVar(&cfg.Address, "a", cfg.Address, "Net address like 'host:port'")
+	fs.IntVar(&cfg.PollInterval, "p", cfg.PollInterval, "Poll metricks interval")
+	fs.IntVar(&cfg.ReportInterval, "r", cfg.ReportInterval, "Report metricks interval")
+	fs.IntVar(&cfg.RateLimit, "l", cfg.RateLimit, "Rate limit")
+	loader.Var(&cfg.GzipCompress, "gzip", "Use gzip compress in requests (true or false)")
+	fs.StringVar(&cfg.HashKey, "k", cfg.HashKey, "Key for HASHSUMM in SHA256")
+	fs.StringVar(&cfg.HashScheme, "hash-scheme", cfg.HashScheme, "Requests signature scheme: nonce or body")
+	fs.StringVar(&cfg.AgentID, "agent-id", cfg.AgentID, "Agent ID for server's keys registry")
+	fs.StringVar(&cfg.PublicKeyPath, "crypto-key", cfg.PublicKeyPath, "Path to PUBLIC key file")
+	fs.StringVar(&cfg.PrivateKeyPath, "response-key", cfg.PrivateKeyPath, "Path to PRIVATE key file for responses")
+	fs.BoolVar(&cfg.SendByRPC, "rpc", cfg.SendByRPC, "Use RPC for send data to server. Sets only by this arg")
+	fs.StringVar(&cfg.StatsDAddress, "statsd", cfg.StatsDAddress, "UDP address for StatsD listener like 'host:port'")
+	fs.StringVar(&cfg.PushAddress, "push", cfg.PushAddress,
+		"Local push API address like 'localhost:port' or 'unix:/path/to/socket'")
+	fs.StringVar(&cfg.BufferPath, "buffer", cfg.BufferPath, "Directory for batches which were not delivered to server")
+	fs.IntVar(&cfg.BufferMaxSize, "buffer-size", cfg.BufferMaxSize, "Max size of not delivered batches in bytes")
+	fs.IntVar(&cfg.BufferMaxAge, "buffer-age", cfg.BufferMaxAge, "Max age of not delivered batch in seconds")
+	fs.IntVar(&cfg.RetryAttempts, "retry", cfg.RetryAttempts, "Max send attempts")
+	fs.IntVar(&cfg.RetryBaseDelay, "retry-delay", cfg.RetryBaseDelay, "Delay before first retry in milliseconds")
+	fs.IntVar(&cfg.RetryMaxDelay, "retry-max-delay", cfg.RetryMaxDelay, "Max delay between retries in milliseconds")
+	fs.Float64Var(&cfg.RetryJitter, "retry-jitter", cfg.RetryJitter, "Retry delay deviation from 0 to 1")
+	loader.Var(&cfg.RetryHTTPCodes, "retry-codes", "Retryable HTTP status codes like '502,503'")
+	loader.Var(&cfg.RetryGRPCCodes, "retry-grpc-codes", "Retryable gRPC codes like 'Unavailable,Aborted'")
+	fs.Var(&cfg.Targets, "targets", "Servers list like 'host1:port,grpc://host2:port'")
+	fs.StringVar(&cfg.TargetsMode, "targets-mode", cfg.TargetsMode, "Servers list mode: 'failover' or 'fanout'")
+	fs.BoolVar(&cfg.RuntimeMetrics, "runtime-metrics", cfg.RuntimeMetrics,
+		"Collect runtime stats from runtime/metrics instead of runtime.MemStats")
+	loader.Var(&cfg.PauseBuckets, "pause-buckets", "GC pauses histogram buckets in seconds like '0.0001,0.001'")
+	loader.Var(&cfg.Allow, "allow", "Patterns of metrics for sending like 'Heap*,re:^Num.*'")
+	loader.Var(&cfg.Deny, "deny", "Patterns of metrics which are not sent like '*Gauge'")
+	fs.Var(&cfg.Rename, "rename", "Metrics rename rules like 'RandomValue=random,re:^Heap(.*)=heap_$1'")
+	fs.StringVar(&cfg.Prefix, "prefix", cfg.Prefix, "Prefix for metrics names")
+	loader.Var(&cfg.Labels, "labels", "Static labels for every metric like 'host=web1,env=prod'")
+	fs.StringVar(&cfg.Output, "output", cfg.Output, "Metrics output: 'server', 'stdout' or 'file'")
+	fs.StringVar(&cfg.OutputPath, "output-path", cfg.OutputPath, "Output file path for 'file' output")
+	fs.IntVar(&cfg.OutputMaxSize, "output-size", cfg.OutputMaxSize, "Output file max size in bytes")
+	fs.IntVar(&cfg.OutputMaxFiles, "output-files", cfg.OutputMaxFiles, "Count of rotated output files")
+	fs.BoolVar(&cfg.Once, "once", cfg.Once, "Collect metrics once, send them and exit")
+	fs.StringVar(&cfg.StatusAddress, "status", cfg.StatusAddress, "Status endpoint address like 'localhost:port'")
+	fs.StringVar(&cfg.TraceExporter, "trace", cfg.TraceExporter, "Spans exporter: 'stdout' or 'otlp'")
+	fs.StringVar(&cfg.TraceEndpoint, "trace-endpoint", cfg.TraceEndpoint, "OTLP collector address like 'host:4317'")
+	fs.BoolVar(&cfg.TraceInsecure, "trace-insecure", cfg.TraceInsecure, "Connect to OTLP collector without TLS")
+	fs.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "Logger level: debug, info, warn or error")
+	fs.StringVar(&cfg.LogFormat, "log-format", cfg.LogFormat, "Logger format: 'console' or 'json'")
+	fs.StringVar(&cfg.LogFile, "log-file", cfg.LogFile, "Logger output file, stderr if empty")
+	if err = loader.Load(&cfg, args); err != nil {
+		return nil, err //nolint:wrapcheck //<-senselessly
+	}
+	if err = cfg.loadKeys(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Reload reads config file and enviroment again with the same startup variables.
+// Returns new Config object, n is not changed.
+func (n *Config) Reload() (*Config, error) {
+	return LoadConfig(n.args)
+}
+
+// restartChanges is private func. Returns names of changed options, which can't be applied without restart.
+func (n *Config) restartChanges(c *Config) []string {
+	var names []string
+	check := func(name string, changed bool) {
+		if changed {
+			names = append(names, name)
+		}
+	}
+	check("address", n.IP != c.IP || n.Port != c.Port)
+	check("targets", !sameTargets(n.Targets, c.Targets))
+	check("targets_mode", n.TargetsMode != c.TargetsMode)
+	check("gzip", n.GzipCompress != c.GzipCompress)
+	check("agent_id", n.AgentID != c.AgentID)
+	check("hash_scheme", n.HashScheme != c.HashScheme)
+	check("response_key", n.PrivateKeyPath != c.PrivateKeyPath)
+	check("statsd_address", n.StatsDAddress != c.StatsDAddress)
+	check("push_address", n.PushAddress != c.PushAddress)
+	check("status_address", n.StatusAddress != c.StatusAddress)
+	check("trace", n.Tracing() != c.Tracing())
+	check("log_format", n.LogFormat != c.LogFormat)
+	check("log_file", n.LogFile != c.LogFile)
+	check("buffer_path", n.BufferPath != c.BufferPath)
+	check("buffer_max_size", n.BufferMaxSize != c.BufferMaxSize)
+	check("buffer_max_age", n.BufferMaxAge != c.BufferMaxAge)
+	check("runtime_metrics", n.RuntimeMetrics != c.RuntimeMetrics)
+	check("pause_buckets", !reflect.DeepEqual(n.PauseBuckets, c.PauseBuckets))
+	check("output", n.Output != c.Output || n.OutputPath != c.OutputPath ||
+		n.OutputMaxSize != c.OutputMaxSize || n.OutputMaxFiles != c.OutputMaxFiles)
+	return names
+}
+
+// sameTargets is private func. Compares targets addresses and protocols, keys are not compared.
+func sameTargets(a, b []TargetConfig) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Address != b[i].Address || a[i].SendByRPC != b[i].SendByRPC {
+			return false
+		}
+	}
+	return true
+}
+
+// merge is private func. Returns copy of n with options from c, which can be changed without restart:
+// intervals, keys, rate limit, retry policy, metrics filter and log level.
+func (n *Config) merge(c *Config) *Config {
+	cfg := *n
+	cfg.PollInterval = c.PollInterval
+	cfg.ReportInterval = c.ReportInterval
+	cfg.RateLimit = c.RateLimit
+	cfg.HashKey = c.HashKey
+	cfg.PublicKey = c.PublicKey
+	cfg.PublicKeyPath = c.PublicKeyPath
+	if sameTargets(n.Targets, c.Targets) {
+		cfg.Targets = c.Targets
+	}
+	cfg.RetryAttempts = c.RetryAttempts
+	cfg.RetryBaseDelay = c.RetryBaseDelay
+	cfg.RetryMaxDelay = c.RetryMaxDelay
+	cfg.RetryJitter = c.RetryJitter
+	cfg.RetryHTTPCodes = c.RetryHTTPCodes
+	cfg.RetryGRPCCodes = c.RetryGRPCCodes
+	cfg.Allow = c.Allow
+	cfg.Deny = c.Deny
+	cfg.Rename = c.Rename
+	cfg.Prefix = c.Prefix
+	cfg.Labels = c.Labels
+	cfg.LogLevel = c.LogLevel
+	return &cfg
+}
+
+// targetKeys is private func. Returns target's keys, empty values are taken from Config.
+// Empty hash key is nil, so requests are not hashed.
+func (n *Config) targetKeys(t TargetConfig) metrics.TargetKeys {
+	keys := metrics.TargetKeys{PublicKey: n.PublicKey}
+	if n.HashKey != "" {
+		keys.Key = []byte(n.HashKey)
+	}
+	if t.Key != "" {
+		keys.Key = []byte(t.Key)
+	}
+	if t.PublicKey != nil {
+		keys.PublicKey = t.PublicKey
+	}
+	return keys
+}
+
+// settings is private func. Returns storage options, which can be changed while agent is running.
+func (n *Config) settings() (*metrics.Settings, error) {
+	r, err := n.RetryPolicy()
+	if err != nil {
 		return nil, err
 	}
-	if err := lookEnviroment(&agentArgs); err != nil {
+	f, err := n.Filter()
+	if err != nil {
 		return nil, err
 	}
-	return &agentArgs, agentArgs.validate()
+	s := metrics.Settings{Retry: r, Filter: f, RateLimit: n.RateLimit}
+	if len(n.Targets) == 0 {
+		s.Keys = []metrics.TargetKeys{n.targetKeys(TargetConfig{})}
+	}
+	for _, t := range n.Targets {
+		s.Keys = append(s.Keys, n.targetKeys(t))
+	}
+	return &s, nil
 }
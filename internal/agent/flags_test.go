@@ -1,15 +1,23 @@
 package agent
 
 import (
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/gostuding/go-metrics/internal/rsakeys"
 )
 
 func TestConfig_setDefault(t *testing.T) {
 	config := Config{}
 	config.setDefault()
 	t.Run("Set default values", func(t *testing.T) {
-		if config.Port != defPort {
-			t.Errorf("default port error. Want: %d, got: %d", defPort, config.Port)
+		if config.Address != defAddress {
+			t.Errorf("default address error. Want: %s, got: %s", defAddress, config.Address)
 		}
 		if config.PollInterval != defPoolInterval {
 			t.Errorf("default pollInterval error. Want: %d, got: %d", defPoolInterval, config.PollInterval)
@@ -23,5 +31,143 @@ func TestConfig_setDefault(t *testing.T) {
 		if config.RateLimit != defRateLimit {
 			t.Errorf("default rateLimit error. Want: %d, got: %d", defRateLimit, config.RateLimit)
 		}
+		if _, err := config.RetryPolicy(); err != nil {
+			t.Errorf("default retry policy error: %v", err)
+		}
 	})
 }
+
+func TestTargetList_Set(t *testing.T) {
+	var got TargetList
+	if err := got.Set("host1:8080, grpc://host2:3200,http://host3:8081"); err != nil {
+		t.Fatalf("TargetList.Set() error = %v", err)
+	}
+	want := TargetList{
+		{Address: "host1:8080", defaultRPC: true},
+		{Address: "host2:3200", SendByRPC: true},
+		{Address: "host3:8081", SendByRPC: false},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TargetList.Set() = %v, want %v", got, want)
+	}
+	if s := got.String(); s != "host1:8080,grpc://host2:3200,http://host3:8081" {
+		t.Errorf("TargetList.String() = %s", s)
+	}
+	cfg := Config{Address: defAddress, Targets: got, SendByRPC: true}
+	if err := cfg.loadKeys(); err != nil {
+		t.Fatalf("loadKeys() error = %v", err)
+	}
+	if !cfg.Targets[0].SendByRPC || cfg.Targets[2].SendByRPC {
+		t.Errorf("targets without scheme must use rpc arg: %v", cfg.Targets)
+	}
+}
+
+func TestRenameRules_Set(t *testing.T) {
+	var got RenameRules
+	if err := got.Set("RandomValue=random, re:^Heap(.*)=heap_$1"); err != nil {
+		t.Fatalf("RenameRules.Set() error = %v", err)
+	}
+	want := RenameRules{{From: "RandomValue", To: "random"}, {From: "re:^Heap(.*)", To: "heap_$1"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RenameRules.Set() = %v, want %v", got, want)
+	}
+	if err := got.Set("host"); err == nil {
+		t.Error("RenameRules.Set() error expected")
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := "address: localhost:3200\npoll_interval: 4\nreport_interval: 6\nkey: file\nallow: [Heap*]\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("POLL_INTERVAL", "5")
+	t.Setenv("KEY", "env")
+	cfg, err := LoadConfig([]string{"-c", path, "-k", "flag"})
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if cfg.IP != "localhost" || cfg.Port != 3200 || cfg.ReportInterval != 6 {
+		t.Errorf("file values error: %+v", cfg)
+	}
+	if cfg.PollInterval != 5 {
+		t.Errorf("enviroment must be used instead of file, got: %d", cfg.PollInterval)
+	}
+	if cfg.HashKey != "flag" {
+		t.Errorf("startup variable must be used instead of enviroment, got: %s", cfg.HashKey)
+	}
+	if !reflect.DeepEqual(cfg.Allow, []string{"Heap*"}) {
+		t.Errorf("file list error: %v", cfg.Allow)
+	}
+	_, err = LoadConfig([]string{"-a", "host", "-p", "0", "-targets-mode", "all", "-status", "0.0.0.0:8082",
+		"-push", ":8081"})
+	if err == nil || !strings.Contains(err.Error(), "POLL_INTERVAL") || !strings.Contains(err.Error(), "targets mode") ||
+		!strings.Contains(err.Error(), "address") || !strings.Contains(err.Error(), "status address") ||
+		!strings.Contains(err.Error(), "push address") {
+		t.Errorf("all validation errors expected, got: %v", err)
+	}
+}
+
+func TestConfig_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	write := func(data string) {
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`{"address": "localhost:8080", "poll_interval": 2, "key": "first", "rate_limit": 1}`)
+	t.Setenv("CONFIG", path)
+	cfg, err := NewConfig()
+	if err != nil {
+		t.Fatalf("NewConfig() error = %v", err)
+	}
+	write(`{"address": "localhost:9090", "poll_interval": 5, "key": "second", "rate_limit": 3, "prefix": "app.",
+		"log_level": "warn"}`)
+	n, err := cfg.Reload()
+	if err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if got := cfg.restartChanges(n); !reflect.DeepEqual(got, []string{"address"}) {
+		t.Errorf("restartChanges() = %v, want [address]", got)
+	}
+	got := cfg.merge(n)
+	if got.Port != 8080 || got.PollInterval != 5 || got.HashKey != "second" || got.RateLimit != 3 || got.Prefix != "app." ||
+		got.LogLevel != "warn" {
+		t.Errorf("merge() = %+v", got)
+	}
+	if cfg.HashKey != "first" {
+		t.Error("Reload() must not change config")
+	}
+}
+
+func TestLoadConfig_keys(t *testing.T) {
+	dir := t.TempDir()
+	key, err := rsakeys.Generate(rsakeys.MinSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := rsakeys.EncodePublic(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privatePath, publicPath := filepath.Join(dir, "private_key"), filepath.Join(dir, "public_key")
+	data := pem.EncodeToMemory(&pem.Block{Type: rsakeys.TypePrivatePKCS8, Bytes: pkcs8})
+	if err = os.WriteFile(privatePath, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(publicPath, public, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig([]string{"-crypto-key", publicPath, "-response-key", privatePath})
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if !key.Equal(cfg.PrivateKey) || !key.PublicKey.Equal(cfg.PublicKey) {
+		t.Error("PKCS #8 private key and public key must be read")
+	}
+}
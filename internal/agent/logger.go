@@ -0,0 +1,16 @@
+package agent
+
+import (
+	"github.com/gostuding/go-metrics/internal/logging"
+	"go.uber.org/zap"
+)
+
+// NewLogger creates agent's logger. Level of the logger is changed on config reload.
+// Format and output file are set by options, see Config.Logging.
+func NewLogger(o logging.Options) (*zap.Logger, error) {
+	logger, err := logging.New(o)
+	if err != nil {
+		return nil, err //nolint:wrapcheck //<-senselessly
+	}
+	return logger, nil
+}
@@ -0,0 +1,39 @@
+package metrics
+
+// addCounter is private func. Adds delta to counter value, which was not sent yet.
+// Must be called under ms.mx lock.
+func (ms *metricsStorage) addCounter(name string, delta int64) {
+	if item, ok := ms.MetricsSlice[name]; ok && item.MType == counter && item.Delta != nil {
+		delta += *item.Delta
+	}
+	ms.MetricsSlice[name] = metrics{ID: name, MType: counter, Delta: &delta}
+}
+
+// takeDeltas is private func. Removes counters and histograms from MetricsSlice when they are taken for sending.
+// Returns deltas of the batch, which must be restored if sending fails.
+// So every counter and histogram contains values since the last successful send and
+// overlapping sends never contain the same delta.
+// Must be called under ms.mx lock.
+func (ms *metricsStorage) takeDeltas() map[string]metrics {
+	deltas := make(map[string]metrics)
+	for name, item := range ms.MetricsSlice {
+		if (item.MType == counter && item.Delta != nil) || (item.MType == histogramType && item.Histogram != nil) {
+			deltas[name] = item
+			delete(ms.MetricsSlice, name)
+		}
+	}
+	return deltas
+}
+
+// restoreDeltas is private func. Returns counters and histograms of not delivered batch to MetricsSlice.
+// Must be called under ms.mx lock.
+func (ms *metricsStorage) restoreDeltas(deltas map[string]metrics) {
+	for name, item := range deltas {
+		switch item.MType {
+		case counter:
+			ms.addCounter(name, *item.Delta)
+		case histogramType:
+			ms.addHistogram(name, item.Histogram)
+		}
+	}
+}
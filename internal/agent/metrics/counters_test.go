@@ -0,0 +1,140 @@
+package metrics
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// countersServer is a fake server which sums received counters deltas.
+// Requests are held until gate is released, so sends are overlapped deterministically.
+type countersServer struct {
+	srv      *httptest.Server
+	gate     chan struct{}
+	arrived  chan struct{}
+	fail     atomic.Bool
+	mx       sync.Mutex
+	counters map[string]int64
+}
+
+func newCountersServer(t *testing.T) *countersServer {
+	t.Helper()
+	cs := countersServer{
+		gate:     make(chan struct{}),
+		arrived:  make(chan struct{}, 10), //nolint:gomnd //<-test value
+		counters: make(map[string]int64),
+	}
+	cs.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cs.arrived <- struct{}{}
+		<-cs.gate
+		if cs.fail.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		var items []metrics
+		if err := json.NewDecoder(r.Body).Decode(&items); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		cs.mx.Lock()
+		defer cs.mx.Unlock()
+		for _, m := range items {
+			if m.MType == counter && m.Delta != nil {
+				cs.counters[m.ID] += *m.Delta
+			}
+		}
+	}))
+	t.Cleanup(func() {
+		close(cs.gate)
+		cs.srv.Close()
+	})
+	return &cs
+}
+
+// wait waits for count requests to be held by the server.
+func (cs *countersServer) wait(t *testing.T, count int) {
+	t.Helper()
+	for i := 0; i < count; i++ {
+		select {
+		case <-cs.arrived:
+		case <-time.After(time.Second):
+			t.Fatal("request wait timeout")
+		}
+	}
+}
+
+// release lets count held requests to be processed.
+func (cs *countersServer) release(count int) {
+	for i := 0; i < count; i++ {
+		cs.gate <- struct{}{}
+	}
+}
+
+func (cs *countersServer) value(name string) int64 {
+	cs.mx.Lock()
+	defer cs.mx.Unlock()
+	return cs.counters[name]
+}
+
+// pending returns counter value which is not sent yet.
+func (ms *metricsStorage) pending(name string) int64 {
+	ms.mx.RLock()
+	defer ms.mx.RUnlock()
+	if item, ok := ms.MetricsSlice[name]; ok && item.Delta != nil {
+		return *item.Delta
+	}
+	return 0
+}
+
+func Test_metricsStorage_countersDelta(t *testing.T) {
+	cs := newCountersServer(t)
+	local := net.ParseIP("127.0.0.1")
+	ms := NewMemoryStorage(nil, zap.NewNop(), "", nil, 0, false, 2, &local, false)
+	ms.Targets = []*Target{NewTarget(strings.TrimPrefix(cs.srv.URL, "http://"), nil, nil, false)}
+	add := func(delta int64) {
+		ms.mx.Lock()
+		ms.addCounter("hits", delta)
+		ms.mx.Unlock()
+	}
+
+	t.Run("Overlapping sends", func(t *testing.T) {
+		add(5)
+		ms.SendMetricsSlice()
+		add(3)
+		ms.SendMetricsSlice()
+		cs.wait(t, 2)
+		add(1)
+		assert.Equal(t, int64(1), ms.pending("hits"), "in-flight deltas must not be pending")
+		cs.release(2)
+		assert.Eventually(t, func() bool { return cs.value("hits") == 8 },
+			time.Second, time.Millisecond, "every delta must be received once")
+	})
+	t.Run("Restore after failure", func(t *testing.T) {
+		cs.fail.Store(true)
+		ms.SendMetricsSlice()
+		cs.wait(t, 1)
+		add(2)
+		cs.release(1)
+		assert.Eventually(t, func() bool { return ms.pending("hits") == 3 },
+			time.Second, time.Millisecond, "failed batch deltas must be restored")
+		assert.Equal(t, int64(8), cs.value("hits"), "failed batch must not be counted")
+	})
+	t.Run("Send restored deltas", func(t *testing.T) {
+		cs.fail.Store(false)
+		ms.SendMetricsSlice()
+		cs.wait(t, 1)
+		cs.release(1)
+		assert.Eventually(t, func() bool { return cs.value("hits") == 11 },
+			time.Second, time.Millisecond, "received total must be equal to added total")
+		assert.Equal(t, int64(0), ms.pending("hits"), "nothing must be pending")
+	})
+}
@@ -0,0 +1,151 @@
+package metrics
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// regexPrefix marks pattern as regular expression, other patterns are globs.
+const regexPrefix = "re:"
+
+// labelName is Prometheus label name pattern, names are checked by server in the same way.
+var labelName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+type (
+	// RenameRule changes metric name From to To.
+	// From is exact name or regular expression with 're:' prefix,
+	// in the last case To can contain submatches like '$1'.
+	RenameRule struct {
+		From string `json:"from"` // name or regular expression
+		To   string `json:"to"`   // new name
+	}
+
+	// Filter contains rules which are applied to metrics before sending.
+	Filter struct {
+		Labels map[string]string // static labels which are added to every metric
+		Prefix string            // prefix for every metric name
+		allow  []matcher         // metric is sent if it matches one of patterns, all metrics if empty
+		deny   []matcher         // metric is not sent if it matches one of patterns
+		rename []renamer         // the first matched rule is applied
+	}
+
+	// matcher is private type. Checks metric name by glob or regular expression.
+	matcher func(name string) bool
+
+	// renamer is private type. Returns new name and true if rule is matched.
+	renamer func(name string) (string, bool)
+)
+
+// NewFilter creates metrics filter.
+//
+// Args:
+// allow []string - glob or regular expression ('re:' prefix) patterns of metrics for sending, all metrics if empty
+// deny []string - patterns of metrics which are never sent
+// rename []RenameRule - rename rules, the first matched rule is applied
+// prefix string - prefix for every metric name
+// labels map[string]string - static labels for every metric, 'le' and names with '__' prefix are reserved.
+func NewFilter(allow, deny []string, rename []RenameRule, prefix string, labels map[string]string) (*Filter, error) {
+	f := Filter{Prefix: prefix, Labels: labels}
+	for name := range labels {
+		if !labelName.MatchString(name) || strings.HasPrefix(name, "__") || name == "le" {
+			return nil, fmt.Errorf("label name '%s' is incorrect", name)
+		}
+	}
+	var err error
+	if f.allow, err = newMatchers(allow); err != nil {
+		return nil, fmt.Errorf("allow rules error: %w", err)
+	}
+	if f.deny, err = newMatchers(deny); err != nil {
+		return nil, fmt.Errorf("deny rules error: %w", err)
+	}
+	for _, r := range rename {
+		item, err := newRenamer(r)
+		if err != nil {
+			return nil, fmt.Errorf("rename rules error: %w", err)
+		}
+		f.rename = append(f.rename, item)
+	}
+	return &f, nil
+}
+
+// newMatchers is private func. Compiles patterns.
+func newMatchers(patterns []string) ([]matcher, error) {
+	items := make([]matcher, 0, len(patterns))
+	for _, p := range patterns {
+		if expr, ok := strings.CutPrefix(p, regexPrefix); ok {
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, fmt.Errorf("pattern '%s' compile error: %w", p, err)
+			}
+			items = append(items, re.MatchString)
+			continue
+		}
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("pattern '%s' error: %w", p, err)
+		}
+		p := p
+		items = append(items, func(name string) bool {
+			ok, _ := path.Match(p, name) //nolint:errcheck //<-pattern is checked
+			return ok
+		})
+	}
+	return items, nil
+}
+
+// newRenamer is private func. Compiles rename rule.
+func newRenamer(r RenameRule) (renamer, error) {
+	if r.From == "" || r.To == "" {
+		return nil, fmt.Errorf("rename rule '%s' -> '%s' is empty", r.From, r.To)
+	}
+	expr, ok := strings.CutPrefix(r.From, regexPrefix)
+	if !ok {
+		return func(name string) (string, bool) {
+			return r.To, name == r.From
+		}, nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("rename rule '%s' compile error: %w", r.From, err)
+	}
+	return func(name string) (string, bool) {
+		if !re.MatchString(name) {
+			return "", false
+		}
+		return re.ReplaceAllString(name, r.To), true
+	}, nil
+}
+
+// match is private func. Checks if name matches one of matchers.
+func match(items []matcher, name string) bool {
+	for _, m := range items {
+		if m(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply is private func. Returns metric with changed name and labels
+// or false if metric must not be sent. Allow and deny rules are checked by the original name.
+// Nil filter returns metric without changes.
+func (f *Filter) apply(m metrics) (metrics, bool) {
+	if f == nil {
+		return m, true
+	}
+	if (len(f.allow) > 0 && !match(f.allow, m.ID)) || match(f.deny, m.ID) {
+		return m, false
+	}
+	for _, r := range f.rename {
+		if name, ok := r(m.ID); ok {
+			m.ID = name
+			break
+		}
+	}
+	m.ID = f.Prefix + m.ID
+	if len(f.Labels) > 0 {
+		m.Labels = f.Labels
+	}
+	return m, true
+}
@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilter_apply(t *testing.T) {
+	f, err := NewFilter(
+		[]string{"Heap*", "re:^Num.*", "PollCount*"},
+		[]string{"*Gauge"},
+		[]RenameRule{{From: "PollCount", To: "polls"}, {From: "re:^Heap(.*)$", To: "heap_$1"}},
+		"app.",
+		map[string]string{"host": "web1"},
+	)
+	assert.NoError(t, err, "create filter error")
+	tests := []struct {
+		name string
+		id   string
+		want string
+		ok   bool
+	}{
+		{name: "Regex rename", id: "HeapAlloc", want: "app.heap_Alloc", ok: true},
+		{name: "Exact rename", id: "PollCount", want: "app.polls", ok: true},
+		{name: "Regex allow", id: "NumGC", want: "app.NumGC", ok: true},
+		{name: "Denied", id: "PollCountGauge", ok: false},
+		{name: "Not allowed", id: "RandomValue", ok: false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := f.apply(metrics{ID: tt.id, MType: gauge})
+			assert.Equal(t, tt.ok, ok, "filter result")
+			if ok {
+				assert.Equal(t, tt.want, got.ID, "metric name")
+				assert.Equal(t, "web1", got.Labels["host"], "metric labels")
+			}
+		})
+	}
+	t.Run("Nil filter", func(t *testing.T) {
+		var f *Filter
+		got, ok := f.apply(metrics{ID: "HeapAlloc"})
+		assert.True(t, ok, "nil filter must send all metrics")
+		assert.Equal(t, "HeapAlloc", got.ID, "metric name")
+	})
+	t.Run("Pattern error", func(t *testing.T) {
+		_, err := NewFilter([]string{"re:("}, nil, nil, "", nil)
+		assert.Error(t, err, "regex error expected")
+		_, err = NewFilter(nil, []string{"[a"}, nil, "", nil)
+		assert.Error(t, err, "glob error expected")
+		_, err = NewFilter(nil, nil, nil, "", map[string]string{"le": "1"})
+		assert.Error(t, err, "reserved label name error expected")
+		_, err = NewFilter(nil, nil, nil, "", map[string]string{"host-name": "web1"})
+		assert.Error(t, err, "label name error expected")
+	})
+}
@@ -16,7 +16,7 @@ const (
 )
 
 // MakeMetric is private func for create metrics object from id:value values.
-// It defines type of metrics from value's type (int64 or float64).
+// It defines type of metrics from value's type (int64, float64 or *histogram).
 func makeMetric(id string, value any) (*metrics, error) {
 	switch value.(type) {
 	case int, uint32, int64, uint64:
@@ -39,47 +39,49 @@ func makeMetric(id string, value any) (*metrics, error) {
 			MType: gauge,
 			Value: &val,
 		}, nil
+	case *histogram:
+		return &metrics{
+			ID:        id,
+			MType:     histogramType,
+			Histogram: value.(*histogram),
+		}, nil
 	default:
 		return nil, fmt.Errorf("convert error. metric '%s' type undefined", id)
 	}
 }
 
 // MakeMap is private func for create metrics map[string]any from runtime.MemStats.
-func makeMap(r *runtime.MemStats, pollCount *int64) map[string]any {
+// Monotonic runtime counters are uint64 totals, other values are float64 gauges.
+func makeMap(r *runtime.MemStats) map[string]any {
 	mass := make(map[string]any)
-	mass["Alloc"] = r.Alloc
-	mass["BuckHashSys"] = r.BuckHashSys
+	mass["Alloc"] = float64(r.Alloc)
+	mass["BuckHashSys"] = float64(r.BuckHashSys)
 	mass["Frees"] = r.Frees
 	mass["GCCPUFraction"] = r.GCCPUFraction
-	mass["GCSys"] = r.GCSys
-	mass["HeapAlloc"] = r.HeapAlloc
-	mass["HeapIdle"] = r.HeapIdle
-	mass["HeapInuse"] = r.HeapInuse
-	mass["HeapObjects"] = r.HeapObjects
-	mass["HeapReleased"] = r.HeapReleased
-	mass["HeapSys"] = r.HeapSys
-	mass["LastGC"] = r.LastGC
+	mass["GCSys"] = float64(r.GCSys)
+	mass["HeapAlloc"] = float64(r.HeapAlloc)
+	mass["HeapIdle"] = float64(r.HeapIdle)
+	mass["HeapInuse"] = float64(r.HeapInuse)
+	mass["HeapObjects"] = float64(r.HeapObjects)
+	mass["HeapReleased"] = float64(r.HeapReleased)
+	mass["HeapSys"] = float64(r.HeapSys)
+	mass["LastGC"] = float64(r.LastGC)
 	mass["Lookups"] = r.Lookups
-	mass["MCacheInuse"] = r.MCacheInuse
-	mass["MCacheSys"] = r.MCacheSys
-	mass["MSpanInuse"] = r.MSpanInuse
-	mass["MSpanSys"] = r.MSpanSys
+	mass["MCacheInuse"] = float64(r.MCacheInuse)
+	mass["MCacheSys"] = float64(r.MCacheSys)
+	mass["MSpanInuse"] = float64(r.MSpanInuse)
+	mass["MSpanSys"] = float64(r.MSpanSys)
 	mass["Mallocs"] = r.Mallocs
-	mass["NextGC"] = r.NextGC
-	mass["NumForcedGC"] = r.NumForcedGC
-	mass["NumGC"] = r.NumGC
-	mass["OtherSys"] = r.OtherSys
+	mass["NextGC"] = float64(r.NextGC)
+	mass["NumForcedGC"] = uint64(r.NumForcedGC)
+	mass["NumGC"] = uint64(r.NumGC)
+	mass["OtherSys"] = float64(r.OtherSys)
 	mass["PauseTotalNs"] = r.PauseTotalNs
-	mass["StackInuse"] = r.StackInuse
-	mass["StackSys"] = r.StackSys
+	mass["StackInuse"] = float64(r.StackInuse)
+	mass["StackSys"] = float64(r.StackSys)
 	mass["TotalAlloc"] = r.TotalAlloc
-	mass["Sys"] = r.Sys
+	mass["Sys"] = float64(r.Sys)
 	mass["RandomValue"] = rand.Float64()
-	if pollCount == nil {
-		mass[pCount] = 1
-	} else {
-		mass[pCount] = *pollCount + 1
-	}
 	return mass
 }
 
@@ -0,0 +1,156 @@
+package metrics
+
+import (
+	"errors"
+	"math"
+	rm "runtime/metrics"
+	"sort"
+)
+
+const (
+	histogramType = "histogram" // name for histogram type
+	gcPause       = "GCPause"   // GC pauses histogram name
+	gcPausesCount = 256         // size of runtime.MemStats.PauseNs
+	nsInSecond    = 1e9
+)
+
+// Runtime/metrics GC pauses keys, the first supported key is used.
+var runtimePauseKeys = []string{"/sched/pauses/total/gc:seconds", "/gc/pauses:seconds"}
+
+// DefaultPauseBuckets are default GC pauses histogram buckets in seconds.
+var DefaultPauseBuckets = []float64{ //nolint:gochecknoglobals //<-default values
+	0.00001, 0.00005, 0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1,
+}
+
+// Histogram contains observations count in buckets.
+// Counts has one item more then Buckets, the last item is count of observations greater then the last bucket.
+type histogram struct {
+	Buckets []float64 `json:"buckets"` // buckets upper bounds in ascending order
+	Counts  []uint64  `json:"counts"`  // observations count in each bucket, not cumulative
+	Count   uint64    `json:"count"`   // observations count
+	Sum     float64   `json:"sum"`     // observations sum
+}
+
+// newHistogram is private func. Creates empty histogram with buckets.
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{
+		Buckets: append([]float64{}, buckets...),
+		Counts:  make([]uint64, len(buckets)+1),
+	}
+}
+
+// observe is private func. Adds count values to histogram.
+func (h *histogram) observe(value float64, count uint64) {
+	i := sort.SearchFloat64s(h.Buckets, value)
+	h.Counts[i] += count
+	h.Count += count
+	h.Sum += value * float64(count)
+}
+
+// merge is private func. Adds other histogram observations to h.
+func (h *histogram) merge(other *histogram) error {
+	if len(h.Buckets) != len(other.Buckets) || len(h.Counts) != len(other.Counts) {
+		return errors.New("histogram buckets mismatch")
+	}
+	for i, b := range h.Buckets {
+		if b != other.Buckets[i] {
+			return errors.New("histogram buckets mismatch")
+		}
+	}
+	for i, c := range other.Counts {
+		h.Counts[i] += c
+	}
+	h.Count += other.Count
+	h.Sum += other.Sum
+	return nil
+}
+
+// addHistogram is private func. Adds histogram to histogram value, which was not sent yet.
+// Not sent value is replaced if buckets were changed.
+// Must be called under ms.mx lock.
+func (ms *metricsStorage) addHistogram(name string, h *histogram) {
+	item := newHistogram(h.Buckets)
+	if err := item.merge(h); err != nil {
+		ms.Logger.Warnf("histogram '%s' error: %v", name, err)
+		return
+	}
+	if old, ok := ms.MetricsSlice[name]; ok && old.Histogram != nil {
+		if err := item.merge(old.Histogram); err != nil {
+			ms.Logger.Debugf("histogram '%s' buckets changed, not sent value is dropped", name)
+		}
+	}
+	ms.MetricsSlice[name] = metrics{ID: name, MType: histogramType, Histogram: item}
+}
+
+// pausesFromMemStats is private func. Returns histogram of GC pauses, which were made since previous call.
+// Only the last 256 pauses are available in runtime.MemStats.
+func (ms *metricsStorage) pausesFromMemStats(numGC uint32, pauses *[gcPausesCount]uint64) *histogram {
+	h := newHistogram(ms.PauseBuckets)
+	from := ms.lastNumGC
+	if numGC-from > gcPausesCount {
+		from = numGC - gcPausesCount
+	}
+	for k := from + 1; k <= numGC; k++ {
+		h.observe(float64(pauses[(k+gcPausesCount-1)%gcPausesCount])/nsInSecond, 1)
+	}
+	ms.lastNumGC = numGC
+	return h
+}
+
+// pauses is private func. Returns histogram of GC pauses from runtime/metrics, which were made since previous call.
+// Runtime histogram buckets are converted to buckets by their upper bounds, so sum is approximate.
+// Must be called after makeMap.
+func (rs *runtimeStats) pauses(buckets []float64) *histogram {
+	h := newHistogram(buckets)
+	rh := rs.pausesHistogram()
+	if rh == nil {
+		return h
+	}
+	if len(rs.lastPauses) != len(rh.Counts) {
+		rs.lastPauses = make([]uint64, len(rh.Counts))
+	}
+	for j, c := range rh.Counts {
+		delta := c - rs.lastPauses[j]
+		rs.lastPauses[j] = c
+		if delta == 0 {
+			continue
+		}
+		h.observe(bucketValue(rh, j), delta)
+	}
+	return h
+}
+
+// pauseTotalNs is private func. Returns approximate total GC pauses time in nanoseconds from runtime/metrics
+// like runtime.MemStats.PauseTotalNs. Pauses are counted by upper bounds of their buckets as in pauses.
+func (rs *runtimeStats) pauseTotalNs() (uint64, bool) {
+	rh := rs.pausesHistogram()
+	if rh == nil {
+		return 0, false
+	}
+	var total float64
+	for j, c := range rh.Counts {
+		if c > 0 {
+			total += bucketValue(rh, j) * float64(c)
+		}
+	}
+	return uint64(total * nsInSecond), true
+}
+
+// pausesHistogram is private func. Returns GC pauses histogram by the first supported key or nil.
+func (rs *runtimeStats) pausesHistogram() *rm.Float64Histogram {
+	for _, key := range runtimePauseKeys {
+		if i, ok := rs.index[key]; ok && rs.samples[i].Value.Kind() == rm.KindFloat64Histogram {
+			return rs.samples[i].Value.Float64Histogram()
+		}
+	}
+	return nil
+}
+
+// bucketValue is private func. Returns upper bound of runtime histogram bucket,
+// lower bound is used for the last infinite bucket.
+func bucketValue(rh *rm.Float64Histogram, j int) float64 {
+	if value := rh.Buckets[j+1]; !math.IsInf(value, 1) {
+		return value
+	}
+	return rh.Buckets[j]
+}
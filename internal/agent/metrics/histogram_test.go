@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func Test_metricsStorage_pausesFromMemStats(t *testing.T) {
+	ms := NewMemoryStorage(nil, zap.NewNop(), "", nil, 0, false, 1, nil, false)
+	ms.PauseBuckets = []float64{0.001, 0.01}
+	var pauses [gcPausesCount]uint64
+	pauses[0], pauses[1] = 500_000, 5_000_000
+	h := ms.pausesFromMemStats(2, &pauses)
+	assert.Equal(t, []uint64{1, 1, 0}, h.Counts, "pauses counts")
+	assert.InDelta(t, 0.0055, h.Sum, 1e-9, "pauses sum")
+
+	pauses[2] = 50_000_000
+	h = ms.pausesFromMemStats(3, &pauses)
+	assert.Equal(t, []uint64{0, 0, 1}, h.Counts, "only new pauses must be observed")
+}
+
+func Test_metricsStorage_histogramDeltas(t *testing.T) {
+	ms := NewMemoryStorage(nil, zap.NewNop(), "", nil, 0, false, 1, nil, false)
+	h := newHistogram([]float64{1})
+	h.observe(0.5, 2)
+	ms.addHistogram(gcPause, h)
+	deltas := ms.takeDeltas()
+	_, ok := ms.MetricsSlice[gcPause]
+	assert.False(t, ok, "histogram must be taken for sending")
+
+	h = newHistogram([]float64{1})
+	h.observe(3, 1)
+	ms.addHistogram(gcPause, h)
+	ms.restoreDeltas(deltas)
+	assert.Equal(t, []uint64{2, 1}, ms.MetricsSlice[gcPause].Histogram.Counts, "restored histogram counts")
+	assert.Equal(t, uint64(3), ms.MetricsSlice[gcPause].Histogram.Count, "restored histogram count")
+}
+
+func Test_runtimeStats_pauses(t *testing.T) {
+	rs := newRuntimeStats()
+	rs.makeMap()
+	rs.pauses(DefaultPauseBuckets)
+	runtime.GC()
+	rs.makeMap()
+	h := rs.pauses(DefaultPauseBuckets)
+	assert.Equal(t, len(DefaultPauseBuckets)+1, len(h.Counts), "counts length")
+	assert.GreaterOrEqual(t, h.Count, uint64(1), "forced GC pauses must be observed")
+}
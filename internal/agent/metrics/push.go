@@ -0,0 +1,142 @@
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/go-chi/chi"
+)
+
+// Push API values.
+const (
+	unixPrefix    = "unix:"            // address prefix for unix socket listening
+	contentType   = "Content-Type"     // header name
+	jsonType      = "application/json" // header value
+	pushReadLimit = 1 << 20            // max request body size
+)
+
+// addPushMetric is private func and adds one metric received by push API in MetricsSlice.
+// Counters and histograms are accumulated until next SendMetricsSlice call, gauges are replaced.
+// Must be called under ms.mx lock.
+func (ms *metricsStorage) addPushMetric(m metrics) (*metrics, error) {
+	if m.ID == "" {
+		return nil, errors.New("metric id undefined")
+	}
+	switch m.MType {
+	case counter:
+		if m.Delta == nil {
+			return nil, fmt.Errorf("metric '%s' delta undefined", m.ID)
+		}
+		ms.addCounter(m.ID, *m.Delta)
+		m = ms.MetricsSlice[m.ID]
+		return &m, nil
+	case gauge:
+		if m.Value == nil {
+			return nil, fmt.Errorf("metric '%s' value undefined", m.ID)
+		}
+		value := *m.Value
+		m = metrics{ID: m.ID, MType: gauge, Value: &value}
+	case histogramType:
+		if m.Histogram == nil || len(m.Histogram.Counts) != len(m.Histogram.Buckets)+1 {
+			return nil, fmt.Errorf("metric '%s' histogram error", m.ID)
+		}
+		ms.addHistogram(m.ID, m.Histogram)
+		m = ms.MetricsSlice[m.ID]
+		return &m, nil
+	default:
+		return nil, fmt.Errorf("metric '%s' type '%s' error, use counter, gauge or histogram", m.ID, m.MType)
+	}
+	ms.MetricsSlice[m.ID] = m
+	return &m, nil
+}
+
+// pushRouter is private func. Creates handlers for push API.
+// The handlers accept the same JSON as server's '/update/' and '/updates/'.
+func (ms *metricsStorage) pushRouter() http.Handler {
+	router := chi.NewRouter()
+	router.Post("/update/", func(w http.ResponseWriter, r *http.Request) {
+		var m metrics
+		if err := json.NewDecoder(io.LimitReader(r.Body, pushReadLimit)).Decode(&m); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			ms.Logger.Debugf("push metric decode error: %v", err)
+			return
+		}
+		ms.mx.Lock()
+		item, err := ms.addPushMetric(m)
+		ms.mx.Unlock()
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			ms.Logger.Debugf("push metric error: %v", err)
+			return
+		}
+		data, err := json.Marshal(item)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			ms.Logger.Warnf("push metric marshal error: %v", err)
+			return
+		}
+		w.Header().Set(contentType, jsonType)
+		if _, err = w.Write(data); err != nil {
+			ms.Logger.Warnf("push metric write response error: %v", err)
+		}
+	})
+	router.Post("/updates/", func(w http.ResponseWriter, r *http.Request) {
+		var mSlice []metrics
+		if err := json.NewDecoder(io.LimitReader(r.Body, pushReadLimit)).Decode(&mSlice); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			ms.Logger.Debugf("push metrics decode error: %v", err)
+			return
+		}
+		resp := ""
+		ms.mx.Lock()
+		for index, m := range mSlice {
+			if _, err := ms.addPushMetric(m); err != nil {
+				resp += fmt.Sprintf("%d. '%s' update ERROR: %v\n", index+1, m.ID, err)
+			} else {
+				resp += fmt.Sprintf("%d. '%s' update SUCCESS \n", index+1, m.ID)
+			}
+		}
+		ms.mx.Unlock()
+		if _, err := w.Write([]byte(resp)); err != nil {
+			ms.Logger.Warnf("push metrics write response error: %v", err)
+		}
+	})
+	return router
+}
+
+// ListenPush starts local HTTP server for applications metrics.
+// Use 'host:port' address for TCP or 'unix:/path/to/socket' for unix socket.
+// Received metrics are sent to server with the agent's metrics.
+// Server finishes when Close is called.
+func (ms *metricsStorage) ListenPush(address string) error {
+	var listen net.Listener
+	var err error
+	if path, ok := strings.CutPrefix(address, unixPrefix); ok {
+		if err = os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("remove old unix socket error: %w", err)
+		}
+		listen, err = net.Listen("unix", path)
+	} else {
+		listen, err = net.Listen("tcp", address)
+	}
+	if err != nil {
+		return fmt.Errorf("push API listen error: %w", err)
+	}
+	srv := &http.Server{Handler: ms.pushRouter()} //nolint:gosec //<-local server
+	ms.mx.Lock()
+	ms.pushServer = srv
+	ms.mx.Unlock()
+	ms.Logger.Infof("Push API run at address: %s", listen.Addr().String())
+	go func() {
+		if err := srv.Serve(listen); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			ms.Logger.Warnf("push API serve error: %v", err)
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func Test_metricsStorage_pushRouter(t *testing.T) {
+	ms := NewMemoryStorage(nil, zap.NewNop(), "", nil, 0, false, 1, nil, false)
+	router := ms.pushRouter()
+	tests := []struct {
+		name   string
+		url    string
+		body   string
+		status int
+	}{
+		{
+			name:   "Update counter",
+			url:    "/update/",
+			body:   `{"id":"app.hits","type":"counter","delta":2}`,
+			status: http.StatusOK,
+		},
+		{
+			name:   "Update gauge",
+			url:    "/update/",
+			body:   `{"id":"app.load","type":"gauge","value":0.5}`,
+			status: http.StatusOK,
+		},
+		{
+			name:   "Update slice",
+			url:    "/updates/",
+			body:   `[{"id":"app.hits","type":"counter","delta":3},{"id":"app.load","type":"gauge","value":1.5}]`,
+			status: http.StatusOK,
+		},
+		{
+			name:   "Type error",
+			url:    "/update/",
+			body:   `{"id":"app.hits","type":"set","delta":2}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Delta error",
+			url:    "/update/",
+			body:   `{"id":"app.hits","type":"counter"}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "JSON error",
+			url:    "/updates/",
+			body:   `{"id":"app.hits"`,
+			status: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tt.url, strings.NewReader(tt.body)))
+			assert.Equal(t, tt.status, w.Code, "status code")
+		})
+	}
+	assert.Equal(t, int64(5), *ms.MetricsSlice["app.hits"].Delta, "counter accumulate error")
+	assert.Equal(t, 1.5, *ms.MetricsSlice["app.load"].Value, "gauge update error")
+	ms.takeDeltas()
+	_, ok := ms.MetricsSlice["app.hits"]
+	assert.False(t, ok, "pushed counter must be reset after send")
+}
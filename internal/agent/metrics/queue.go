@@ -0,0 +1,213 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Queue values.
+const (
+	queueFileExt   = ".batch" // queue file extension
+	queueDirMode   = 0700     // queue directory permissions
+	queueFileMode  = 0600     // queue file permissions
+	queueLength    = "OutboundQueueLength"
+	queueBytes     = "OutboundQueueBytes"
+	queueDropped   = "OutboundQueueDropped"
+	queueNameSplit = "-"
+)
+
+// DiskQueue is bounded on-disk FIFO queue for metrics batches,
+// which were not delivered to server.
+type DiskQueue struct {
+	dir     string        // directory for batch files
+	maxSize int64         // max total size of files in bytes
+	maxAge  time.Duration // max batch age. Older batches are removed
+	mx      sync.Mutex    // mutex for files and stats
+	replay  sync.Mutex    // mutex for only one replay at the same time
+	size    int64         // current total size of files
+	count   int           // current batches count
+	dropped int64         // count of batches removed by limits
+	seq     int64         // sequence for files names
+}
+
+// NewDiskQueue creates queue in dir.
+// Batches which are left in dir from previous run are added to queue.
+//
+// Args:
+// dir string - directory for batch files
+// maxSize int64 - max total size of batches in bytes
+// maxAge time.Duration - max batch age.
+func NewDiskQueue(dir string, maxSize int64, maxAge time.Duration) (*DiskQueue, error) {
+	if err := os.MkdirAll(dir, queueDirMode); err != nil {
+		return nil, fmt.Errorf("create queue dir error: %w", err)
+	}
+	q := DiskQueue{dir: dir, maxSize: maxSize, maxAge: maxAge}
+	files, err := q.files()
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		info, err := os.Stat(filepath.Join(dir, f))
+		if err != nil {
+			return nil, fmt.Errorf("queue file stat error: %w", err)
+		}
+		q.size += info.Size()
+		q.count++
+	}
+	return &q, nil
+}
+
+// files is private func. Returns sorted list of batch files names.
+func (q *DiskQueue) files() ([]string, error) {
+	entries, err := os.ReadDir(q.dir)
+	if err != nil {
+		return nil, fmt.Errorf("read queue dir error: %w", err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), queueFileExt) {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// fileTime is private func. Returns batch creation time from file name.
+func fileTime(name string) time.Time {
+	ts, _, _ := strings.Cut(name, queueNameSplit)
+	nano, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(0, nano)
+}
+
+// remove is private func. Deletes batch file and updates queue stats.
+// Must be called under q.mx lock.
+func (q *DiskQueue) remove(name string) error {
+	path := filepath.Join(q.dir, name)
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("queue file stat error: %w", err)
+	}
+	if err = os.Remove(path); err != nil {
+		return fmt.Errorf("queue file remove error: %w", err)
+	}
+	q.size -= info.Size()
+	q.count--
+	return nil
+}
+
+// trim is private func. Removes expired batches and the oldest batches while size limit is exceeded.
+// Must be called under q.mx lock.
+func (q *DiskQueue) trim() error {
+	files, err := q.files()
+	if err != nil {
+		return err
+	}
+	for _, name := range files {
+		expired := q.maxAge > 0 && time.Since(fileTime(name)) > q.maxAge
+		if !expired && (q.maxSize <= 0 || q.size <= q.maxSize) {
+			break
+		}
+		if err = q.remove(name); err != nil {
+			return err
+		}
+		q.dropped++
+	}
+	return nil
+}
+
+// Push adds batch to the end of queue.
+func (q *DiskQueue) Push(body []byte) error {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+	if q.maxSize > 0 && int64(len(body)) > q.maxSize {
+		q.dropped++
+		return errors.New("batch size is greater then queue max size")
+	}
+	q.seq++
+	name := fmt.Sprintf("%020d%s%06d%s", time.Now().UnixNano(), queueNameSplit, q.seq, queueFileExt)
+	if err := os.WriteFile(filepath.Join(q.dir, name), body, queueFileMode); err != nil {
+		return fmt.Errorf("queue file write error: %w", err)
+	}
+	q.size += int64(len(body))
+	q.count++
+	return q.trim()
+}
+
+// pending is private func. Removes expired batches and returns the list of batches for replay.
+func (q *DiskQueue) pending() ([]string, error) {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+	if err := q.trim(); err != nil {
+		return nil, err
+	}
+	return q.files()
+}
+
+// read is private func. Reads batch file under q.mx lock.
+func (q *DiskQueue) read(name string) ([]byte, error) {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+	body, err := os.ReadFile(filepath.Join(q.dir, name))
+	if err != nil {
+		return nil, fmt.Errorf("queue file read error: %w", err)
+	}
+	return body, nil
+}
+
+// done is private func. Removes delivered batch.
+// The batch may be removed by limits while it was sent, it is not an error.
+func (q *DiskQueue) done(name string) error {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+	if err := q.remove(name); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Replay sends batches from queue in order by send func.
+// Delivered batches are removed. Replay stops at the first send error.
+// Only one replay runs at the same time, so the batches order is kept.
+// The queue is not locked while a batch is sent, so Push and Stats are not blocked by slow server.
+func (q *DiskQueue) Replay(send func([]byte) error) error {
+	q.replay.Lock()
+	defer q.replay.Unlock()
+	files, err := q.pending()
+	if err != nil {
+		return err
+	}
+	for _, name := range files {
+		body, err := q.read(name)
+		if errors.Is(err, os.ErrNotExist) {
+			continue // removed by limits
+		}
+		if err != nil {
+			return err
+		}
+		if err = send(body); err != nil {
+			return fmt.Errorf("replay batch error: %w", err)
+		}
+		if err = q.done(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stats returns batches count, total size in bytes and count of batches removed by limits.
+func (q *DiskQueue) Stats() (int, int64, int64) {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+	return q.count, q.size, q.dropped
+}
@@ -0,0 +1,139 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestDiskQueue_Replay(t *testing.T) {
+	q, err := NewDiskQueue(t.TempDir(), 0, 0)
+	assert.NoError(t, err, "create queue error")
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, q.Push([]byte(strconv.Itoa(i))), "push error")
+	}
+	count, size, _ := q.Stats()
+	assert.Equal(t, 3, count, "queue length")
+	assert.Equal(t, int64(3), size, "queue size")
+	t.Run("Stop at send error", func(t *testing.T) {
+		sent := make([]string, 0)
+		err := q.Replay(func(b []byte) error {
+			if len(sent) == 1 {
+				return errors.New("send error")
+			}
+			sent = append(sent, string(b))
+			return nil
+		})
+		assert.Error(t, err, "replay error expected")
+		assert.Equal(t, []string{"0"}, sent, "sent batches")
+		count, _, _ := q.Stats()
+		assert.Equal(t, 2, count, "queue length after error")
+	})
+	t.Run("Replay in order", func(t *testing.T) {
+		sent := make([]string, 0)
+		err := q.Replay(func(b []byte) error {
+			sent = append(sent, string(b))
+			return nil
+		})
+		assert.NoError(t, err, "replay error")
+		assert.Equal(t, []string{"1", "2"}, sent, "sent batches")
+		count, size, _ := q.Stats()
+		assert.Equal(t, 0, count, "queue length after replay")
+		assert.Equal(t, int64(0), size, "queue size after replay")
+	})
+	t.Run("Queue is not locked while sending", func(t *testing.T) {
+		assert.NoError(t, q.Push([]byte("3")), "push error")
+		sent := make([]string, 0)
+		err := q.Replay(func(b []byte) error {
+			sent = append(sent, string(b))
+			count, _, _ := q.Stats()
+			assert.Equal(t, 1, count, "queue length while sending")
+			return q.Push([]byte("4"))
+		})
+		assert.NoError(t, err, "replay error")
+		assert.Equal(t, []string{"3"}, sent, "only batches queued before replay are sent")
+		count, _, _ := q.Stats()
+		assert.Equal(t, 1, count, "batch pushed while sending is kept")
+	})
+}
+
+func TestDiskQueue_Limits(t *testing.T) {
+	t.Run("Size limit", func(t *testing.T) {
+		q, err := NewDiskQueue(t.TempDir(), 4, 0)
+		assert.NoError(t, err, "create queue error")
+		for _, b := range []string{"aa", "bb", "cc"} {
+			assert.NoError(t, q.Push([]byte(b)), "push error")
+		}
+		assert.Error(t, q.Push([]byte("too long")), "batch greater then limit")
+		count, size, dropped := q.Stats()
+		assert.Equal(t, 2, count, "queue length")
+		assert.Equal(t, int64(4), size, "queue size")
+		assert.Equal(t, int64(2), dropped, "dropped count")
+	})
+	t.Run("Age limit", func(t *testing.T) {
+		q, err := NewDiskQueue(t.TempDir(), 0, time.Millisecond)
+		assert.NoError(t, err, "create queue error")
+		assert.NoError(t, q.Push([]byte("old")), "push error")
+		time.Sleep(10 * time.Millisecond)
+		err = q.Replay(func(b []byte) error {
+			return fmt.Errorf("expired batch sent: %s", string(b))
+		})
+		assert.NoError(t, err, "replay error")
+		_, _, dropped := q.Stats()
+		assert.Equal(t, int64(1), dropped, "dropped count")
+	})
+	t.Run("Restore from dir", func(t *testing.T) {
+		dir := t.TempDir()
+		q, err := NewDiskQueue(dir, 0, 0)
+		assert.NoError(t, err, "create queue error")
+		assert.NoError(t, q.Push([]byte("data")), "push error")
+		q, err = NewDiskQueue(dir, 0, 0)
+		assert.NoError(t, err, "restore queue error")
+		count, size, _ := q.Stats()
+		assert.Equal(t, 1, count, "restored queue length")
+		assert.Equal(t, int64(4), size, "restored queue size")
+	})
+}
+
+func Test_metricsStorage_sendJSONToServer_queue(t *testing.T) {
+	var fail atomic.Bool
+	var received atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		received.Add(1)
+	}))
+	defer srv.Close()
+	host, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	assert.NoError(t, err, "server address error")
+	p, err := strconv.Atoi(port)
+	assert.NoError(t, err, "server port error")
+	local := net.ParseIP("127.0.0.1")
+	ms := NewMemoryStorage(nil, zap.NewNop(), host, nil, p, false, 1, &local, false)
+	ms.Queue, err = NewDiskQueue(t.TempDir(), 0, 0)
+	assert.NoError(t, err, "create queue error")
+
+	fail.Store(true)
+	ms.requestChan <- struct{}{}
+	ms.sendJSONToServer([]byte("[]"), nil, ms.requestChan)
+	count, _, _ := ms.Queue.Stats()
+	assert.Equal(t, 1, count, "failed batch must be saved in queue")
+
+	fail.Store(false)
+	ms.requestChan <- struct{}{}
+	ms.sendJSONToServer([]byte("[]"), nil, ms.requestChan)
+	count, _, _ = ms.Queue.Stats()
+	assert.Equal(t, 0, count, "queue must be replayed")
+	assert.Equal(t, int32(2), received.Load(), "replayed and new batches must be received")
+}
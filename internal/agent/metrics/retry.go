@@ -0,0 +1,138 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type (
+	// RetryPolicy describes how failed sends are repeated.
+	RetryPolicy struct {
+		HTTPCodes   []int         // retryable HTTP status codes
+		GRPCCodes   []codes.Code  // retryable gRPC status codes
+		BaseDelay   time.Duration // delay before the first retry
+		MaxDelay    time.Duration // max delay between retries
+		Jitter      float64       // part of delay for random deviation, from 0 to 1
+		MaxAttempts int           // max attempts count including the first one
+	}
+
+	// StatusError is returned when server responses with not 200 status code.
+	statusError struct {
+		Code int // HTTP status code
+	}
+
+	// TransportError is returned when request was not delivered to server.
+	transportError struct {
+		Err error
+	}
+)
+
+// Error implements error interface.
+func (e *statusError) Error() string {
+	return fmt.Sprintf("statusCode error: %d", e.Code)
+}
+
+// Error implements error interface.
+func (e *transportError) Error() string {
+	return fmt.Sprintf("send error: '%v'", e.Err)
+}
+
+// Unwrap returns the transport error.
+func (e *transportError) Unwrap() error {
+	return e.Err
+}
+
+// DefaultRetryPolicy returns policy with 3 attempts,
+// which retries 429, 502, 503, 504 HTTP codes and
+// Unavailable, ResourceExhausted, DeadlineExceeded gRPC codes.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 3,                      //nolint:gomnd //<-default values
+		BaseDelay:   100 * time.Millisecond, //nolint:gomnd //<-default values
+		MaxDelay:    5 * time.Second,        //nolint:gomnd //<-default values
+		Jitter:      0.2,                    //nolint:gomnd //<-default values
+		HTTPCodes: []int{
+			http.StatusTooManyRequests, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusGatewayTimeout,
+		},
+		GRPCCodes: []codes.Code{codes.Unavailable, codes.ResourceExhausted, codes.DeadlineExceeded},
+	}
+}
+
+// ParseGRPCCode converts code name like 'Unavailable' to codes.Code.
+func ParseGRPCCode(name string) (codes.Code, error) {
+	for c := codes.OK; c <= codes.Unauthenticated; c++ {
+		if strings.EqualFold(c.String(), name) {
+			return c, nil
+		}
+	}
+	return codes.Unknown, fmt.Errorf("gRPC code '%s' undefined", name)
+}
+
+// delay is private func. Returns delay before retry with number attempt.
+func (p *RetryPolicy) delay(attempt int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < attempt && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		d = time.Duration(float64(d) * (1 + p.Jitter*(rand.Float64()*2-1))) //nolint:gosec,gomnd //<-jitter
+	}
+	return d
+}
+
+// retryable is private func. Checks if the send error can be repeated.
+func (p *RetryPolicy) retryable(err error) bool {
+	var sErr *statusError
+	if errors.As(err, &sErr) {
+		for _, c := range p.HTTPCodes {
+			if c == sErr.Code {
+				return true
+			}
+		}
+		return false
+	}
+	var tErr *transportError
+	if errors.As(err, &tErr) {
+		return true
+	}
+	if s, ok := status.FromError(err); ok {
+		for _, c := range p.GRPCCodes {
+			if c == s.Code() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Do calls f until it succeeds, returns not retryable error or attempts are over.
+// Waiting between attempts is interrupted when done is closed.
+// Nil policy calls f once.
+func (p *RetryPolicy) Do(done <-chan struct{}, f func() error) error {
+	err := f()
+	if p == nil {
+		return err
+	}
+	for attempt := 1; attempt < p.MaxAttempts && err != nil && p.retryable(err); attempt++ {
+		timer := time.NewTimer(p.delay(attempt))
+		select {
+		case <-done:
+			timer.Stop()
+			return fmt.Errorf("retry interrupted by shutdown: %w", err)
+		case <-timer.C:
+		}
+		err = f()
+	}
+	return err
+}
@@ -0,0 +1,125 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRetryPolicy_delay(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	tests := []struct {
+		name    string
+		attempt int
+		want    time.Duration
+	}{
+		{name: "First retry", attempt: 1, want: 100 * time.Millisecond},
+		{name: "Second retry", attempt: 2, want: 200 * time.Millisecond},
+		{name: "Third retry", attempt: 3, want: 400 * time.Millisecond},
+		{name: "Max delay", attempt: 10, want: time.Second},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, p.delay(tt.attempt), "delay error")
+		})
+	}
+	t.Run("Jitter", func(t *testing.T) {
+		p := RetryPolicy{BaseDelay: 100 * time.Millisecond, Jitter: 0.5}
+		for i := 0; i < 10; i++ {
+			d := p.delay(1)
+			assert.GreaterOrEqual(t, d, 50*time.Millisecond, "min jitter delay")
+			assert.LessOrEqual(t, d, 150*time.Millisecond, "max jitter delay")
+		}
+	})
+}
+
+func TestRetryPolicy_retryable(t *testing.T) {
+	p := DefaultRetryPolicy()
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "Bad gateway", err: &statusError{Code: http.StatusBadGateway}, want: true},
+		{name: "Bad request", err: &statusError{Code: http.StatusBadRequest}, want: false},
+		{name: "Connection error", err: &transportError{Err: errors.New("connection reset")}, want: true},
+		{name: "gRPC unavailable", err: status.Error(codes.Unavailable, "unavailable"), want: true},
+		{name: "gRPC aborted", err: status.Error(codes.Aborted, "incorrect hash"), want: false},
+		{name: "Other error", err: errors.New("hash error"), want: false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, p.retryable(tt.err), "retryable error")
+		})
+	}
+}
+
+func TestRetryPolicy_Do(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 3, HTTPCodes: []int{http.StatusBadGateway}}
+	t.Run("Success after retry", func(t *testing.T) {
+		calls := 0
+		err := p.Do(nil, func() error {
+			calls++
+			if calls < 3 {
+				return &statusError{Code: http.StatusBadGateway}
+			}
+			return nil
+		})
+		assert.NoError(t, err, "send error")
+		assert.Equal(t, 3, calls, "calls count")
+	})
+	t.Run("Attempts are over", func(t *testing.T) {
+		calls := 0
+		err := p.Do(nil, func() error {
+			calls++
+			return &statusError{Code: http.StatusBadGateway}
+		})
+		assert.Error(t, err, "send error expected")
+		assert.Equal(t, 3, calls, "calls count")
+	})
+	t.Run("Not retryable", func(t *testing.T) {
+		calls := 0
+		err := p.Do(nil, func() error {
+			calls++
+			return &statusError{Code: http.StatusBadRequest}
+		})
+		assert.Error(t, err, "send error expected")
+		assert.Equal(t, 1, calls, "calls count")
+	})
+	t.Run("Shutdown", func(t *testing.T) {
+		done := make(chan struct{})
+		close(done)
+		p := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour, HTTPCodes: []int{http.StatusBadGateway}}
+		calls := 0
+		err := p.Do(done, func() error {
+			calls++
+			return &statusError{Code: http.StatusBadGateway}
+		})
+		assert.Error(t, err, "send error expected")
+		assert.Equal(t, 1, calls, "calls count")
+	})
+	t.Run("Nil policy", func(t *testing.T) {
+		var p *RetryPolicy
+		calls := 0
+		_ = p.Do(nil, func() error {
+			calls++
+			return &statusError{Code: http.StatusBadGateway}
+		})
+		assert.Equal(t, 1, calls, "calls count")
+	})
+}
+
+func TestParseGRPCCode(t *testing.T) {
+	c, err := ParseGRPCCode("unavailable")
+	assert.NoError(t, err, "parse error")
+	assert.Equal(t, codes.Unavailable, c, "code error")
+	_, err = ParseGRPCCode("Undefined")
+	assert.Error(t, err, "parse error expected")
+}
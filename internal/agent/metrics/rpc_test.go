@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/gostuding/go-metrics/internal/agentkeys"
+	pb "github.com/gostuding/go-metrics/internal/proto"
+	"github.com/gostuding/go-metrics/internal/server/interseptors"
+	"github.com/gostuding/go-metrics/internal/signing"
+)
+
+// rpcServer is a fake metrics server which returns response error.
+type rpcServer struct {
+	pb.UnimplementedMetricsServer
+	response string
+}
+
+func (s *rpcServer) AddMetrics(context.Context, *pb.MetricsRequest) (*pb.MetricsResponse, error) {
+	return &pb.MetricsResponse{Error: s.response}, nil
+}
+
+// startRPCServer is private func. Starts gRPC server with interceptors and returns its address.
+func startRPCServer(t *testing.T, srv *rpcServer, items ...grpc.UnaryServerInterceptor) string {
+	t.Helper()
+	listen, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err, "listen error")
+	s := grpc.NewServer(grpc.ChainUnaryInterceptor(items...))
+	pb.RegisterMetricsServer(s, srv)
+	go s.Serve(listen) //nolint:errcheck //<-stopped by cleanup
+	t.Cleanup(s.Stop)
+	return listen.Addr().String()
+}
+
+func Test_metricsStorage_sendByRPC(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048) //nolint:gomnd //<-test key size
+	require.NoError(t, err, "generate key error")
+	pub, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err, "marshal public key error")
+	registry, err := agentkeys.NewRegistry(filepath.Join(t.TempDir(), "keys.json"))
+	require.NoError(t, err, "create registry error")
+	_, err = registry.Add("agent-1", "secret", string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub})))
+	require.NoError(t, err, "add key error")
+	_, err = registry.Add("agent-2", "secret", "")
+	require.NoError(t, err, "add key error")
+	keys := registry.KeyFunc(nil)
+	verifier := signing.NewVerifier(signing.Options{Scheme: signing.SchemeNonce, Skew: signing.DefaultSkew},
+		signing.NewNonceCache())
+	srv := rpcServer{response: "update error"}
+	signed := startRPCServer(t, &srv,
+		interseptors.HashInterceptor(keys, verifier), interseptors.ResponseInterceptor(keys, registry.PublicKey))
+	unsigned := startRPCServer(t, &srv, interseptors.HashInterceptor(keys, verifier))
+
+	tests := []struct {
+		name       string
+		url        string
+		agentID    string
+		privateKey *rsa.PrivateKey
+		response   string
+		wantErr    string
+	}{
+		{name: "Encrypted", url: signed, agentID: "agent-1", privateKey: key, response: "update error",
+			wantErr: "server response error: update error"},
+		{name: "Encrypted success", url: signed, agentID: "agent-1", privateKey: key},
+		{name: "Signed", url: signed, agentID: "agent-2", response: "update error",
+			wantErr: "server response error: update error"},
+		{name: "Private key not set", url: signed, agentID: "agent-1", response: "update error",
+			wantErr: "response key is not set"},
+		{name: "Hash undefined", url: unsigned, agentID: "agent-2", wantErr: "check responce hash summ error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv.response = tt.response
+			ms := NewMemoryStorage(nil, zap.NewNop(), "", nil, 0, false, 1, nil, true)
+			ms.AgentID, ms.ResponseKey = tt.agentID, tt.privateKey
+			err := ms.sendByRPC(context.Background(), tt.url, []byte("secret"), []byte("[]"))
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
@@ -19,13 +19,20 @@ import (
 	"sync"
 	"time"
 
+	"github.com/gostuding/go-metrics/internal/agentkeys"
+	"github.com/gostuding/go-metrics/internal/logging"
 	pb "github.com/gostuding/go-metrics/internal/proto"
+	"github.com/gostuding/go-metrics/internal/signing"
+	"github.com/gostuding/go-metrics/internal/tracing"
 
 	"github.com/shirou/gopsutil/mem"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
 )
 
 // Const values.
@@ -34,34 +41,57 @@ const (
 	hashErrorString = "write hash summ error: '%w'" //
 )
 
+// tracerName is tracer name for agent's spans.
+const tracerName = "github.com/gostuding/go-metrics/internal/agent/metrics"
+
 type (
 	// MetricsStorage is object for use as Storager interface.
 	metricsStorage struct {
-		URL          string             // URL for requests send to server
-		MetricsSlice map[string]metrics // metrics storage
-		localAddress *net.IP            // Local IP addres
-		PublicKey    *rsa.PublicKey     // encription messages key
-		Logger       *zap.SugaredLogger // logger
-		resiveChan   chan resiveStruct  // chan for read requests results
-		requestChan  chan struct{}      // chan for make requests
-		Key          []byte             // check hash key
-		mx           sync.RWMutex       // mutex
-		GzipCompress bool               // flag to use gzip compress
-		SendByRPC    bool               // flag for send by gRPC instead of HTTP
-		Supplier     runtime.MemStats   // metrics data supplier
+		Targets        []*Target             // servers for send metrics
+		MetricsSlice   map[string]metrics    // metrics storage
+		localAddress   *net.IP               // Local IP addres
+		Logger         *zap.SugaredLogger    // logger
+		resiveChan     chan resiveStruct     // chan for read requests results
+		resiveDone     chan struct{}         // closed when resiveChan reader is finished
+		sends          sync.WaitGroup        // in-flight sends
+		requestChan    chan struct{}         // chan for make requests
+		statsdConn     net.PacketConn        // StatsD UDP listener
+		pushServer     *http.Server          // local push API server
+		statsdTimers   map[string]*timerStat // StatsD timers values between report ticks
+		runtimeTotals  map[string]uint64     // runtime counters values at the last poll
+		pollTotal      int64                 // polls count since start
+		mx             sync.RWMutex          // mutex
+		GzipCompress   bool                  // flag to use gzip compress
+		FanOut         bool                  // flag for send to all targets instead of failover
+		AgentID        string                // agent ID for server's keys registry, not sent if empty
+		HashScheme     string                // requests signature scheme, 'nonce' if empty
+		ResponseKey    *rsa.PrivateKey       // agent's private key for gRPC responses decryption
+		Supplier       runtime.MemStats      // metrics data supplier
+		Queue          *DiskQueue            // queue for not delivered batches, used in failover mode
+		Retry          *RetryPolicy          // policy for repeat failed sends
+		RuntimeMetrics bool                  // flag to collect runtime stats from runtime/metrics
+		PauseBuckets   []float64             // GC pauses histogram buckets, histogram is not collected if empty
+		Filter         *Filter               // rules which are applied to metrics before sending
+		Sink           Sink                  // output for metrics instead of targets, if set
+		lastNumGC      uint32                // GC count at the previous poll
+		runtimeStats   *runtimeStats         // runtime/metrics reader
+		closeChan      chan struct{}         // closed when storage is closing
+		droppedBatches int64                 // batches which were dropped because send chan was full
 	}
 
 	// Metrics is one metric struct.
 	metrics struct {
-		Value *float64 `json:"value,omitempty"` // gauge value
-		Delta *int64   `json:"delta,omitempty"` // counter value
-		ID    string   `json:"id"`              // metrics name
-		MType string   `json:"type"`            // metrics type: gauge or counter
+		Value     *float64          `json:"value,omitempty"`     // gauge value
+		Delta     *int64            `json:"delta,omitempty"`     // counter value
+		Histogram *histogram        `json:"histogram,omitempty"` // histogram value
+		Labels    map[string]string `json:"labels,omitempty"`    // static labels
+		ID        string            `json:"id"`                  // metrics name
+		MType     string            `json:"type"`                // metrics type: gauge, counter or histogram
 	}
 
 	// ResiveStruct is internal struct.
 	resiveStruct struct {
-		Metric *metrics
+		Deltas map[string]metrics // counters and histograms deltas of the sent batch
 		Err    error
 	}
 )
@@ -87,38 +117,30 @@ func NewMemoryStorage(
 	localIP *net.IP,
 	sendRPC bool,
 ) *metricsStorage {
-	var address string
-	if sendRPC {
-		address = fmt.Sprintf("%s:%d", ip, port)
-	} else {
-		address = fmt.Sprintf("http://%s/updates/", net.JoinHostPort(ip, fmt.Sprint(port)))
-	}
 	mS := metricsStorage{
-		MetricsSlice: make(map[string]metrics),
-		Logger:       logger.Sugar(),
-		PublicKey:    pk,
-		GzipCompress: compress,
-		Key:          key,
-		URL:          address,
-		resiveChan:   make(chan resiveStruct, rateLimit),
-		requestChan:  make(chan struct{}, rateLimit),
-		localAddress: localIP,
-		SendByRPC:    sendRPC,
+		MetricsSlice:  make(map[string]metrics),
+		Logger:        logger.Sugar(),
+		GzipCompress:  compress,
+		Targets:       []*Target{newTargetFromIP(ip, port, key, pk, sendRPC)},
+		resiveChan:    make(chan resiveStruct, rateLimit),
+		resiveDone:    make(chan struct{}),
+		requestChan:   make(chan struct{}, rateLimit),
+		localAddress:  localIP,
+		statsdTimers:  make(map[string]*timerStat),
+		runtimeTotals: make(map[string]uint64),
+		closeChan:     make(chan struct{}),
 	}
 
 	go func() {
 		for item := range mS.resiveChan {
 			if item.Err != nil {
-				mS.Logger.Warnf("send error: %w", item.Err)
-			} else {
+				mS.Logger.Warnf("send error: %v", item.Err)
 				mS.mx.Lock()
-				if item.Metric.ID == "PollCount" {
-					delta := *mS.MetricsSlice["PollCount"].Delta - *item.Metric.Delta
-					mS.MetricsSlice["PollCount"] = metrics{ID: "PollCount", MType: "counter", Delta: &delta}
-				}
+				mS.restoreDeltas(item.Deltas)
 				mS.mx.Unlock()
 			}
 		}
+		close(mS.resiveDone)
 	}()
 	return &mS
 }
@@ -133,11 +155,22 @@ func (ms *metricsStorage) addMetric(name string, value any) {
 	}
 }
 
+// resetTimers is private func. Removes StatsD timers after they were taken for sending.
+// Must be called under ms.mx lock.
+func (ms *metricsStorage) resetTimers() {
+	for name := range ms.statsdTimers {
+		for _, suffix := range []string{timerCount, timerSum, timerMin, timerMax, timerMean} {
+			delete(ms.MetricsSlice, name+suffix)
+		}
+	}
+	ms.statsdTimers = make(map[string]*timerStat)
+}
+
 // UpdateAditionalMetrics collects metrics from mem.VirtualMemoryStat.
 func (ms *metricsStorage) UpdateAditionalMetrics() {
 	memory, err := mem.VirtualMemory()
 	if err != nil {
-		ms.Logger.Warnf("get virtualmemory metric error: %w", err)
+		ms.Logger.Warnf("get virtualmemory metric error: %v", err)
 		return
 	}
 	mSlice := make(map[string]float64)
@@ -152,118 +185,263 @@ func (ms *metricsStorage) UpdateAditionalMetrics() {
 	ms.mx.Unlock()
 }
 
-// UpdateMetrics collects metrics from runtime.MemStats.
+// UpdateMetrics collects metrics from runtime.MemStats or from runtime/metrics if RuntimeMetrics is set.
+// Runtime counters are added as deltas since the previous poll,
+// their total values are added as gauges with 'Gauge' suffix.
 func (ms *metricsStorage) UpdateMetrics() {
-	var rStats runtime.MemStats
-	runtime.ReadMemStats(&rStats)
 	ms.mx.Lock()
-	for name, value := range makeMap(&rStats, ms.MetricsSlice["PollCount"].Delta) {
-		ms.addMetric(name, value)
-		if ms.MetricsSlice[name].MType == "counter" {
-			ms.addMetric(fmt.Sprintf("%sGauge", name), float64(*ms.MetricsSlice[name].Delta))
+	defer ms.mx.Unlock()
+	var values map[string]any
+	if ms.RuntimeMetrics {
+		if ms.runtimeStats == nil {
+			ms.runtimeStats = newRuntimeStats()
+		}
+		values = ms.runtimeStats.makeMap()
+		if len(ms.PauseBuckets) > 0 {
+			ms.addHistogram(gcPause, ms.runtimeStats.pauses(ms.PauseBuckets))
+		}
+	} else {
+		var rStats runtime.MemStats
+		runtime.ReadMemStats(&rStats)
+		values = makeMap(&rStats)
+		if len(ms.PauseBuckets) > 0 {
+			ms.addHistogram(gcPause, ms.pausesFromMemStats(rStats.NumGC, &rStats.PauseNs))
 		}
 	}
-	ms.mx.Unlock()
+	for name, value := range values {
+		total, ok := value.(uint64)
+		if !ok {
+			ms.addMetric(name, value)
+			continue
+		}
+		ms.addCounter(name, int64(total-ms.runtimeTotals[name]))
+		ms.runtimeTotals[name] = total
+		ms.addMetric(fmt.Sprintf("%sGauge", name), float64(total))
+	}
+	ms.pollTotal++
+	ms.addCounter(pCount, 1)
+	ms.addMetric(fmt.Sprintf("%sGauge", pCount), float64(ms.pollTotal))
 }
 
-// SendMetricsSlice sends metrics by JSON list.
-func (ms *metricsStorage) SendMetricsSlice() {
-	mSlice := make([]metrics, 0)
+// queueStats is private func. Returns outbound queues stats, nil if queues are not used.
+// Queues stats are read without ms.mx lock, so polling is not blocked by the queue.
+func (ms *metricsStorage) queueStats() map[string]float64 {
 	ms.mx.RLock()
-	defer ms.mx.RUnlock()
+	queues := ms.queues()
+	ms.mx.RUnlock()
+	if len(queues) == 0 {
+		return nil
+	}
+	var count int
+	var size, dropped int64
+	for _, q := range queues {
+		c, s, d := q.Stats()
+		count, size, dropped = count+c, size+s, dropped+d
+	}
+	return map[string]float64{queueLength: float64(count), queueBytes: float64(size), queueDropped: float64(dropped)}
+}
+
+// takeBatch is private func. Returns JSON batch of filtered metrics and deltas of the batch,
+// which are removed from storage.
+// Must be called under ms.mx lock.
+func (ms *metricsStorage) takeBatch(stats map[string]float64) ([]byte, map[string]metrics, error) {
+	for name, value := range stats {
+		ms.addMetric(name, value)
+	}
+	mSlice := make([]metrics, 0)
 	for _, item := range ms.MetricsSlice {
-		mSlice = append(mSlice, item)
+		if item, ok := ms.Filter.apply(item); ok {
+			mSlice = append(mSlice, item)
+		}
 	}
 	body, err := json.Marshal(mSlice)
 	if err != nil {
-		ms.Logger.Warnf("metrics slice conver error: %w", err)
+		return nil, nil, fmt.Errorf("metrics slice conver error: %w", err)
+	}
+	deltas := ms.takeDeltas()
+	ms.resetTimers()
+	return body, deltas, nil
+}
+
+// SendMetricsSlice sends metrics by JSON list.
+// Metrics are filtered and renamed by Filter rules before batching.
+// If the send chan is full and the queue is set, metrics are saved in the queue.
+func (ms *metricsStorage) SendMetricsSlice() {
+	stats := ms.queueStats()
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	body, deltas, err := ms.takeBatch(stats)
+	if err != nil {
+		ms.Logger.Warn(err)
 		return
 	}
 	select {
 	case ms.requestChan <- struct{}{}:
-		metric := ms.MetricsSlice["PollCount"]
-		go ms.sendJSONToServer(body, &metric)
+		ms.sends.Add(1)
+		go func() {
+			defer ms.sends.Done()
+			ms.sendJSONToServer(body, deltas, ms.requestChan)
+		}()
 		ms.Logger.Debug("Metrics slice send success")
 	default:
-		ms.Logger.Warnln("send metric slice error. Chan is full.")
+		queues := ms.queues()
+		if len(queues) == 0 {
+			ms.restoreDeltas(deltas)
+			ms.droppedBatches++
+			ms.Logger.Warnln("send metric slice error. Chan is full.")
+			return
+		}
+		for _, q := range queues {
+			if err = q.Push(body); err != nil {
+				ms.restoreDeltas(deltas)
+				ms.droppedBatches++
+				ms.Logger.Warnf("send chan is full, save metrics slice in queue error: %v", err)
+				return
+			}
+		}
+		ms.Logger.Warnln("send chan is full, metrics slice saved in queue")
 	}
 }
 
 // SendJSONToServer is private func for send requests to server.
-func (ms *metricsStorage) sendJSONToServer(body []byte, metric *metrics) {
+// Counters and histograms of the batch are restored in storage by resiveChan reader if sending fails.
+// The request place is released in requests chan, which was used for the send.
+func (ms *metricsStorage) sendJSONToServer(body []byte, deltas map[string]metrics, requests chan struct{}) {
 	defer func() {
-		<-ms.requestChan
+		<-requests
 	}()
+	deltas, err := ms.sendBatch(body, deltas)
+	ms.resiveChan <- resiveStruct{Err: err, Deltas: deltas}
+}
 
+// sendBatch is private func. Sends batch and returns deltas, which must be restored if sending fails.
+// If Sink is set, body is written to Sink instead of servers.
+// In failover mode body is sent to the first healthy target, in fan-out mode to all targets.
+func (ms *metricsStorage) sendBatch(body []byte, deltas map[string]metrics) (map[string]metrics, error) {
+	ctx, span := tracing.Start(context.Background(), tracerName, "SendMetricsSlice", trace.SpanKindInternal,
+		attribute.Int("metrics.batch_size", len(body)))
 	var err error
-	if ms.PublicKey != nil {
-		body, err = encryptMessage(body, ms.PublicKey)
+	switch {
+	case ms.Sink != nil:
+		err = ms.Sink.Write(body)
+	case ms.FanOut:
+		err = ms.sendFanOut(ctx, body)
+		// Not accepted deltas are kept by targets.
+		deltas = nil
+	default:
+		err = ms.deliver(ms.Queue, body, func(b []byte) error {
+			return ms.sendFailover(ctx, b)
+		})
+	}
+	tracing.End(span, err)
+	return deltas, err
+}
+
+// deliver is private func. Sends batches from queue and body by send func.
+// If the queue is set, batches from queue are sent before body.
+// When sending fails, body is saved in the queue and its counters are considered as sent.
+func (ms *metricsStorage) deliver(q *DiskQueue, body []byte, send func([]byte) error) error {
+	if q == nil {
+		return send(body)
+	}
+	if err := q.Replay(send); err != nil {
+		return ms.saveInQueue(q, body, err)
+	}
+	if err := send(body); err != nil {
+		return ms.saveInQueue(q, body, err)
+	}
+	return nil
+}
+
+// saveInQueue is private func. Saves body in the queue after send error.
+func (ms *metricsStorage) saveInQueue(q *DiskQueue, body []byte, sendErr error) error {
+	if err := q.Push(body); err != nil {
+		return fmt.Errorf("%w, save in queue error: %w", sendErr, err)
+	}
+	ms.Logger.Warnf("send error, metrics slice saved in queue: %v", sendErr)
+	return nil
+}
+
+// sendBody is private func. Encrypts and compresses body and sends it to target.
+// All attempts are sent with the same request ID.
+func (ms *metricsStorage) sendBody(ctx context.Context, t *Target, body []byte) error {
+	var err error
+	keys := t.keys()
+	if keys.PublicKey != nil {
+		body, err = encryptMessage(body, keys.PublicKey)
 		if err != nil {
-			ms.Logger.Warnf("metrics encription error: %w", err)
-			return
+			return fmt.Errorf("metrics encription error: %w", err)
 		}
 	}
 	if ms.GzipCompress {
 		var b bytes.Buffer
 		gz := gzip.NewWriter(&b)
-		_, err := gz.Write(body)
-		if err != nil {
-			ms.resiveChan <- resiveStruct{Err: fmt.Errorf("compress error: %w", err), Metric: metric}
-			return
+		if _, err = gz.Write(body); err != nil {
+			return fmt.Errorf("compress error: %w", err)
 		}
-		err = gz.Close()
-		if err != nil {
-			ms.resiveChan <- resiveStruct{Err: fmt.Errorf("compressor close error: %w", err), Metric: metric}
-			return
+		if err = gz.Close(); err != nil {
+			return fmt.Errorf("compressor close error: %w", err)
 		}
 		body = b.Bytes()
 	}
-	if ms.SendByRPC {
-		err = ms.sendByRPC(body)
-	} else {
-		err = ms.sendByHTTP(body)
-	}
-	if err != nil {
-		ms.resiveChan <- resiveStruct{Err: err, Metric: metric}
-		return
-	}
-	ms.resiveChan <- resiveStruct{Err: nil, Metric: metric}
+	ctx = logging.WithRequestID(ctx, logging.NewRequestID())
+	return ms.retry().Do(ms.closeChan, func() error {
+		if t.SendByRPC {
+			return ms.sendByRPC(ctx, t.URL, keys.Key, body)
+		}
+		return ms.sendByHTTP(ctx, t.URL, keys.Key, body)
+	})
 }
 
-func (ms *metricsStorage) sendByHTTP(body []byte) error {
+// sendByHTTP is private func. Sends body to server by HTTP in span with trace context in headers.
+func (ms *metricsStorage) sendByHTTP(ctx context.Context, url string, key, body []byte) (err error) {
+	ctx, span := tracing.Start(ctx, tracerName, "sendByHTTP", trace.SpanKindClient, attribute.String("http.url", url))
+	defer func() { tracing.End(span, err) }()
 	client := http.Client{}
-	req, err := http.NewRequest(http.MethodPost, ms.URL, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
 	if err != nil {
 		return fmt.Errorf("request create error: %w", err)
 	}
+	tracing.InjectHTTP(ctx, req.Header)
+	if id := logging.RequestID(ctx); id != "" {
+		req.Header.Set(logging.HeaderRequestID, id)
+	}
 	if ms.GzipCompress {
 		req.Header.Add("Content-Encoding", "gzip")
 	}
 	req.Header.Add("X-Real-IP", ms.localAddress.String())
+	if ms.AgentID != "" {
+		req.Header.Set(agentkeys.HeaderAgentID, ms.AgentID)
+	}
 	req.Body = io.NopCloser(bytes.NewReader(body))
 
-	if ms.Key != nil {
-		h := hmac.New(sha256.New, ms.Key)
-		_, err = h.Write(body)
+	if key != nil {
+		var sign signing.Signature
+		sign, err = signing.Sign(key, body, ms.HashScheme, time.Now())
 		if err != nil {
 			return fmt.Errorf(hashErrorString, err)
 		}
-		req.Header.Add(hashVarName, hashToString(h))
+		req.Header.Add(hashVarName, sign.Hash)
+		if sign.Nonce != "" {
+			req.Header.Set(signing.HeaderTimestamp, sign.Timestamp)
+			req.Header.Set(signing.HeaderNonce, sign.Nonce)
+		}
 	}
 	resp, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("send error: '%w'", err)
+		return &transportError{Err: err}
 	}
 	defer resp.Body.Close() //nolint:errcheck // <- senselessly
+	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("statusCode error: %d", resp.StatusCode)
+		return &statusError{Code: resp.StatusCode}
 	}
-	if ms.Key != nil {
+	if key != nil {
 		data, err := io.ReadAll(resp.Body)
 		if err != nil {
 			return fmt.Errorf("responce body read error: %w", err)
 		}
-		hash := hmac.New(sha256.New, ms.Key)
+		hash := hmac.New(sha256.New, key)
 		_, err = hash.Write(data)
 		if err != nil {
 			return fmt.Errorf("responce read hash summ error: '%w'", err)
@@ -275,8 +453,11 @@ func (ms *metricsStorage) sendByHTTP(body []byte) error {
 	return nil
 }
 
-func (ms *metricsStorage) sendByRPC(body []byte) error {
-	conn, err := grpc.Dial(ms.URL, grpc.WithTransportCredentials(insecure.NewCredentials()))
+// sendByRPC is private func. Sends body to server by gRPC in span with trace context in metadata.
+func (ms *metricsStorage) sendByRPC(ctx context.Context, url string, key, body []byte) (err error) {
+	ctx, span := tracing.Start(ctx, tracerName, "sendByRPC", trace.SpanKindClient, attribute.String("rpc.address", url))
+	defer func() { tracing.End(span, err) }()
+	conn, err := grpc.Dial(url, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
 		return fmt.Errorf("dial RPC error: %w", err)
 	}
@@ -286,48 +467,132 @@ func (ms *metricsStorage) sendByRPC(body []byte) error {
 	if ms.GzipCompress {
 		data["gzip"] = ""
 	}
-	if ms.Key != nil {
-		h := hmac.New(sha256.New, ms.Key)
-		_, err = h.Write(body)
+	if id := logging.RequestID(ctx); id != "" {
+		data[logging.MetadataRequestID] = id
+	}
+	if ms.AgentID != "" {
+		data[agentkeys.MetadataAgentID] = ms.AgentID
+	}
+	var sign signing.Signature
+	if key != nil {
+		sign, err = signing.Sign(key, body, ms.HashScheme, time.Now())
 		if err != nil {
 			return fmt.Errorf(hashErrorString, err)
 		}
-		data[hashVarName] = hashToString(h)
+		data[hashVarName] = sign.Hash
+		if sign.Nonce != "" {
+			data[signing.MetadataTimestamp] = sign.Timestamp
+			data[signing.MetadataNonce] = sign.Nonce
+		}
 	}
 	md := metadata.New(data)
-	ctx := metadata.NewOutgoingContext(context.Background(), md)
-	resp, err := c.AddMetrics(ctx, &pb.MetricsRequest{Metrics: body})
+	tracing.InjectGRPC(ctx, md)
+	ctx = metadata.NewOutgoingContext(ctx, md)
+	var header metadata.MD
+	resp, err := c.AddMetrics(ctx, &pb.MetricsRequest{Metrics: body}, grpc.Header(&header))
 	if err != nil {
 		return fmt.Errorf("send by RPC error: %w", err)
 	}
+	if err = ms.checkRPCResponse(resp, header, key, sign); err != nil {
+		return err
+	}
 	if resp.Error != "" {
 		return fmt.Errorf("server response error: %s", resp.Error)
 	}
 	return nil
 }
 
+// checkRPCResponse is private func. Checks response hash from header metadata, if key is set,
+// and decrypts response error text, if response is encrypted.
+func (ms *metricsStorage) checkRPCResponse(resp *pb.MetricsResponse, header metadata.MD, key []byte,
+	sign signing.Signature,
+) error {
+	if key != nil {
+		data, err := proto.MarshalOptions{Deterministic: true}.Marshal(resp)
+		if err != nil {
+			return fmt.Errorf("responce convert error: %w", err)
+		}
+		hash := signing.Hash(key, data, sign.Timestamp, sign.Nonce)
+		if values := header.Get(hashVarName); len(values) == 0 || !hmac.Equal([]byte(values[0]), []byte(hash)) {
+			return errors.New("check responce hash summ error")
+		}
+	}
+	if len(resp.Data) == 0 {
+		return nil
+	}
+	if ms.ResponseKey == nil {
+		return errors.New("responce is encrypted, but response key is not set")
+	}
+	msg, err := decryptMessage(resp.Data, ms.ResponseKey)
+	if err != nil {
+		return err
+	}
+	resp.Error = string(msg)
+	return nil
+}
+
+// sendLast is private func. Sends the last batch, if counters were not sent, and returns send result.
+func (ms *metricsStorage) sendLast() error {
+	stats := ms.queueStats()
+	ms.mx.Lock()
+	send := false
+	for _, m := range ms.MetricsSlice {
+		if m.MType == counter && m.Delta != nil && *m.Delta != 0 {
+			send = true
+			break
+		}
+	}
+	if !send {
+		ms.mx.Unlock()
+		return nil
+	}
+	body, deltas, err := ms.takeBatch(stats)
+	ms.mx.Unlock()
+	if err != nil {
+		return err
+	}
+	_, err = ms.sendBatch(body, deltas)
+	return err
+}
+
 // Close checks if the last data were send to server. If not, sends data to server.
+// Retries of in-flight sends are interrupted, Close waits for them before the last send.
 func (ms *metricsStorage) Close() error {
-	close(ms.resiveChan)
+	close(ms.closeChan)
+	if ms.statsdConn != nil {
+		if err := ms.statsdConn.Close(); err != nil {
+			ms.Logger.Warnf("statsd listener close error: %v", err)
+		}
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(closeTimeout)*time.Second)
 	defer cancel()
-	ms.resiveChan = make(chan resiveStruct, 1)
-	closeResive := true
-	for _, m := range ms.MetricsSlice {
-		if m.MType == counter && m.ID == pCount && m.Delta != nil && *m.Delta > 0 {
-			ms.SendMetricsSlice()
-			closeResive = false
+	if ms.pushServer != nil {
+		if err := ms.pushServer.Shutdown(ctx); err != nil {
+			ms.Logger.Warnf("push API server shutdown error: %v", err)
 		}
 	}
-	if closeResive {
-		close(ms.resiveChan)
-	}
+	// Results of in-flight sends are read before the last batch is taken,
+	// so restored deltas are sent with the last batch.
+	done := make(chan struct{})
+	go func() {
+		ms.sends.Wait()
+		close(done)
+	}()
+	var err error
 	select {
-	case r := <-ms.resiveChan:
-		return r.Err
+	case <-done:
+		close(ms.resiveChan)
+		<-ms.resiveDone
+		err = ms.sendLast()
 	case <-ctx.Done():
-		return errors.New("close timeout error")
+		err = errors.New("close timeout error")
 	}
+	if ms.Sink != nil {
+		if serr := ms.Sink.Close(); serr != nil {
+			err = errors.Join(err, serr)
+		}
+	}
+	return err
 }
 
 // encryption message.
@@ -356,3 +621,21 @@ func encryptMessage(msg []byte, key *rsa.PublicKey) ([]byte, error) {
 	}
 	return encripted, nil
 }
+
+// decryptMessage is private func. Decrypts message, which was encrypted by RSA-OAEP blocks.
+func decryptMessage(msg []byte, key *rsa.PrivateKey) ([]byte, error) {
+	size := key.PublicKey.Size()
+	if len(msg)%size != 0 {
+		return nil, errors.New("encrypted message length error")
+	}
+	hash := sha256.New()
+	decrypted := make([]byte, 0)
+	for i := 0; i < len(msg); i += size {
+		data, err := rsa.DecryptOAEP(hash, nil, key, msg[i:i+size], []byte(""))
+		if err != nil {
+			return nil, fmt.Errorf("message decrypt error: %w", err)
+		}
+		decrypted = append(decrypted, data...)
+	}
+	return decrypted, nil
+}
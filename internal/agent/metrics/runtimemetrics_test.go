@@ -1,10 +1,8 @@
 package metrics
 
 import (
-	"fmt"
 	"reflect"
 	"runtime"
-	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -98,12 +96,19 @@ func Test_metricsStorage_addMetric(t *testing.T) {
 func Test_metricsStorage_UpdateMetrics(t *testing.T) {
 	ms := NewMemoryStorage(nil, &zap.Logger{}, "", []byte(""), 0, false, 1, nil, false)
 	ms.UpdateMetrics()
-	pollCount := ms.MetricsSlice["PollCount"].Delta
 	ms.UpdateMetrics()
 	t.Run("pollCountChange", func(t *testing.T) {
-		if pollCount == ms.MetricsSlice["PollCount"].Delta {
-			t.Error("UpdateMetrics pollCount change error")
-		}
+		assert.Equal(t, int64(2), *ms.MetricsSlice[pCount].Delta, "PollCount delta")
+		assert.Equal(t, float64(2), *ms.MetricsSlice[pCount+"Gauge"].Value, "PollCountGauge value")
+	})
+	t.Run("runtimeCountersDelta", func(t *testing.T) {
+		total := *ms.MetricsSlice["MallocsGauge"].Value
+		delta := *ms.MetricsSlice["Mallocs"].Delta
+		ms.takeDeltas()
+		ms.UpdateMetrics()
+		assert.Equal(t, total+float64(*ms.MetricsSlice["Mallocs"].Delta), *ms.MetricsSlice["MallocsGauge"].Value,
+			"counter must contain delta since the previous poll")
+		assert.LessOrEqual(t, float64(delta), total, "delta must not be greater then total")
 	})
 	t.Run("additionalMetricsChange", func(t *testing.T) {
 		ms.UpdateAditionalMetrics()
@@ -116,33 +121,23 @@ func Test_metricsStorage_UpdateMetrics(t *testing.T) {
 func Test_makeMap(t *testing.T) {
 	var r runtime.MemStats
 	runtime.ReadMemStats(&r)
-	var p int64 = 10
-	mName := "PollCount"
+	m := makeMap(&r)
 	tests := []struct {
-		name      string
-		pollCount *int64
-		want      int64
+		name    string
+		counter bool
 	}{
-		{
-			name:      "Make map with nil pollCount",
-			pollCount: nil,
-			want:      1,
-		},
-		{
-			name:      "Make map with pollCount = 10",
-			pollCount: &p,
-			want:      11,
-		},
+		{name: "Mallocs", counter: true},
+		{name: "TotalAlloc", counter: true},
+		{name: "NumGC", counter: true},
+		{name: "HeapAlloc", counter: false},
+		{name: "NumForcedGC", counter: true},
+		{name: "Sys", counter: false},
 	}
 	for _, tt := range tests {
 		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
-			m := makeMap(&r, tt.pollCount)
-			got, err := strconv.ParseInt(fmt.Sprint(m[mName]), 10, 64)
-			assert.NoError(t, err, "value convert error")
-			if got != tt.want {
-				t.Errorf("makeMap() = %v, want %v", got, tt.want)
-			}
+			_, ok := m[tt.name].(uint64)
+			assert.Equal(t, tt.counter, ok, "metric type error")
 		})
 	}
 }
@@ -0,0 +1,192 @@
+package metrics
+
+import (
+	"math"
+	"math/rand"
+	rm "runtime/metrics"
+	"strings"
+)
+
+// Summary suffixes for runtime/metrics histograms.
+const (
+	summaryCount  = ".count"
+	summaryP50    = ".p50"
+	summaryP90    = ".p90"
+	summaryP99    = ".p99"
+	summaryMax    = ".max"
+	runtimePrefix = "runtime."
+)
+
+// runtimeCompat contains runtime.MemStats names with runtime/metrics keys,
+// which values are summed to get the same value.
+// Metric is skipped if one of keys is not supported by the Go version.
+// PauseTotalNs is approximated by GC pauses histogram, LastGC has no analog and is not sent.
+// Lookups is always 0 as in runtime.MemStats.
+var runtimeCompat = map[string][]string{
+	"Alloc":        {"/memory/classes/heap/objects:bytes"},
+	"BuckHashSys":  {"/memory/classes/profiling/buckets:bytes"},
+	"GCSys":        {"/memory/classes/metadata/other:bytes"},
+	"HeapAlloc":    {"/memory/classes/heap/objects:bytes"},
+	"HeapIdle":     {"/memory/classes/heap/released:bytes", "/memory/classes/heap/free:bytes"},
+	"HeapInuse":    {"/memory/classes/heap/objects:bytes", "/memory/classes/heap/unused:bytes"},
+	"HeapObjects":  {"/gc/heap/objects:objects"},
+	"HeapReleased": {"/memory/classes/heap/released:bytes"},
+	"HeapSys": {
+		"/memory/classes/heap/objects:bytes", "/memory/classes/heap/unused:bytes",
+		"/memory/classes/heap/released:bytes", "/memory/classes/heap/free:bytes",
+	},
+	"MCacheInuse": {"/memory/classes/metadata/mcache/inuse:bytes"},
+	"MCacheSys":   {"/memory/classes/metadata/mcache/inuse:bytes", "/memory/classes/metadata/mcache/free:bytes"},
+	"MSpanInuse":  {"/memory/classes/metadata/mspan/inuse:bytes"},
+	"MSpanSys":    {"/memory/classes/metadata/mspan/inuse:bytes", "/memory/classes/metadata/mspan/free:bytes"},
+	"NextGC":      {"/gc/heap/goal:bytes"},
+	"OtherSys":    {"/memory/classes/other:bytes"},
+	"StackInuse":  {"/memory/classes/heap/stacks:bytes"},
+	"StackSys":    {"/memory/classes/heap/stacks:bytes", "/memory/classes/os-stacks:bytes"},
+	"Sys":         {"/memory/classes/total:bytes"},
+	"Frees":       {"/gc/heap/frees:objects"},
+	"Mallocs":     {"/gc/heap/allocs:objects"},
+	"NumForcedGC": {"/gc/cycles/forced:gc-cycles"},
+	"NumGC":       {"/gc/cycles/total:gc-cycles"},
+	"TotalAlloc":  {"/gc/heap/allocs:bytes"},
+}
+
+// runtimeStats reads runtime/metrics without stopping the world.
+type runtimeStats struct {
+	samples    []rm.Sample
+	index      map[string]int
+	cumulative map[string]bool
+	lastPauses []uint64 // GC pauses histogram counts at the previous poll
+}
+
+// newRuntimeStats is private func. Creates reader for all supported runtime/metrics.
+func newRuntimeStats() *runtimeStats {
+	descs := rm.All()
+	rs := runtimeStats{
+		samples:    make([]rm.Sample, len(descs)),
+		index:      make(map[string]int, len(descs)),
+		cumulative: make(map[string]bool, len(descs)),
+	}
+	for i, d := range descs {
+		rs.samples[i].Name = d.Name
+		rs.index[d.Name] = i
+		rs.cumulative[d.Name] = d.Cumulative
+	}
+	return &rs
+}
+
+// runtimeName is private func. Converts runtime/metrics key like '/gc/heap/allocs:bytes'
+// to metric name like 'runtime.gc.heap.allocs.bytes'.
+func runtimeName(key string) string {
+	name := strings.NewReplacer("/", ".", ":", ".", "-", "_").Replace(strings.TrimPrefix(key, "/"))
+	return runtimePrefix + name
+}
+
+// makeMap is private func. Reads runtime/metrics and returns the same map as makeMap for runtime.MemStats
+// with all supported runtime metrics. Cumulative values are uint64 totals, other values are float64 gauges.
+// Histograms are converted to summaries: count, p50, p90, p99 and max.
+func (rs *runtimeStats) makeMap() map[string]any {
+	rm.Read(rs.samples)
+	mass := make(map[string]any)
+	for _, s := range rs.samples {
+		name := runtimeName(s.Name)
+		switch s.Value.Kind() {
+		case rm.KindUint64:
+			if rs.cumulative[s.Name] {
+				mass[name] = s.Value.Uint64()
+			} else {
+				mass[name] = float64(s.Value.Uint64())
+			}
+		case rm.KindFloat64:
+			mass[name] = s.Value.Float64()
+		case rm.KindFloat64Histogram:
+			addSummary(mass, name, s.Value.Float64Histogram())
+		case rm.KindBad:
+		}
+	}
+	for name, keys := range runtimeCompat {
+		if value, ok := rs.sum(keys); ok {
+			if rs.cumulativeAll(keys) {
+				mass[name] = value
+			} else {
+				mass[name] = float64(value)
+			}
+		}
+	}
+	if value, ok := rs.pauseTotalNs(); ok {
+		mass["PauseTotalNs"] = value
+	}
+	mass["Lookups"] = uint64(0)
+	mass["GCCPUFraction"] = rs.ratio("/cpu/classes/gc/total:cpu-seconds", "/cpu/classes/total:cpu-seconds")
+	mass["RandomValue"] = rand.Float64() //nolint:gosec //<-random metric
+	return mass
+}
+
+// sum is private func. Returns sum of uint64 samples by keys.
+func (rs *runtimeStats) sum(keys []string) (uint64, bool) {
+	var total uint64
+	for _, key := range keys {
+		i, ok := rs.index[key]
+		if !ok || rs.samples[i].Value.Kind() != rm.KindUint64 {
+			return 0, false
+		}
+		total += rs.samples[i].Value.Uint64()
+	}
+	return total, true
+}
+
+// cumulativeAll is private func. Checks if all keys are cumulative.
+func (rs *runtimeStats) cumulativeAll(keys []string) bool {
+	for _, key := range keys {
+		if !rs.cumulative[key] {
+			return false
+		}
+	}
+	return true
+}
+
+// ratio is private func. Returns ratio of two float64 samples or 0 if they are not supported.
+func (rs *runtimeStats) ratio(part, total string) float64 {
+	i, ok := rs.index[part]
+	j, okTotal := rs.index[total]
+	if !ok || !okTotal || rs.samples[i].Value.Kind() != rm.KindFloat64 || rs.samples[j].Value.Kind() != rm.KindFloat64 {
+		return 0
+	}
+	if t := rs.samples[j].Value.Float64(); t > 0 {
+		return rs.samples[i].Value.Float64() / t
+	}
+	return 0
+}
+
+// addSummary is private func. Adds histogram as summary values to mass.
+func addSummary(mass map[string]any, name string, h *rm.Float64Histogram) {
+	var count uint64
+	for _, c := range h.Counts {
+		count += c
+	}
+	mass[name+summaryCount] = count
+	mass[name+summaryP50] = quantile(h, count, 0.5)  //nolint:gomnd //<-quantile
+	mass[name+summaryP90] = quantile(h, count, 0.9)  //nolint:gomnd //<-quantile
+	mass[name+summaryP99] = quantile(h, count, 0.99) //nolint:gomnd //<-quantile
+	mass[name+summaryMax] = quantile(h, count, 1)
+}
+
+// quantile is private func. Returns upper bound of the bucket which contains quantile q.
+// Infinite bounds are replaced by the nearest finite bound.
+func quantile(h *rm.Float64Histogram, count uint64, q float64) float64 {
+	if count == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(q * float64(count)))
+	var seen uint64
+	for i, c := range h.Counts {
+		seen += c
+		if seen >= rank && c > 0 {
+			if bound := h.Buckets[i+1]; !math.IsInf(bound, 0) {
+				return bound
+			}
+			return h.Buckets[i]
+		}
+	}
+	return 0
+}
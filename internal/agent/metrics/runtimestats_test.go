@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"math"
+	"runtime"
+	rm "runtime/metrics"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func Test_runtimeName(t *testing.T) {
+	assert.Equal(t, "runtime.gc.heap.allocs.bytes", runtimeName("/gc/heap/allocs:bytes"), "name convert error")
+	assert.Equal(t, "runtime.gc.cycles.total.gc_cycles", runtimeName("/gc/cycles/total:gc-cycles"), "name convert error")
+}
+
+func Test_quantile(t *testing.T) {
+	h := rm.Float64Histogram{
+		Counts:  []uint64{5, 4, 1},
+		Buckets: []float64{0, 1, 2, math.Inf(1)},
+	}
+	tests := []struct {
+		name string
+		q    float64
+		want float64
+	}{
+		{name: "p50", q: 0.5, want: 1},
+		{name: "p90", q: 0.9, want: 2},
+		{name: "max", q: 1, want: 2},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, quantile(&h, 10, tt.q), "quantile error")
+		})
+	}
+	assert.Equal(t, float64(0), quantile(&h, 0, 0.5), "empty histogram")
+}
+
+func Test_runtimeStats_makeMap(t *testing.T) {
+	m := newRuntimeStats().makeMap()
+	tests := []struct {
+		name    string
+		counter bool
+	}{
+		{name: "Alloc", counter: false},
+		{name: "HeapInuse", counter: false},
+		{name: "Sys", counter: false},
+		{name: "Mallocs", counter: true},
+		{name: "NumGC", counter: true},
+		{name: "TotalAlloc", counter: true},
+		{name: "runtime.gc.pauses.seconds" + summaryCount, counter: true},
+		{name: "runtime.sched.latencies.seconds" + summaryP99, counter: false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			value, ok := m[tt.name]
+			assert.True(t, ok, "metric not found")
+			_, isCounter := value.(uint64)
+			assert.Equal(t, tt.counter, isCounter, "metric type error")
+		})
+	}
+}
+
+func Test_metricsStorage_UpdateMetrics_runtimeMetrics(t *testing.T) {
+	ms := NewMemoryStorage(nil, zap.NewNop(), "", nil, 0, false, 1, nil, false)
+	ms.RuntimeMetrics = true
+	ms.UpdateMetrics()
+	ms.UpdateMetrics()
+	assert.Equal(t, int64(2), *ms.MetricsSlice[pCount].Delta, "PollCount delta")
+	assert.Equal(t, gauge, ms.MetricsSlice["HeapAlloc"].MType, "HeapAlloc type")
+	assert.Equal(t, counter, ms.MetricsSlice["Mallocs"].MType, "Mallocs type")
+	assert.NotNil(t, ms.MetricsSlice["MallocsGauge"].Value, "MallocsGauge value")
+}
+
+func Test_runtimeStats_pauseTotalNs(t *testing.T) {
+	runtime.GC()
+	rs := newRuntimeStats()
+	m := rs.makeMap()
+	value, ok := m["PauseTotalNs"].(uint64)
+	assert.True(t, ok, "PauseTotalNs must be counter")
+	assert.Greater(t, value, uint64(0), "GC pauses total")
+	_, ok = m["LastGC"]
+	assert.False(t, ok, "LastGC has no analog")
+}
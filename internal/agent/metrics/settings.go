@@ -0,0 +1,57 @@
+package metrics
+
+import "crypto/rsa"
+
+type (
+	// TargetKeys contains target's keys.
+	TargetKeys struct {
+		PublicKey *rsa.PublicKey // encription messages key
+		Key       []byte         // check hash key
+	}
+
+	// Settings contains storage options, which can be changed while agent is running.
+	Settings struct {
+		Retry     *RetryPolicy // policy for repeat failed sends
+		Filter    *Filter      // rules which are applied to metrics before sending
+		Keys      []TargetKeys // targets keys in the same order as Targets
+		RateLimit int          // max requests count, not changed if 0
+	}
+)
+
+// Apply changes storage options. Sends, which were started before, use previous options.
+func (ms *metricsStorage) Apply(s *Settings) {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	ms.Retry = s.Retry
+	ms.Filter = s.Filter
+	if s.RateLimit > 0 && s.RateLimit != cap(ms.requestChan) {
+		ms.requestChan = make(chan struct{}, s.RateLimit)
+	}
+	for i, t := range ms.Targets {
+		if i < len(s.Keys) {
+			t.setKeys(s.Keys[i])
+		}
+	}
+}
+
+// retry is private func. Returns current retry policy.
+func (ms *metricsStorage) retry() *RetryPolicy {
+	ms.mx.RLock()
+	defer ms.mx.RUnlock()
+	return ms.Retry
+}
+
+// setKeys is private func. Changes target's keys.
+func (t *Target) setKeys(keys TargetKeys) {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	t.Key = keys.Key
+	t.PublicKey = keys.PublicKey
+}
+
+// keys is private func. Returns target's keys.
+func (t *Target) keys() TargetKeys {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	return TargetKeys{Key: t.Key, PublicKey: t.PublicKey}
+}
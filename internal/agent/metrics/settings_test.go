@@ -0,0 +1,30 @@
+package metrics
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func Test_metricsStorage_Apply(t *testing.T) {
+	local := net.ParseIP("127.0.0.1")
+	ms := NewMemoryStorage(nil, zap.NewNop(), "", []byte("old"), 0, false, 1, &local, false)
+	ms.Targets = append(ms.Targets, NewTarget("host:8080", []byte("other"), nil, false))
+	requests := ms.requestChan
+	f, err := NewFilter(nil, []string{"*"}, nil, "", nil)
+	assert.NoError(t, err, "create filter error")
+	ms.Apply(&Settings{
+		Retry:     DefaultRetryPolicy(),
+		Filter:    f,
+		Keys:      []TargetKeys{{Key: []byte("new")}},
+		RateLimit: 3,
+	})
+	assert.Equal(t, []byte("new"), ms.Targets[0].keys().Key, "target key must be changed")
+	assert.Equal(t, []byte("other"), ms.Targets[1].keys().Key, "target without key must not be changed")
+	assert.Equal(t, 3, cap(ms.requestChan), "rate limit must be changed")
+	assert.Equal(t, 1, cap(requests), "previous requests chan must not be changed")
+	assert.Same(t, f, ms.Filter)
+	assert.NotNil(t, ms.retry())
+}
@@ -0,0 +1,179 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Output values.
+const (
+	OutputServer = "server" // send metrics to servers
+	OutputStdout = "stdout" // write metrics to stdout as JSON lines
+	OutputFile   = "file"   // write metrics to rotating file as JSON lines
+	sinkFileMode = 0600     // output file permissions
+	sinkDirMode  = 0700     // output directory permissions
+)
+
+type (
+	// Sink is used instead of servers for metrics batches output.
+	Sink interface {
+		Write(body []byte) error // writes JSON list of metrics
+		Close() error
+	}
+
+	// writerSink is private struct. Writes every metric as JSON line.
+	writerSink struct {
+		w  io.Writer  // output
+		mx sync.Mutex // mutex
+	}
+
+	// FileSink writes metrics as JSON lines to file, which is rotated by size.
+	FileSink struct {
+		file     *os.File   // current file
+		path     string     // current file path, rotated files have '.1', '.2' ... suffixes
+		maxSize  int64      // file max size in bytes, the file is not rotated if 0
+		maxFiles int        // count of rotated files which are kept
+		size     int64      // current file size
+		mx       sync.Mutex // mutex
+	}
+)
+
+// NewWriterSink creates sink, which writes metrics as JSON lines to w, for example to os.Stdout.
+func NewWriterSink(w io.Writer) Sink {
+	return &writerSink{w: w}
+}
+
+// jsonLines is private func. Converts JSON list of metrics to JSON lines.
+func jsonLines(body []byte) ([]byte, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(body, &items); err != nil {
+		return nil, fmt.Errorf("metrics list convert error: %w", err)
+	}
+	var b bytes.Buffer
+	for _, item := range items {
+		if err := json.Compact(&b, item); err != nil {
+			return nil, fmt.Errorf("metric convert error: %w", err)
+		}
+		b.WriteByte('\n')
+	}
+	return b.Bytes(), nil
+}
+
+// Write writes metrics list as JSON lines.
+func (s *writerSink) Write(body []byte) error {
+	data, err := jsonLines(body)
+	if err != nil {
+		return err
+	}
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if _, err = s.w.Write(data); err != nil {
+		return fmt.Errorf("metrics write error: %w", err)
+	}
+	return nil
+}
+
+// Close does nothing, the writer is not closed.
+func (s *writerSink) Close() error {
+	return nil
+}
+
+// NewFileSink creates or opens file for metrics output.
+//
+// Args:
+// path string - output file path
+// maxSize int64 - file max size in bytes, the file is rotated when it is exceeded
+// maxFiles int - count of rotated files which are kept.
+func NewFileSink(path string, maxSize int64, maxFiles int) (*FileSink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), sinkDirMode); err != nil {
+		return nil, fmt.Errorf("create output dir error: %w", err)
+	}
+	s := FileSink{path: path, maxSize: maxSize, maxFiles: maxFiles}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// open is private func. Opens output file for appending.
+func (s *FileSink) open() error {
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, sinkFileMode)
+	if err != nil {
+		return fmt.Errorf("open output file error: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return errors.Join(fmt.Errorf("output file stat error: %w", err), f.Close())
+	}
+	s.file, s.size = f, info.Size()
+	return nil
+}
+
+// rotate is private func. Renames current file to '.1', previous rotated files are shifted,
+// the oldest file is removed.
+func (s *FileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return fmt.Errorf("close output file error: %w", err)
+	}
+	if s.maxFiles < 1 {
+		if err := os.Remove(s.path); err != nil {
+			return fmt.Errorf("remove output file error: %w", err)
+		}
+		return s.open()
+	}
+	for i := s.maxFiles - 1; i > 0; i-- {
+		err := os.Rename(fmt.Sprintf("%s.%d", s.path, i), fmt.Sprintf("%s.%d", s.path, i+1))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("rotate output file error: %w", err)
+		}
+	}
+	if err := os.Rename(s.path, s.path+".1"); err != nil {
+		return fmt.Errorf("rotate output file error: %w", err)
+	}
+	return s.open()
+}
+
+// Write writes metrics list as JSON lines. The file is rotated before writing if max size is exceeded.
+func (s *FileSink) Write(body []byte) error {
+	data, err := jsonLines(body)
+	if err != nil {
+		return err
+	}
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.file == nil {
+		return errors.New("output file is closed")
+	}
+	if s.maxSize > 0 && s.size > 0 && s.size+int64(len(data)) > s.maxSize {
+		if err = s.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := s.file.Write(data)
+	s.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("output file write error: %w", err)
+	}
+	return nil
+}
+
+// Close closes output file.
+func (s *FileSink) Close() error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	if err != nil {
+		return fmt.Errorf("close output file error: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func Test_writerSink_Write(t *testing.T) {
+	var b bytes.Buffer
+	s := NewWriterSink(&b)
+	require.NoError(t, s.Write([]byte(`[{"id":"a","type":"gauge","value":1}, {"id":"b","type":"counter","delta":2}]`)))
+	assert.Equal(t, "{\"id\":\"a\",\"type\":\"gauge\",\"value\":1}\n{\"id\":\"b\",\"type\":\"counter\",\"delta\":2}\n", b.String())
+	assert.Error(t, s.Write([]byte("{")), "convert error expected")
+}
+
+func TestFileSink_rotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out", "metrics.jsonl")
+	body := []byte(`[{"id":"a","type":"gauge","value":1}]`)
+	s, err := NewFileSink(path, int64(len(body)+1), 2)
+	require.NoError(t, err)
+	for i := 0; i < 4; i++ {
+		require.NoError(t, s.Write(body))
+	}
+	require.NoError(t, s.Close())
+	assert.Error(t, s.Write(body), "closed file error expected")
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		data, err := os.ReadFile(name)
+		require.NoError(t, err)
+		assert.Equal(t, 1, strings.Count(string(data), "\n"), "file '%s' must contain one line", name)
+	}
+	_, err = os.Stat(path + ".3")
+	assert.ErrorIs(t, err, os.ErrNotExist, "the oldest file must be removed")
+}
+
+func Test_metricsStorage_Sink(t *testing.T) {
+	var b bytes.Buffer
+	local := net.ParseIP("127.0.0.1")
+	ms := NewMemoryStorage(nil, zap.NewNop(), "", nil, 0, false, 1, &local, false)
+	ms.Sink = NewWriterSink(&b)
+	ms.Filter, _ = NewFilter([]string{pCount}, nil, nil, "", nil) //nolint:errcheck //<-static rules
+	ms.UpdateMetrics()
+	require.NoError(t, ms.Close())
+	assert.Equal(t, "{\"delta\":1,\"id\":\"PollCount\",\"type\":\"counter\"}\n", b.String())
+}
+
+// blockingSink is test sink. The first Write waits for release and fails.
+type blockingSink struct {
+	release chan struct{}
+	bodies  []string
+}
+
+func (s *blockingSink) Write(body []byte) error {
+	if s.bodies == nil {
+		s.bodies = make([]string, 0)
+		<-s.release
+		return errors.New("first write error")
+	}
+	s.bodies = append(s.bodies, string(body))
+	return nil
+}
+
+func (s *blockingSink) Close() error {
+	return nil
+}
+
+func Test_metricsStorage_CloseInFlight(t *testing.T) {
+	local := net.ParseIP("127.0.0.1")
+	ms := NewMemoryStorage(nil, zap.NewNop(), "", nil, 0, false, 1, &local, false)
+	sink := &blockingSink{release: make(chan struct{})}
+	ms.Sink = sink
+	ms.Filter, _ = NewFilter([]string{pCount}, nil, nil, "", nil) //nolint:errcheck //<-static rules
+	ms.UpdateMetrics()
+	ms.SendMetricsSlice()
+	closed := make(chan error)
+	go func() {
+		closed <- ms.Close()
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(sink.release)
+	select {
+	case err := <-closed:
+		require.NoError(t, err, "the last send result expected")
+	case <-time.After(5 * time.Second):
+		t.Fatal("close must not wait for timeout")
+	}
+	assert.Equal(t, []string{"[{\"delta\":1,\"id\":\"PollCount\",\"type\":\"counter\"}]"}, sink.bodies,
+		"not sent deltas must be sent on close")
+}
@@ -0,0 +1,172 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// StatsD values.
+const (
+	statsdCounter    = "c"      // StatsD counter type
+	statsdGauge      = "g"      // StatsD gauge type
+	statsdTimer      = "ms"     // StatsD timer type
+	statsdBufferSize = 65535    // max UDP packet size
+	timerCount       = ".count" // suffix for timer's count metric
+	timerSum         = ".sum"   // suffix for timer's sum metric
+	timerMin         = ".min"   // suffix for timer's min metric
+	timerMax         = ".max"   // suffix for timer's max metric
+	timerMean        = ".mean"  // suffix for timer's mean metric
+)
+
+type (
+	// StatsdMetric is one parsed StatsD line.
+	statsdMetric struct {
+		Name     string  // metric name
+		MType    string  // StatsD type: c, g or ms
+		Value    float64 // metric value
+		Rate     float64 // sample rate
+		Relative bool    // gauge value has sign and changes current value
+	}
+
+	// TimerStat contains timer values collected between report ticks.
+	timerStat struct {
+		Count float64
+		Sum   float64
+		Min   float64
+		Max   float64
+	}
+)
+
+// parseStatsDLine is private func for parse one line like 'name:value|type|@rate'.
+func parseStatsDLine(line string) (*statsdMetric, error) {
+	name, data, ok := strings.Cut(line, ":")
+	if !ok || name == "" {
+		return nil, fmt.Errorf("statsd line '%s' name undefined", line)
+	}
+	parts := strings.Split(data, "|")
+	if len(parts) < 2 { //nolint:gomnd //<-value and type
+		return nil, fmt.Errorf("statsd line '%s' type undefined", line)
+	}
+	m := statsdMetric{Name: name, MType: parts[1], Rate: 1}
+	switch m.MType {
+	case statsdCounter, statsdGauge, statsdTimer:
+	default:
+		return nil, fmt.Errorf("statsd metric '%s' type '%s' unsupported", name, m.MType)
+	}
+	val, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return nil, fmt.Errorf("statsd metric '%s' value convert error: %w", name, err)
+	}
+	m.Value = val
+	m.Relative = m.MType == statsdGauge && (strings.HasPrefix(parts[0], "+") || strings.HasPrefix(parts[0], "-"))
+	for _, p := range parts[2:] {
+		if !strings.HasPrefix(p, "@") {
+			continue
+		}
+		rate, err := strconv.ParseFloat(p[1:], 64)
+		if err != nil {
+			return nil, fmt.Errorf("statsd metric '%s' sample rate convert error: %w", name, err)
+		}
+		if rate <= 0 || rate > 1 {
+			return nil, fmt.Errorf("statsd metric '%s' sample rate must be in (0, 1]", name)
+		}
+		m.Rate = rate
+	}
+	return &m, nil
+}
+
+// parseStatsDPacket is private func for parse UDP packet with lines splitted by '\n'.
+func parseStatsDPacket(packet []byte) ([]*statsdMetric, []error) {
+	items := make([]*statsdMetric, 0)
+	errs := make([]error, 0)
+	for _, line := range strings.Split(string(packet), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		m, err := parseStatsDLine(line)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		items = append(items, m)
+	}
+	return items, errs
+}
+
+// addStatsDMetric is private func and aggregates one StatsD metric in MetricsSlice.
+// Must be called under ms.mx lock.
+func (ms *metricsStorage) addStatsDMetric(m *statsdMetric) {
+	switch m.MType {
+	case statsdCounter:
+		// Sampled counter is rounded, truncation gives too low values for rates like 0.3.
+		ms.addCounter(m.Name, int64(math.Round(m.Value/m.Rate)))
+	case statsdGauge:
+		value := m.Value
+		if item, ok := ms.MetricsSlice[m.Name]; ok && m.Relative && item.Value != nil {
+			value += *item.Value
+		}
+		ms.MetricsSlice[m.Name] = metrics{ID: m.Name, MType: gauge, Value: &value}
+	case statsdTimer:
+		t, ok := ms.statsdTimers[m.Name]
+		if !ok {
+			t = &timerStat{Min: m.Value, Max: m.Value}
+			ms.statsdTimers[m.Name] = t
+		}
+		t.Count += 1 / m.Rate
+		t.Sum += m.Value / m.Rate
+		if m.Value < t.Min {
+			t.Min = m.Value
+		}
+		if m.Value > t.Max {
+			t.Max = m.Value
+		}
+		mean := t.Sum / t.Count
+		for suffix, value := range map[string]float64{
+			timerCount: t.Count, timerSum: t.Sum, timerMin: t.Min, timerMax: t.Max, timerMean: mean,
+		} {
+			value := value
+			ms.MetricsSlice[m.Name+suffix] = metrics{ID: m.Name + suffix, MType: gauge, Value: &value}
+		}
+	}
+}
+
+// ListenStatsD starts UDP listener for StatsD packets at address.
+// Received metrics are aggregated in MetricsSlice until next SendMetricsSlice call.
+// Listener finishes when Close is called.
+func (ms *metricsStorage) ListenStatsD(address string) error {
+	conn, err := net.ListenPacket("udp", address)
+	if err != nil {
+		return fmt.Errorf("statsd listen error: %w", err)
+	}
+	ms.mx.Lock()
+	ms.statsdConn = conn
+	ms.mx.Unlock()
+	ms.Logger.Infof("StatsD listener run at address: %s", conn.LocalAddr().String())
+	go func() {
+		buf := make([]byte, statsdBufferSize)
+		for {
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					ms.Logger.Warnf("statsd read error: %v", err)
+				}
+				return
+			}
+			items, errs := parseStatsDPacket(buf[:n])
+			for _, e := range errs {
+				ms.Logger.Debugf("statsd parse error: %v", e)
+			}
+			ms.mx.Lock()
+			for _, m := range items {
+				ms.addStatsDMetric(m)
+			}
+			ms.mx.Unlock()
+		}
+	}()
+	return nil
+}
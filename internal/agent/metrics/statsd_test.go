@@ -0,0 +1,130 @@
+package metrics
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func Test_parseStatsDLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    *statsdMetric
+		wantErr bool
+	}{
+		{
+			name: "Counter",
+			line: "requests:1|c",
+			want: &statsdMetric{Name: "requests", MType: statsdCounter, Value: 1, Rate: 1},
+		},
+		{
+			name: "Counter with sample rate",
+			line: "requests:2|c|@0.5",
+			want: &statsdMetric{Name: "requests", MType: statsdCounter, Value: 2, Rate: 0.5},
+		},
+		{
+			name: "Gauge",
+			line: "temperature:36.6|g",
+			want: &statsdMetric{Name: "temperature", MType: statsdGauge, Value: 36.6, Rate: 1},
+		},
+		{
+			name: "Relative gauge",
+			line: "temperature:-1|g",
+			want: &statsdMetric{Name: "temperature", MType: statsdGauge, Value: -1, Rate: 1, Relative: true},
+		},
+		{
+			name: "Timer with tags",
+			line: "response:320|ms|@0.1|#host:local",
+			want: &statsdMetric{Name: "response", MType: statsdTimer, Value: 320, Rate: 0.1},
+		},
+		{
+			name:    "Type error",
+			line:    "users:1|s",
+			wantErr: true,
+		},
+		{
+			name:    "Value error",
+			line:    "users:one|c",
+			wantErr: true,
+		},
+		{
+			name:    "Rate error",
+			line:    "users:1|c|@2",
+			wantErr: true,
+		},
+		{
+			name:    "Format error",
+			line:    "users",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseStatsDLine(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseStatsDLine() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseStatsDLine() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_metricsStorage_addStatsDMetric(t *testing.T) {
+	ms := NewMemoryStorage(nil, zap.NewNop(), "", nil, 0, false, 1, nil, false)
+	items, errs := parseStatsDPacket([]byte("hits:1|c\nhits:1|c|@0.5\nload:10|g\nload:+5|g\nlat:10|ms\nlat:30|ms\nbad"))
+	assert.Equal(t, 1, len(errs), "parse errors count")
+	for _, m := range items {
+		ms.addStatsDMetric(m)
+	}
+	assert.Equal(t, int64(3), *ms.MetricsSlice["hits"].Delta, "counter value")
+	assert.Equal(t, float64(15), *ms.MetricsSlice["load"].Value, "gauge value")
+	assert.Equal(t, float64(2), *ms.MetricsSlice["lat"+timerCount].Value, "timer count")
+	assert.Equal(t, float64(10), *ms.MetricsSlice["lat"+timerMin].Value, "timer min")
+	assert.Equal(t, float64(30), *ms.MetricsSlice["lat"+timerMax].Value, "timer max")
+	assert.Equal(t, float64(20), *ms.MetricsSlice["lat"+timerMean].Value, "timer mean")
+	t.Run("Reset after send", func(t *testing.T) {
+		ms.takeDeltas()
+		ms.resetTimers()
+		_, ok := ms.MetricsSlice["hits"]
+		assert.False(t, ok, "counter must be reset")
+		_, ok = ms.MetricsSlice["lat"+timerMean]
+		assert.False(t, ok, "timer must be reset")
+		_, ok = ms.MetricsSlice["load"]
+		assert.True(t, ok, "gauge must be kept")
+	})
+	t.Run("Non-integer sample ratio", func(t *testing.T) {
+		items, errs := parseStatsDPacket([]byte("sampled:1|c|@0.3\nsampled:2|c|@0.3"))
+		assert.Empty(t, errs, "parse errors")
+		for _, m := range items {
+			ms.addStatsDMetric(m)
+		}
+		assert.Equal(t, int64(10), *ms.MetricsSlice["sampled"].Delta, "rounded counter value")
+	})
+}
+
+func Test_metricsStorage_ListenStatsD(t *testing.T) {
+	ms := NewMemoryStorage(nil, zap.NewNop(), "", nil, 0, false, 1, nil, false)
+	err := ms.ListenStatsD("127.0.0.1:0")
+	assert.NoError(t, err, "listen error")
+	defer ms.statsdConn.Close() //nolint:errcheck //<-senselessly
+	conn, err := net.Dial("udp", ms.statsdConn.LocalAddr().String())
+	assert.NoError(t, err, "dial error")
+	defer conn.Close() //nolint:errcheck //<-senselessly
+	_, err = conn.Write([]byte("udp.hits:5|c"))
+	assert.NoError(t, err, "write error")
+	assert.Eventually(t, func() bool {
+		ms.mx.RLock()
+		defer ms.mx.RUnlock()
+		m, ok := ms.MetricsSlice["udp.hits"]
+		return ok && *m.Delta == 5
+	}, time.Second, 10*time.Millisecond)
+}
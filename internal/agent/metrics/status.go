@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"sort"
+	"time"
+)
+
+type (
+	// TargetStatus contains the last send result to target.
+	TargetStatus struct {
+		LastSend  time.Time `json:"last_send,omitempty"`  // the last send time
+		URL       string    `json:"url"`                  // target URL
+		LastError string    `json:"last_error,omitempty"` // the last send error, empty if success
+		Failures  int       `json:"failures"`             // count of failed sends in a row
+		Healthy   bool      `json:"healthy"`              // target is used in failover mode
+	}
+
+	// Status contains storage state for agent's status endpoint.
+	Status struct {
+		Metrics        []metrics      `json:"metrics"`         // metrics which were not sent yet
+		Targets        []TargetStatus `json:"targets"`         // targets send results
+		InFlight       int            `json:"in_flight"`       // count of requests which are sending now
+		RateLimit      int            `json:"rate_limit"`      // max requests count
+		DroppedBatches int64          `json:"dropped_batches"` // batches which were dropped because rate limit was reached
+		QueueLength    int            `json:"queue_length"`    // batches in outbound queue
+		QueueBytes     int64          `json:"queue_bytes"`     // outbound queue size
+		QueueDropped   int64          `json:"queue_dropped"`   // batches which were removed from queue by limits
+	}
+)
+
+// status is private func. Returns target's state.
+func (t *Target) status() TargetStatus {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	s := TargetStatus{
+		URL:      t.URL,
+		LastSend: t.lastSend,
+		Failures: t.failures,
+		Healthy:  time.Now().After(t.downUntil),
+	}
+	if t.lastErr != nil {
+		s.LastError = t.lastErr.Error()
+	}
+	return s
+}
+
+// Status returns current storage state. Metrics are sorted by name.
+func (ms *metricsStorage) Status() *Status {
+	ms.mx.RLock()
+	defer ms.mx.RUnlock()
+	s := Status{
+		Metrics:        make([]metrics, 0, len(ms.MetricsSlice)),
+		Targets:        make([]TargetStatus, 0, len(ms.Targets)),
+		InFlight:       len(ms.requestChan),
+		RateLimit:      cap(ms.requestChan),
+		DroppedBatches: ms.droppedBatches,
+	}
+	for _, m := range ms.MetricsSlice {
+		s.Metrics = append(s.Metrics, m)
+	}
+	sort.Slice(s.Metrics, func(i, j int) bool {
+		return s.Metrics[i].ID < s.Metrics[j].ID
+	})
+	for _, t := range ms.Targets {
+		s.Targets = append(s.Targets, t.status())
+	}
+	for _, q := range ms.queues() {
+		count, size, dropped := q.Stats()
+		s.QueueLength += count
+		s.QueueBytes += size
+		s.QueueDropped += dropped
+	}
+	return &s
+}
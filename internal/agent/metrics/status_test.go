@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func Test_metricsStorage_Status(t *testing.T) {
+	local := net.ParseIP("127.0.0.1")
+	ms := NewMemoryStorage(nil, zap.NewNop(), "localhost", nil, 8080, false, 2, &local, false)
+	ms.mx.Lock()
+	ms.addCounter("b", 2)
+	ms.MetricsSlice["a"] = metrics{ID: "a", MType: gauge}
+	ms.droppedBatches = 3
+	ms.mx.Unlock()
+	ms.Targets[0].setHealth(errors.New("connection refused"))
+
+	s := ms.Status()
+	assert.Equal(t, []string{"a", "b"}, []string{s.Metrics[0].ID, s.Metrics[1].ID}, "metrics must be sorted")
+	assert.Equal(t, 2, s.RateLimit, "rate limit error")
+	assert.Equal(t, 0, s.InFlight, "in-flight requests error")
+	assert.Equal(t, int64(3), s.DroppedBatches, "dropped batches error")
+	if assert.Equal(t, 1, len(s.Targets), "targets count error") {
+		assert.Equal(t, "connection refused", s.Targets[0].LastError, "last error")
+		assert.Equal(t, 1, s.Targets[0].Failures, "failures count")
+		assert.False(t, s.Targets[0].Healthy, "target must be unhealthy")
+		assert.False(t, s.Targets[0].LastSend.IsZero(), "last send time must be set")
+	}
+}
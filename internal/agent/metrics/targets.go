@@ -0,0 +1,231 @@
+package metrics
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// Targets values.
+const (
+	ModeFailover   = "failover"       // send batch to the first healthy target
+	ModeFanOut     = "fanout"         // send batch to all targets
+	targetCooldown = 10 * time.Second // failed target is skipped in failover mode during this time
+)
+
+// Target is one server for metrics sending.
+type Target struct {
+	PublicKey *rsa.PublicKey // encription messages key
+	Queue     *DiskQueue     // queue for not delivered batches, used in fan-out mode
+	downUntil time.Time      // target is skipped in failover mode until this time
+	URL       string         // URL for requests send to server
+	Key       []byte         // check hash key
+	mx        sync.Mutex     // mutex
+	failures  int            // count of failed sends in a row
+	lastSend  time.Time      // the last send time
+	lastErr   error          // the last send error
+	pending   []metrics      // counters and histograms, which were not accepted by target in fan-out mode
+	SendByRPC bool           // flag for send by gRPC instead of HTTP
+}
+
+// NewTarget creates target for server address like 'host:port'.
+//
+// Args:
+// address string - server address
+// key []byte - key for requests hash check
+// pk *rsa.PublicKey - public RSA key for messages encription
+// sendRPC bool - flag for send by gRPC instead of HTTP.
+func NewTarget(address string, key []byte, pk *rsa.PublicKey, sendRPC bool) *Target {
+	url := address
+	if !sendRPC {
+		url = fmt.Sprintf("http://%s/updates/", address)
+	}
+	return &Target{URL: url, Key: key, PublicKey: pk, SendByRPC: sendRPC}
+}
+
+// newTargetFromIP is private func. Creates target from ip and port.
+func newTargetFromIP(ip string, port int, key []byte, pk *rsa.PublicKey, sendRPC bool) *Target {
+	return NewTarget(net.JoinHostPort(ip, fmt.Sprint(port)), key, pk, sendRPC)
+}
+
+// healthy is private func. Checks if the target can be used in failover mode.
+func (t *Target) healthy() bool {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	return time.Now().After(t.downUntil)
+}
+
+// setHealth is private func. Updates target health by send result.
+func (t *Target) setHealth(err error) {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	t.lastSend, t.lastErr = time.Now(), err
+	if err == nil {
+		t.failures = 0
+		t.downUntil = time.Time{}
+		return
+	}
+	t.failures++
+	t.downUntil = time.Now().Add(targetCooldown)
+}
+
+// deltasOf is private func. Returns counters and histograms of batch body.
+func deltasOf(body []byte) ([]metrics, error) {
+	items := make([]metrics, 0)
+	if err := json.Unmarshal(body, &items); err != nil {
+		return nil, fmt.Errorf("batch convert error: %w", err)
+	}
+	deltas := make([]metrics, 0)
+	for _, item := range items {
+		if (item.MType == counter && item.Delta != nil) || (item.MType == histogramType && item.Histogram != nil) {
+			deltas = append(deltas, item)
+		}
+	}
+	return deltas, nil
+}
+
+// sumDeltas is private func. Adds counters and histograms of other to items by metrics ID.
+// Histogram with changed buckets is replaced.
+func sumDeltas(items, other []metrics) []metrics {
+	index := make(map[string]int, len(items))
+	for i, item := range items {
+		index[item.ID] = i
+	}
+	for _, item := range other {
+		i, ok := index[item.ID]
+		if !ok {
+			index[item.ID] = len(items)
+			items = append(items, item)
+			continue
+		}
+		if items[i].MType != item.MType {
+			continue
+		}
+		switch item.MType {
+		case counter:
+			delta := *items[i].Delta + *item.Delta
+			items[i].Delta = &delta
+		case histogramType:
+			h := newHistogram(items[i].Histogram.Buckets)
+			if h.merge(items[i].Histogram) == nil && h.merge(item.Histogram) == nil {
+				items[i].Histogram = h
+			}
+		}
+	}
+	return items
+}
+
+// withPending is private func. Takes target's pending deltas and adds them to batch body.
+// Returns body for the target and the batch deltas, which must be returned to target if sending fails.
+func (t *Target) withPending(body []byte) ([]byte, []metrics, error) {
+	deltas, err := deltasOf(body)
+	if err != nil {
+		return nil, nil, err
+	}
+	t.mx.Lock()
+	pending := t.pending
+	t.pending = nil
+	t.mx.Unlock()
+	if len(pending) == 0 {
+		return body, deltas, nil
+	}
+	items := make([]metrics, 0)
+	if err = json.Unmarshal(body, &items); err != nil {
+		t.addPending(pending)
+		return nil, nil, fmt.Errorf("batch convert error: %w", err)
+	}
+	if body, err = json.Marshal(sumDeltas(items, pending)); err != nil {
+		t.addPending(pending)
+		return nil, nil, fmt.Errorf("batch convert error: %w", err)
+	}
+	return body, sumDeltas(deltas, pending), nil
+}
+
+// addPending is private func. Saves deltas, which were not accepted by target, for the next send.
+func (t *Target) addPending(deltas []metrics) {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	t.pending = sumDeltas(t.pending, deltas)
+}
+
+// sendToTarget is private func. Sends body to target and updates target health.
+func (ms *metricsStorage) sendToTarget(ctx context.Context, t *Target, body []byte) error {
+	err := ms.sendBody(ctx, t, body)
+	t.setHealth(err)
+	if err != nil {
+		return fmt.Errorf("target '%s': %w", t.URL, err)
+	}
+	return nil
+}
+
+// sendFailover is private func. Sends body to the first target which accepts it.
+// Unhealthy targets are skipped while at least one target is healthy.
+func (ms *metricsStorage) sendFailover(ctx context.Context, body []byte) error {
+	targets := make([]*Target, 0, len(ms.Targets))
+	for _, t := range ms.Targets {
+		if t.healthy() {
+			targets = append(targets, t)
+		}
+	}
+	if len(targets) == 0 {
+		targets = ms.Targets
+	}
+	errs := make([]error, 0, len(targets))
+	for _, t := range targets {
+		err := ms.sendToTarget(ctx, t, body)
+		if err == nil {
+			return nil
+		}
+		ms.Logger.Debugf("failover send error: %v", err)
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// sendFanOut is private func. Sends body to all targets at the same time.
+// Each target uses its own queue for not delivered batches.
+// Counters and histograms, which were not accepted by target, are kept in the target
+// and sent with its next batch, so other targets don't receive them twice.
+func (ms *metricsStorage) sendFanOut(ctx context.Context, body []byte) error {
+	errs := make([]error, len(ms.Targets))
+	var wg sync.WaitGroup
+	for i, t := range ms.Targets {
+		wg.Add(1)
+		go func(i int, t *Target) {
+			defer wg.Done()
+			data, deltas, err := t.withPending(body)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			errs[i] = ms.deliver(t.Queue, data, func(b []byte) error {
+				return ms.sendToTarget(ctx, t, b)
+			})
+			if errs[i] != nil {
+				t.addPending(deltas)
+			}
+		}(i, t)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// queues is private func. Returns all used queues.
+func (ms *metricsStorage) queues() []*DiskQueue {
+	items := make([]*DiskQueue, 0)
+	if ms.FanOut {
+		for _, t := range ms.Targets {
+			if t.Queue != nil {
+				items = append(items, t.Queue)
+			}
+		}
+	} else if ms.Queue != nil {
+		items = append(items, ms.Queue)
+	}
+	return items
+}
@@ -0,0 +1,125 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// testServer is a fake server which counts received requests.
+type testServer struct {
+	srv      *httptest.Server
+	fail     atomic.Bool
+	received atomic.Int32
+	hits     atomic.Int64 // sum of received 'hits' counter deltas
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	ts := testServer{}
+	ts.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ts.fail.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		ts.received.Add(1)
+		items := make([]metrics, 0)
+		if err := json.NewDecoder(r.Body).Decode(&items); err != nil {
+			return
+		}
+		for _, item := range items {
+			if item.ID == "hits" && item.Delta != nil {
+				ts.hits.Add(*item.Delta)
+			}
+		}
+	}))
+	t.Cleanup(ts.srv.Close)
+	return &ts
+}
+
+func (ts *testServer) target() *Target {
+	return NewTarget(strings.TrimPrefix(ts.srv.URL, "http://"), nil, nil, false)
+}
+
+func Test_metricsStorage_sendFailover(t *testing.T) {
+	primary, secondary := newTestServer(t), newTestServer(t)
+	local := net.ParseIP("127.0.0.1")
+	ms := NewMemoryStorage(nil, zap.NewNop(), "", nil, 0, false, 1, &local, false)
+	ms.Targets = []*Target{primary.target(), secondary.target()}
+
+	primary.fail.Store(true)
+	assert.NoError(t, ms.sendFailover(context.Background(), []byte("[]")), "failover send error")
+	assert.Equal(t, int32(1), secondary.received.Load(), "secondary must receive batch")
+	assert.False(t, ms.Targets[0].healthy(), "failed primary must be unhealthy")
+
+	primary.fail.Store(false)
+	assert.NoError(t, ms.sendFailover(context.Background(), []byte("[]")), "failover send error")
+	assert.Equal(t, int32(0), primary.received.Load(), "unhealthy primary must be skipped")
+	assert.Equal(t, int32(2), secondary.received.Load(), "secondary must receive batch")
+
+	secondary.fail.Store(true)
+	assert.Error(t, ms.sendFailover(context.Background(), []byte("[]")), "all targets failed error expected")
+	assert.NoError(t, ms.sendFailover(context.Background(), []byte("[]")), "unhealthy targets must be used when all are down")
+	assert.Equal(t, int32(1), primary.received.Load(), "primary must receive batch")
+}
+
+func Test_metricsStorage_sendFanOut(t *testing.T) {
+	first, second := newTestServer(t), newTestServer(t)
+	local := net.ParseIP("127.0.0.1")
+	ms := NewMemoryStorage(nil, zap.NewNop(), "", nil, 0, false, 1, &local, false)
+	ms.Targets = []*Target{first.target(), second.target()}
+	ms.FanOut = true
+	var err error
+	for _, target := range ms.Targets {
+		target.Queue, err = NewDiskQueue(t.TempDir(), 0, 0)
+		assert.NoError(t, err, "create queue error")
+	}
+
+	second.fail.Store(true)
+	assert.NoError(t, ms.sendFanOut(context.Background(), []byte("[]")), "fan-out send error")
+	assert.Equal(t, int32(1), first.received.Load(), "first target must receive batch")
+	count, _, _ := ms.Targets[1].Queue.Stats()
+	assert.Equal(t, 1, count, "failed target's batch must be saved in its queue")
+	assert.Equal(t, 2, len(ms.queues()), "fan-out mode must use targets' queues")
+
+	second.fail.Store(false)
+	assert.NoError(t, ms.sendFanOut(context.Background(), []byte("[]")), "fan-out send error")
+	assert.Equal(t, int32(2), first.received.Load(), "first target must not receive replayed batch")
+	assert.Equal(t, int32(2), second.received.Load(), "second target must receive replayed and new batches")
+}
+
+func Test_metricsStorage_sendFanOut_deltas(t *testing.T) {
+	healthy, failed := newTestServer(t), newTestServer(t)
+	local := net.ParseIP("127.0.0.1")
+	ms := NewMemoryStorage(nil, zap.NewNop(), "", nil, 0, false, 1, &local, false)
+	ms.Targets = []*Target{healthy.target(), failed.target()}
+	ms.FanOut = true
+	send := func(delta int64) {
+		ms.mx.Lock()
+		ms.addCounter("hits", delta)
+		ms.mx.Unlock()
+		ms.SendMetricsSlice()
+		assert.Eventually(t, func() bool { return len(ms.requestChan) == 0 }, time.Second, 10*time.Millisecond)
+	}
+
+	failed.fail.Store(true)
+	send(5)
+	assert.Equal(t, int64(5), healthy.hits.Load(), "healthy target counter")
+	failed.fail.Store(false)
+	send(2)
+	assert.Equal(t, int64(7), healthy.hits.Load(), "healthy target must not receive deltas twice")
+	assert.Equal(t, int64(7), failed.hits.Load(), "failed target must receive not accepted deltas")
+	ms.mx.RLock()
+	_, ok := ms.MetricsSlice["hits"]
+	ms.mx.RUnlock()
+	assert.False(t, ok, "deltas must not be restored in storage")
+}
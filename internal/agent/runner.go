@@ -1,25 +1,35 @@
 package agent
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
 	"github.com/gostuding/go-metrics/internal/agent/metrics"
+	"github.com/gostuding/go-metrics/internal/logging"
 	"go.uber.org/zap"
 )
 
 // Struct for send data to server.
 type (
 	Agent struct {
-		cfg      *Config // configuration
-		logger   *zap.Logger
-		Storage  Storager // storage for agent.
-		stopChan chan os.Signal
-		mutex    sync.Mutex
-		isRun    bool
+		cfg          *Config // configuration
+		logger       *zap.Logger
+		Storage      Storager // storage for agent.
+		stopChan     chan os.Signal
+		hupChan      chan os.Signal // config reload signals
+		statusServer *http.Server   // local status endpoint server
+		mutex        sync.Mutex
+		cfgMutex     sync.RWMutex // config mutex, config is read by status endpoint
+		isRun        bool
 	}
 
 	// Storager interface for metrics collecting.
@@ -27,17 +37,79 @@ type (
 		UpdateMetrics()
 		UpdateAditionalMetrics()
 		SendMetricsSlice()
+		ListenStatsD(address string) error
+		ListenPush(address string) error
+		Apply(s *metrics.Settings)
+		Status() *metrics.Status
 		Close() error
 	}
 )
 
 // NewAgent creates new Agent object.
 func NewAgent(cfg *Config, logger *zap.Logger) *Agent {
-	s := metrics.NewMemoryStorage(cfg.PublicKey, logger, cfg.IP, []byte(cfg.HashKey),
+	s := metrics.NewMemoryStorage(cfg.PublicKey, logger, cfg.IP, cfg.targetKeys(TargetConfig{}).Key,
 		cfg.Port, cfg.GzipCompress, cfg.RateLimit, cfg.LocalAddress, cfg.SendByRPC)
+	if r, err := cfg.RetryPolicy(); err != nil {
+		logger.Sugar().Warnf("create retry policy error: %v", err)
+	} else {
+		s.Retry = r
+	}
+	if len(cfg.Targets) > 0 {
+		s.Targets = make([]*metrics.Target, 0, len(cfg.Targets))
+		for _, t := range cfg.Targets {
+			keys := cfg.targetKeys(t)
+			s.Targets = append(s.Targets, metrics.NewTarget(t.Address, keys.Key, keys.PublicKey, t.SendByRPC))
+		}
+	}
+	s.FanOut = cfg.TargetsMode == metrics.ModeFanOut
+	s.AgentID = cfg.AgentID
+	s.HashScheme = cfg.HashScheme
+	s.ResponseKey = cfg.PrivateKey
+	s.RuntimeMetrics = cfg.RuntimeMetrics
+	s.PauseBuckets = cfg.PauseBuckets
+	if f, err := cfg.Filter(); err != nil {
+		logger.Sugar().Warnf("create metrics filter error: %v", err)
+	} else {
+		s.Filter = f
+	}
+	switch cfg.Output {
+	case metrics.OutputStdout:
+		s.Sink = metrics.NewWriterSink(os.Stdout)
+	case metrics.OutputFile:
+		sink, err := metrics.NewFileSink(cfg.OutputPath, int64(cfg.OutputMaxSize), cfg.OutputMaxFiles)
+		if err != nil {
+			logger.Sugar().Warnf("create output file error: %v", err)
+		} else {
+			s.Sink = sink
+		}
+	}
+	if cfg.BufferPath != "" {
+		q, err := newQueues(cfg, s.Targets, s.FanOut)
+		if err != nil {
+			logger.Sugar().Warnf("create outbound queue error: %v", err)
+		}
+		s.Queue = q
+	}
 	return &Agent{Storage: s, logger: logger, cfg: cfg}
 }
 
+// newQueues is private func. Returns outbound queue in failover mode
+// or creates queue for each target in fan-out mode.
+func newQueues(cfg *Config, targets []*metrics.Target, fanOut bool) (*metrics.DiskQueue, error) {
+	maxAge := time.Duration(cfg.BufferMaxAge) * time.Second
+	if !fanOut {
+		return metrics.NewDiskQueue(cfg.BufferPath, int64(cfg.BufferMaxSize), maxAge) //nolint:wrapcheck //<-senselessly
+	}
+	for i, t := range targets {
+		q, err := metrics.NewDiskQueue(filepath.Join(cfg.BufferPath, strconv.Itoa(i)), int64(cfg.BufferMaxSize), maxAge)
+		if err != nil {
+			return nil, err //nolint:wrapcheck //<-senselessly
+		}
+		t.Queue = q
+	}
+	return nil, nil
+}
+
 // StartAgent starts gorutines for update and send metrics.
 func (a *Agent) StartAgent() {
 	a.mutex.Lock()
@@ -48,12 +120,30 @@ func (a *Agent) StartAgent() {
 	a.isRun = true
 	a.stopChan = make(chan os.Signal, 1)
 	signal.Notify(a.stopChan, os.Interrupt, syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT)
+	a.hupChan = make(chan os.Signal, 1)
+	signal.Notify(a.hupChan, syscall.SIGHUP)
 	a.mutex.Unlock()
 	a.logger.Debug("Start agent")
+	if a.cfg.StatsDAddress != "" {
+		if err := a.Storage.ListenStatsD(a.cfg.StatsDAddress); err != nil {
+			a.logger.Sugar().Warnf("start StatsD listener error: %v", err)
+		}
+	}
+	if a.cfg.PushAddress != "" {
+		if err := a.Storage.ListenPush(a.cfg.PushAddress); err != nil {
+			a.logger.Sugar().Warnf("start push API error: %v", err)
+		}
+	}
+	if a.cfg.StatusAddress != "" {
+		if err := a.listenStatus(a.cfg.StatusAddress); err != nil {
+			a.logger.Sugar().Warnf("start status endpoint error: %v", err)
+		}
+	}
 	pollTicker := time.NewTicker(time.Duration(a.cfg.PollInterval) * time.Second)
 	reportTicker := time.NewTicker(time.Duration(a.cfg.ReportInterval) * time.Second)
 	defer pollTicker.Stop()
 	defer reportTicker.Stop()
+	defer signal.Stop(a.hupChan)
 	for {
 		select {
 		case <-pollTicker.C:
@@ -61,6 +151,8 @@ func (a *Agent) StartAgent() {
 			go a.Storage.UpdateAditionalMetrics()
 		case <-reportTicker.C:
 			a.Storage.SendMetricsSlice()
+		case <-a.hupChan:
+			a.reload(pollTicker, reportTicker)
 		case <-a.stopChan:
 			a.StopAgent()
 			a.logger.Debug("Agent work finished")
@@ -69,6 +161,63 @@ func (a *Agent) StartAgent() {
 	}
 }
 
+// reload is private func. Reads config again and applies changes, which can be done without restart.
+// Other changes are logged and rejected.
+func (a *Agent) reload(pollTicker, reportTicker *time.Ticker) {
+	a.logger.Info("SIGHUP received, reload config")
+	n, err := a.cfg.Reload()
+	if err != nil {
+		a.logger.Sugar().Warnf("reload config rejected: %v", err)
+		return
+	}
+	if names := a.cfg.restartChanges(n); len(names) > 0 {
+		a.logger.Sugar().Warnf("config changes require restart and are rejected: %s", strings.Join(names, ", "))
+	}
+	cfg := a.cfg.merge(n)
+	s, err := cfg.settings()
+	if err != nil {
+		a.logger.Sugar().Warnf("reload config rejected: %v", err)
+		return
+	}
+	a.Storage.Apply(s)
+	if err = logging.SetLevel(cfg.LogLevel); err != nil {
+		a.logger.Sugar().Warnln(err)
+	}
+	if cfg.PollInterval != a.cfg.PollInterval {
+		pollTicker.Reset(time.Duration(cfg.PollInterval) * time.Second)
+	}
+	if cfg.ReportInterval != a.cfg.ReportInterval {
+		reportTicker.Reset(time.Duration(cfg.ReportInterval) * time.Second)
+	}
+	a.cfgMutex.Lock()
+	a.cfg = cfg
+	a.cfgMutex.Unlock()
+	a.logger.Info("config reloaded")
+}
+
+// RunOnce collects metrics one time, sends them and closes storage.
+// StatsD, push API and status listeners are not started.
+func (a *Agent) RunOnce() error {
+	a.mutex.Lock()
+	if a.isRun {
+		a.mutex.Unlock()
+		return errors.New("agent already run")
+	}
+	a.isRun = true
+	a.mutex.Unlock()
+	defer func() {
+		a.mutex.Lock()
+		a.isRun = false
+		a.mutex.Unlock()
+	}()
+	a.Storage.UpdateMetrics()
+	a.Storage.UpdateAditionalMetrics()
+	if err := a.Storage.Close(); err != nil {
+		return fmt.Errorf("send metrics error: %w", err)
+	}
+	return nil
+}
+
 // StopAgent finishing agent if it was start.
 func (a *Agent) StopAgent() {
 	a.logger.Debug("Stop agent")
@@ -77,6 +226,7 @@ func (a *Agent) StopAgent() {
 	if !a.isRun {
 		return
 	}
+	a.stopStatus()
 	if err := a.Storage.Close(); err != nil {
 		a.logger.Sugar().Warnf("close storage error: %v", err)
 	}
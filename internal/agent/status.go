@@ -0,0 +1,86 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/gostuding/go-metrics/internal/agent/metrics"
+	"github.com/gostuding/go-metrics/internal/config"
+)
+
+// statusShutdownTimeout is status server shutdown timeout.
+const statusShutdownTimeout = 5 * time.Second
+
+// statusResponse is status endpoint answer.
+type statusResponse struct {
+	*metrics.Status
+	Config any `json:"config"` // effective config, secrets are redacted
+}
+
+// statusRouter is private func. Creates handlers for status endpoint.
+func (a *Agent) statusRouter() http.Handler {
+	router := chi.NewRouter()
+	router.Get("/status", func(w http.ResponseWriter, r *http.Request) {
+		data, err := json.MarshalIndent(statusResponse{
+			Status: a.Storage.Status(),
+			Config: config.Redact(a.config()),
+		}, "", "  ")
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			a.logger.Sugar().Warnf("status marshal error: %v", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err = w.Write(data); err != nil {
+			a.logger.Sugar().Warnf("status write response error: %v", err)
+		}
+	})
+	return router
+}
+
+// listenStatus is private func. Starts local HTTP server for agent's status.
+// Server finishes when StopAgent is called.
+func (a *Agent) listenStatus(address string) error {
+	listen, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("status endpoint listen error: %w", err)
+	}
+	srv := &http.Server{Handler: a.statusRouter(), ReadHeaderTimeout: statusShutdownTimeout}
+	a.mutex.Lock()
+	a.statusServer = srv
+	a.mutex.Unlock()
+	a.logger.Sugar().Infof("Status endpoint run at address: %s", listen.Addr().String())
+	go func() {
+		if err := srv.Serve(listen); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			a.logger.Sugar().Warnf("status endpoint serve error: %v", err)
+		}
+	}()
+	return nil
+}
+
+// stopStatus is private func. Shutdowns status server if it was started.
+// Must be called under a.mutex lock.
+func (a *Agent) stopStatus() {
+	if a.statusServer == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), statusShutdownTimeout)
+	defer cancel()
+	if err := a.statusServer.Shutdown(ctx); err != nil {
+		a.logger.Sugar().Warnf("status endpoint shutdown error: %v", err)
+	}
+	a.statusServer = nil
+}
+
+// config is private func. Returns current agent's config.
+func (a *Agent) config() *Config {
+	a.cfgMutex.RLock()
+	defer a.cfgMutex.RUnlock()
+	return a.cfg
+}
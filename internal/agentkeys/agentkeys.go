@@ -0,0 +1,249 @@
+// Package agentkeys contains registry of agents keys, which are used for requests hash instead of one shared key.
+// Agent sends its ID in 'X-Agent-ID' HTTP header or 'x-agent-id' gRPC metadata,
+// server checks request hash with the agent's key.
+// Registry is stored in JSON file like {"agent-1": "key1", "agent-2": {"key": "key2", "public_key": "PEM"}}.
+// Agent's RSA public key is optional, it is used for gRPC responses encryption.
+package agentkeys
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/gostuding/go-metrics/internal/rsakeys"
+)
+
+// Agent ID values.
+const (
+	HeaderAgentID   = "X-Agent-ID" // HTTP header name
+	MetadataAgentID = "x-agent-id" // gRPC metadata key
+	MaxAgentIDLen   = 100          // max length of agent ID
+	keyBytes        = 32           // random bytes in generated key
+	fileMode        = 0600         // registry file permissions
+)
+
+var (
+	// ErrUnknownAgent is returned, if agent is absent in registry.
+	ErrUnknownAgent = errors.New("unknown agent")
+	// ErrAgentRequired is returned, if request doesn't contain agent ID and shared key is not set.
+	ErrAgentRequired = errors.New("agent ID required")
+)
+
+type (
+	// KeyFunc returns hash key for agent ID. Nil key means that request hash is not checked.
+	KeyFunc func(agentID string) ([]byte, error)
+
+	// Registry contains agents keys. Changes are saved in registry file.
+	Registry struct {
+		keys map[string]*entry // agent ID -> keys
+		path string            // registry file path
+		mx   sync.RWMutex      // mutex
+	}
+
+	// entry is private struct. Agent's keys, it is saved as key string if public key is not set.
+	entry struct {
+		Key       string         `json:"key"`                  // key for requests hash
+		PublicKey string         `json:"public_key,omitempty"` // agent's RSA public key in PEM format
+		public    *rsa.PublicKey // parsed public key
+	}
+)
+
+// UnmarshalJSON reads entry from key string or object.
+func (e *entry) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &e.Key); err == nil {
+		return nil
+	}
+	type item entry
+	return json.Unmarshal(data, (*item)(e)) //nolint:wrapcheck //<-senselessly
+}
+
+// MarshalJSON writes entry as key string if public key is not set.
+func (e *entry) MarshalJSON() ([]byte, error) {
+	if e.PublicKey == "" {
+		return json.Marshal(e.Key) //nolint:wrapcheck //<-senselessly
+	}
+	type item entry
+	return json.Marshal((*item)(e)) //nolint:wrapcheck //<-senselessly
+}
+
+// parsePublicKey is private func. Returns nil if value is empty.
+func parsePublicKey(value string) (*rsa.PublicKey, error) {
+	if value == "" {
+		return nil, nil //nolint:nilnil //<-public key is not set
+	}
+	return rsakeys.ParsePublic([]byte(value)) //nolint:wrapcheck //<-senselessly
+}
+
+// NewRegistry creates registry and loads keys from file. Absent file is an empty registry.
+func NewRegistry(path string) (*Registry, error) {
+	if path == "" {
+		return nil, errors.New("agents keys file path is empty")
+	}
+	r := Registry{keys: make(map[string]*entry), path: path}
+	if err := r.Load(); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Load reads registry file again.
+func (r *Registry) Load() error {
+	keys := make(map[string]*entry)
+	data, err := os.ReadFile(r.path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return fmt.Errorf("read agents keys file error: %w", err)
+	default:
+		if err = json.Unmarshal(data, &keys); err != nil {
+			return fmt.Errorf("agents keys file convert error: %w", err)
+		}
+	}
+	for id, e := range keys {
+		if err = validate(id, e); err != nil {
+			return fmt.Errorf("agents keys file error: %w", err)
+		}
+	}
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	r.keys = keys
+	return nil
+}
+
+// validate is private func. Checks agent ID and key, parses public key.
+func validate(id string, e *entry) error {
+	if id == "" || len(id) > MaxAgentIDLen {
+		return fmt.Errorf("agent ID length must be from 1 to %d", MaxAgentIDLen)
+	}
+	if e == nil || e.Key == "" {
+		return fmt.Errorf("agent '%s' key is empty", id)
+	}
+	pub, err := parsePublicKey(e.PublicKey)
+	if err != nil {
+		return fmt.Errorf("agent '%s' %w", id, err)
+	}
+	e.public = pub
+	return nil
+}
+
+// save is private func. Writes registry file by temporary file renaming. Must be called under lock.
+func (r *Registry) save() error {
+	data, err := json.MarshalIndent(r.keys, "", "  ")
+	if err != nil {
+		return fmt.Errorf("agents keys convert error: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(r.path), filepath.Base(r.path)+".*")
+	if err != nil {
+		return fmt.Errorf("create agents keys file error: %w", err)
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck //<-file is renamed on success
+	_, err = tmp.Write(data)
+	if err = errors.Join(err, tmp.Chmod(fileMode), tmp.Close()); err != nil {
+		return fmt.Errorf("write agents keys file error: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), r.path); err != nil {
+		return fmt.Errorf("save agents keys file error: %w", err)
+	}
+	return nil
+}
+
+// Add sets agent's key and RSA public key in PEM format. If key is empty, random key is generated.
+// Public key is optional. Returns the key.
+func (r *Registry) Add(id, key, publicKey string) (string, error) {
+	if key == "" {
+		b := make([]byte, keyBytes)
+		if _, err := rand.Read(b); err != nil {
+			return "", fmt.Errorf("generate key error: %w", err)
+		}
+		key = hex.EncodeToString(b)
+	}
+	e := entry{Key: key, PublicKey: publicKey}
+	if err := validate(id, &e); err != nil {
+		return "", err
+	}
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	prev, ok := r.keys[id]
+	r.keys[id] = &e
+	if err := r.save(); err != nil {
+		if ok {
+			r.keys[id] = prev
+		} else {
+			delete(r.keys, id)
+		}
+		return "", err
+	}
+	return key, nil
+}
+
+// Revoke removes agent's key. Returns ErrUnknownAgent if agent is absent.
+func (r *Registry) Revoke(id string) error {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	e, ok := r.keys[id]
+	if !ok {
+		return ErrUnknownAgent
+	}
+	delete(r.keys, id)
+	if err := r.save(); err != nil {
+		r.keys[id] = e
+		return err
+	}
+	return nil
+}
+
+// IDs returns sorted agents IDs.
+func (r *Registry) IDs() []string {
+	r.mx.RLock()
+	defer r.mx.RUnlock()
+	ids := make([]string, 0, len(r.keys))
+	for id := range r.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// KeyFunc returns func, which selects key for request: agent's key if agent is in registry, shared key otherwise.
+// If shared key is not set, requests of unknown agents and requests without agent ID are rejected,
+// so shared key must be empty to make revoking effective. Nil registry always returns shared key.
+func (r *Registry) KeyFunc(shared []byte) KeyFunc {
+	if r == nil {
+		return func(string) ([]byte, error) { return shared, nil }
+	}
+	return func(agentID string) ([]byte, error) {
+		r.mx.RLock()
+		defer r.mx.RUnlock()
+		if e, ok := r.keys[agentID]; ok && agentID != "" {
+			return []byte(e.Key), nil
+		}
+		switch {
+		case len(shared) > 0:
+			return shared, nil
+		case agentID != "":
+			return nil, fmt.Errorf("%w: '%s'", ErrUnknownAgent, agentID)
+		default:
+			return nil, ErrAgentRequired
+		}
+	}
+}
+
+// PublicKey returns agent's RSA public key, nil if it is not set or registry is nil.
+func (r *Registry) PublicKey(agentID string) *rsa.PublicKey {
+	if r == nil {
+		return nil
+	}
+	r.mx.RLock()
+	defer r.mx.RUnlock()
+	if e, ok := r.keys[agentID]; ok {
+		return e.public
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package agentkeys
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	_, err := NewRegistry("")
+	assert.Error(t, err, "empty path")
+
+	r, err := NewRegistry(path)
+	require.NoError(t, err, "absent file must be empty registry")
+	assert.Empty(t, r.IDs())
+
+	key, err := r.Add("agent-1", "", "")
+	require.NoError(t, err, "add generated key error")
+	assert.Len(t, key, keyBytes*2, "generated key length")
+	_, err = r.Add("agent-2", "key2", "")
+	require.NoError(t, err, "add key error")
+	_, err = r.Add("", "key", "")
+	assert.Error(t, err, "empty agent ID")
+	assert.Equal(t, []string{"agent-1", "agent-2"}, r.IDs())
+
+	info, err := os.Stat(path)
+	require.NoError(t, err, "registry file must be saved")
+	assert.Equal(t, os.FileMode(fileMode), info.Mode().Perm(), "registry file permissions")
+
+	loaded, err := NewRegistry(path)
+	require.NoError(t, err, "load registry error")
+	assert.Equal(t, r.IDs(), loaded.IDs(), "loaded registry")
+
+	require.NoError(t, r.Revoke("agent-2"), "revoke error")
+	assert.True(t, errors.Is(r.Revoke("agent-2"), ErrUnknownAgent), "revoke unknown agent")
+	require.NoError(t, loaded.Load(), "reload registry error")
+	assert.Equal(t, []string{"agent-1"}, loaded.IDs(), "reloaded registry")
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"agent-1": ""}`), fileMode))
+	assert.Error(t, loaded.Load(), "empty key in file")
+	assert.Equal(t, []string{"agent-1"}, loaded.IDs(), "registry must not be changed on load error")
+}
+
+func TestRegistry_KeyFunc(t *testing.T) {
+	r, err := NewRegistry(filepath.Join(t.TempDir(), "keys.json"))
+	require.NoError(t, err, "create registry error")
+	_, err = r.Add("agent-1", "key1", "")
+	require.NoError(t, err, "add key error")
+	var empty *Registry
+
+	tests := []struct {
+		name    string
+		keys    KeyFunc
+		agentID string
+		want    []byte
+		wantErr error
+	}{
+		{name: "Nil registry", keys: empty.KeyFunc([]byte("shared")), agentID: "agent-1", want: []byte("shared")},
+		{name: "Nil registry without key", keys: empty.KeyFunc(nil), agentID: "agent-1"},
+		{name: "Agent key", keys: r.KeyFunc([]byte("shared")), agentID: "agent-1", want: []byte("key1")},
+		{name: "Shared key", keys: r.KeyFunc([]byte("shared")), agentID: "agent-2", want: []byte("shared")},
+		{name: "Unknown agent", keys: r.KeyFunc(nil), agentID: "agent-2", wantErr: ErrUnknownAgent},
+		{name: "Agent required", keys: r.KeyFunc(nil), wantErr: ErrAgentRequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.keys(tt.agentID)
+			assert.True(t, errors.Is(err, tt.wantErr), "KeyFunc() error = %v, want %v", err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRegistry_PublicKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	key, err := rsa.GenerateKey(rand.Reader, 1024) //nolint:gomnd //<-test key size
+	require.NoError(t, err, "generate key error")
+	pub, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err, "marshal public key error")
+	pemKey := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub}))
+
+	r, err := NewRegistry(path)
+	require.NoError(t, err, "create registry error")
+	_, err = r.Add("agent-1", "key1", pemKey)
+	require.NoError(t, err, "add public key error")
+	_, err = r.Add("agent-2", "key2", "")
+	require.NoError(t, err, "add key error")
+	_, err = r.Add("agent-3", "key3", "not PEM")
+	assert.Error(t, err, "incorrect public key")
+
+	loaded, err := NewRegistry(path)
+	require.NoError(t, err, "load registry error")
+	assert.True(t, key.PublicKey.Equal(loaded.PublicKey("agent-1")), "loaded public key")
+	assert.Nil(t, loaded.PublicKey("agent-2"), "public key is not set")
+	var empty *Registry
+	assert.Nil(t, empty.PublicKey("agent-1"), "nil registry")
+
+	var file map[string]any
+	data, err := os.ReadFile(path)
+	require.NoError(t, err, "read registry file error")
+	require.NoError(t, json.Unmarshal(data, &file), "registry file convert error")
+	assert.Equal(t, "key2", file["agent-2"], "entry without public key is saved as key string")
+}
@@ -0,0 +1,350 @@
+// Package config loads options structs with the same precedence for agent and server:
+// command line flags > environment > config file > defaults.
+//
+// Fields are read from config file by json tag names. File format is chosen by extension:
+// '.json', '.yaml' ('.yml') or '.toml'. Fields with env tag are read from environment,
+// slices and maps are comma separated lists like 'a,b' and 'key=value,key2=value2'.
+// Values of fields with secret:"true" tag are redacted when config is printed.
+package config
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Internal constants.
+const (
+	EnvPath  = "CONFIG" // enviroment with config file path
+	redacted = "******" // printed instead of secret values
+)
+
+// ErrPrinted is returned by Load when config was printed by '-print-config' flag.
+var ErrPrinted = errors.New("config printed")
+
+type (
+	// Validator is implemented by options structs, which check values after loading.
+	// Validate must return all found errors joined by errors.Join.
+	Validator interface {
+		Validate() error
+	}
+
+	// Loader reads options struct from defaults, config file, environment and flags.
+	Loader struct {
+		Flags  *flag.FlagSet // flags must be bound to fields of loaded struct
+		Output io.Writer     // output for '-print-config' flag
+		path   string        // config file path from flags
+		print  bool          // flag to print config
+	}
+
+	// value is private type. Makes flag for field of any type, which can be read from environment.
+	value struct {
+		field reflect.Value
+		raw   string
+	}
+)
+
+// NewLoader creates Loader with common flags:
+// '-c' ('-config') for config file path and '-print-config' to print effective config.
+func NewLoader(name string) *Loader {
+	l := Loader{Flags: flag.NewFlagSet(name, flag.ContinueOnError), Output: os.Stdout}
+	l.Flags.StringVar(&l.path, "c", "", "path to config file: JSON, YAML or TOML")
+	l.Flags.StringVar(&l.path, "config", "", "path to config file (the same as -c)")
+	l.Flags.BoolVar(&l.print, "print-config", false, "print effective config with redacted secrets and exit")
+	return &l
+}
+
+// Var defines flag for field, which is parsed like enviroment value.
+// ptr must be a pointer to struct field.
+func (l *Loader) Var(ptr any, name, usage string) {
+	l.Flags.Var(&value{field: reflect.ValueOf(ptr).Elem()}, name, usage)
+}
+
+// Path returns config file path from flags or from 'CONFIG' enviroment.
+func (l *Loader) Path() string {
+	if l.path != "" {
+		return l.path
+	}
+	return os.Getenv(EnvPath)
+}
+
+// Load parses args and fills dst. dst must be a pointer to struct with default values.
+// Values are taken from config file, then from environment, then from flags, which were set in args.
+// If dst implements Validator, all validation errors are returned together.
+// If '-print-config' flag is set, effective config is printed and ErrPrinted is returned.
+func (l *Loader) Load(dst any, args []string) error {
+	if err := l.Flags.Parse(args); err != nil {
+		return fmt.Errorf("parse args error: %w", err)
+	}
+	set := make(map[string]string)
+	l.Flags.Visit(func(f *flag.Flag) {
+		set[f.Name] = f.Value.String()
+	})
+	if path := l.Path(); path != "" {
+		if err := DecodeFile(path, dst); err != nil {
+			return err
+		}
+	}
+	errs := []error{ReadEnv(dst)}
+	for name, val := range set {
+		if err := l.Flags.Set(name, val); err != nil {
+			errs = append(errs, fmt.Errorf("arg '%s' value error: %w", name, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	var err error
+	if v, ok := dst.(Validator); ok {
+		err = v.Validate()
+	}
+	if l.print {
+		if perr := Print(l.Output, dst); perr != nil {
+			return perr
+		}
+		if err == nil {
+			return ErrPrinted
+		}
+	}
+	return err
+}
+
+// DecodeFile reads config file into dst. Only values, which are in file, are changed.
+func DecodeFile(path string, dst any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file read error: %w", err)
+	}
+	var values map[string]any
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json", "":
+		err = json.Unmarshal(data, dst)
+		if err != nil {
+			return fmt.Errorf("config file convert error: %w", err)
+		}
+		return nil
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return fmt.Errorf("config file format '%s' is not supported", ext)
+	}
+	if err != nil {
+		return fmt.Errorf("config file convert error: %w", err)
+	}
+	// Values are converted through JSON, so json tags are used for all formats.
+	data, err = json.Marshal(values)
+	if err != nil {
+		return fmt.Errorf("config file convert error: %w", err)
+	}
+	if err = json.Unmarshal(data, dst); err != nil {
+		return fmt.Errorf("config file convert error: %w", err)
+	}
+	return nil
+}
+
+// ReadEnv sets dst fields with env tag from environment. All errors are returned together.
+func ReadEnv(dst any) error {
+	v := reflect.ValueOf(dst).Elem()
+	errs := make([]error, 0)
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Tag.Get("env")
+		if name == "" {
+			continue
+		}
+		val, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := setValue(v.Field(i), val); err != nil {
+			errs = append(errs, fmt.Errorf("enviroment '%s' value error: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// setValue is private func. Converts string to field value.
+func setValue(field reflect.Value, val string) error {
+	if field.CanAddr() {
+		switch p := field.Addr().Interface().(type) {
+		case flag.Value:
+			return p.Set(val) //nolint:wrapcheck //<-wrapped by caller
+		case encoding.TextUnmarshaler:
+			return p.UnmarshalText([]byte(val)) //nolint:wrapcheck //<-wrapped by caller
+		case *time.Duration:
+			d, err := time.ParseDuration(val)
+			if err != nil {
+				return err //nolint:wrapcheck //<-wrapped by caller
+			}
+			*p = d
+			return nil
+		}
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(val)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(val))
+		if err != nil {
+			return err //nolint:wrapcheck //<-wrapped by caller
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(strings.TrimSpace(val), 10, field.Type().Bits())
+		if err != nil {
+			return err //nolint:wrapcheck //<-wrapped by caller
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(strings.TrimSpace(val), 10, field.Type().Bits())
+		if err != nil {
+			return err //nolint:wrapcheck //<-wrapped by caller
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(strings.TrimSpace(val), field.Type().Bits())
+		if err != nil {
+			return err //nolint:wrapcheck //<-wrapped by caller
+		}
+		field.SetFloat(n)
+	case reflect.Slice:
+		return setSlice(field, val)
+	case reflect.Map:
+		return setMap(field, val)
+	default:
+		return fmt.Errorf("type '%s' is not supported", field.Type())
+	}
+	return nil
+}
+
+// setSlice is private func. Converts comma separated list to slice.
+func setSlice(field reflect.Value, val string) error {
+	items := SplitList(val)
+	s := reflect.MakeSlice(field.Type(), len(items), len(items))
+	for i, item := range items {
+		if err := setValue(s.Index(i), item); err != nil {
+			return fmt.Errorf("list value '%s' convert error: %w", item, err)
+		}
+	}
+	field.Set(s)
+	return nil
+}
+
+// setMap is private func. Converts comma separated list like 'key=value,key2=value2' to map.
+func setMap(field reflect.Value, val string) error {
+	if field.Type().Key().Kind() != reflect.String {
+		return fmt.Errorf("map key type '%s' is not supported", field.Type().Key())
+	}
+	m := reflect.MakeMap(field.Type())
+	for _, item := range SplitList(val) {
+		key, v, ok := strings.Cut(item, "=")
+		if !ok {
+			return fmt.Errorf("list value '%s' must be like 'key=value'", item)
+		}
+		elem := reflect.New(field.Type().Elem()).Elem()
+		if err := setValue(elem, strings.TrimSpace(v)); err != nil {
+			return fmt.Errorf("list value '%s' convert error: %w", item, err)
+		}
+		m.SetMapIndex(reflect.ValueOf(strings.TrimSpace(key)).Convert(field.Type().Key()), elem)
+	}
+	field.Set(m)
+	return nil
+}
+
+// SplitList splits comma separated list, empty items are skipped.
+func SplitList(val string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(val, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// String returns flag value.
+func (v *value) String() string {
+	return v.raw
+}
+
+// Set converts flag value like enviroment value.
+func (v *value) Set(val string) error {
+	if err := setValue(v.field, val); err != nil {
+		return err
+	}
+	v.raw = val
+	return nil
+}
+
+// Print writes options struct as indented JSON with redacted secrets.
+func Print(w io.Writer, src any) error {
+	data, err := json.MarshalIndent(Redact(src), "", "  ")
+	if err != nil {
+		return fmt.Errorf("config convert error: %w", err)
+	}
+	if _, err = fmt.Fprintln(w, string(data)); err != nil {
+		return fmt.Errorf("config print error: %w", err)
+	}
+	return nil
+}
+
+// Redact returns options struct as map by json names. Not empty values of fields with secret:"true" tag are redacted.
+func Redact(src any) any {
+	return redact(reflect.ValueOf(src))
+}
+
+// redact is private func. Walks through structs, slices and maps.
+func redact(v reflect.Value) any {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return redact(v.Elem())
+	case reflect.Struct:
+		items := make(map[string]any)
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if !f.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			if f.Tag.Get("secret") == "true" && !v.Field(i).IsZero() {
+				items[name] = redacted
+				continue
+			}
+			items[name] = redact(v.Field(i))
+		}
+		return items
+	case reflect.Slice:
+		if v.IsNil() || v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface()
+		}
+		items := make([]any, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			items = append(items, redact(v.Index(i)))
+		}
+		return items
+	default:
+		if !v.CanInterface() {
+			return nil
+		}
+		return v.Interface()
+	}
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testConfig struct {
+	Labels   map[string]string `json:"labels" env:"TEST_LABELS"`
+	Name     string            `json:"name" env:"TEST_NAME"`
+	Key      string            `json:"key" env:"TEST_KEY" secret:"true"`
+	Codes    []int             `json:"codes" env:"TEST_CODES"`
+	Interval int               `json:"interval" env:"TEST_INTERVAL"`
+	Timeout  time.Duration     `json:"timeout" env:"TEST_TIMEOUT"`
+	Enabled  bool              `json:"enabled" env:"TEST_ENABLED"`
+}
+
+func (c *testConfig) Validate() error {
+	errs := make([]error, 0)
+	if c.Interval <= 0 {
+		errs = append(errs, errors.New("interval must be greater then 0"))
+	}
+	if c.Name == "" {
+		errs = append(errs, errors.New("name is empty"))
+	}
+	return errors.Join(errs...)
+}
+
+func writeFile(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+	return path
+}
+
+func TestDecodeFile(t *testing.T) {
+	files := map[string]string{
+		"config.json": `{"name": "file", "interval": 5, "codes": [1, 2], "labels": {"env": "prod"}}`,
+		"config.yaml": "name: file\ninterval: 5\ncodes: [1, 2]\nlabels:\n  env: prod\n",
+		"config.toml": "name = \"file\"\ninterval = 5\ncodes = [1, 2]\n[labels]\nenv = \"prod\"\n",
+	}
+	for name, data := range files {
+		t.Run(name, func(t *testing.T) {
+			cfg := testConfig{Enabled: true}
+			require.NoError(t, DecodeFile(writeFile(t, name, data), &cfg))
+			want := testConfig{
+				Name: "file", Interval: 5, Codes: []int{1, 2}, Labels: map[string]string{"env": "prod"}, Enabled: true,
+			}
+			assert.Equal(t, want, cfg)
+		})
+	}
+	assert.Error(t, DecodeFile(writeFile(t, "config.ini", ""), &testConfig{}), "unknown format error expected")
+}
+
+func TestLoader_Load(t *testing.T) {
+	path := writeFile(t, "config.yaml", "name: file\ninterval: 5\nkey: file\n")
+	t.Setenv(EnvPath, path)
+	t.Setenv("TEST_INTERVAL", "7")
+	t.Setenv("TEST_KEY", "env")
+	t.Setenv("TEST_CODES", "502, 503")
+	t.Setenv("TEST_LABELS", "host=web1,env=prod")
+	t.Setenv("TEST_TIMEOUT", "2s")
+	cfg := testConfig{Interval: 1}
+	l := NewLoader("test")
+	l.Flags.StringVar(&cfg.Key, "k", cfg.Key, "key")
+	l.Var(&cfg.Enabled, "enabled", "flag")
+	require.NoError(t, l.Load(&cfg, []string{"-k", "flag", "-enabled", "true"}))
+	assert.Equal(t, "file", cfg.Name, "file value expected")
+	assert.Equal(t, 7, cfg.Interval, "enviroment must be used instead of file")
+	assert.Equal(t, "flag", cfg.Key, "flag must be used instead of enviroment")
+	assert.True(t, cfg.Enabled)
+	assert.Equal(t, []int{502, 503}, cfg.Codes)
+	assert.Equal(t, map[string]string{"host": "web1", "env": "prod"}, cfg.Labels)
+	assert.Equal(t, 2*time.Second, cfg.Timeout)
+}
+
+func TestLoader_Load_errors(t *testing.T) {
+	t.Setenv("TEST_INTERVAL", "x")
+	t.Setenv("TEST_LABELS", "host")
+	err := NewLoader("test").Load(&testConfig{}, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "TEST_INTERVAL")
+	assert.Contains(t, err.Error(), "TEST_LABELS")
+
+	t.Setenv("TEST_INTERVAL", "0")
+	t.Setenv("TEST_LABELS", "")
+	err = NewLoader("test").Load(&testConfig{}, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "interval must be greater then 0")
+	assert.Contains(t, err.Error(), "name is empty")
+}
+
+func TestLoader_Load_print(t *testing.T) {
+	cfg := testConfig{Name: "agent", Interval: 1, Key: "secret"}
+	l := NewLoader("test")
+	var b bytes.Buffer
+	l.Output = &b
+	err := l.Load(&cfg, []string{"-print-config"})
+	assert.ErrorIs(t, err, ErrPrinted)
+	assert.Contains(t, b.String(), `"name": "agent"`)
+	assert.False(t, strings.Contains(b.String(), "secret"), "secret must be redacted")
+	assert.Contains(t, b.String(), redacted)
+}
@@ -0,0 +1,145 @@
+// Package logging creates zap loggers for agent and server and keeps request scoped values:
+// request ID and logger with request ID field.
+// Request ID is propagated by 'X-Request-ID' HTTP header and 'x-request-id' gRPC metadata.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Log formats.
+const (
+	FormatConsole = "console" // human readable format, default
+	FormatJSON    = "json"    // JSON lines
+)
+
+// Request ID values.
+const (
+	HeaderRequestID   = "X-Request-ID" // HTTP header name
+	MetadataRequestID = "x-request-id" // gRPC metadata key
+	FieldRequestID    = "request_id"   // log field name
+	maxRequestIDLen   = 128            // max length of received request ID
+	requestIDBytes    = 16             // random bytes in generated request ID
+)
+
+// Options contains logger settings.
+type Options struct {
+	Level  string // log level, not changed if empty
+	Format string // log format: console or json
+	File   string // log file path, stderr if empty
+}
+
+type ctxKey int
+
+const (
+	requestIDKey ctxKey = iota // key of request ID in context
+	loggerKey                  // key of request logger in context
+)
+
+// Validate checks options.
+func (o Options) Validate() error {
+	if o.Level != "" {
+		if _, err := zapcore.ParseLevel(o.Level); err != nil {
+			return fmt.Errorf("log level error: %w", err)
+		}
+	}
+	switch o.Format {
+	case "", FormatConsole, FormatJSON:
+	default:
+		return fmt.Errorf("log format must be '%s' or '%s'", FormatConsole, FormatJSON)
+	}
+	return nil
+}
+
+// level is shared by loggers from New, so level can be changed at runtime.
+var level = zap.NewAtomicLevelAt(zap.DebugLevel) //nolint:gochecknoglobals //<-used by all loggers
+
+// New creates logger. Level of all loggers is shared and can be changed at runtime by SetLevel.
+func New(o Options) (*zap.Logger, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	if o.Level != "" {
+		if err := SetLevel(o.Level); err != nil {
+			return nil, err
+		}
+	}
+	cfg := zap.NewDevelopmentConfig()
+	if o.Format == FormatJSON {
+		cfg = zap.NewProductionConfig()
+		cfg.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	}
+	cfg.Level = level
+	if o.File != "" {
+		cfg.OutputPaths = []string{o.File}
+	}
+	logger, err := cfg.Build()
+	if err != nil {
+		return nil, fmt.Errorf("logger init error: %w", err)
+	}
+	return logger, nil
+}
+
+// SetLevel changes level of loggers, which were created by New.
+func SetLevel(l string) error {
+	if err := level.UnmarshalText([]byte(l)); err != nil {
+		return fmt.Errorf("logger level '%s' error: %w", l, err)
+	}
+	return nil
+}
+
+// NewRequestID returns random request ID.
+func NewRequestID() string {
+	b := make([]byte, requestIDBytes)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// ValidRequestID checks received request ID. Only printable ASCII symbols without spaces are allowed.
+func ValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// WithRequestID returns context with request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID returns request ID from context or empty string.
+func RequestID(ctx context.Context) string {
+	id, ok := ctx.Value(requestIDKey).(string)
+	if !ok {
+		return ""
+	}
+	return id
+}
+
+// WithLogger returns context with request ID and logger, which adds request ID field to all lines.
+func WithLogger(ctx context.Context, logger *zap.SugaredLogger, id string) context.Context {
+	ctx = WithRequestID(ctx, id)
+	return context.WithValue(ctx, loggerKey, logger.With(FieldRequestID, id))
+}
+
+// FromContext returns request logger from context. If context has no logger, fallback is returned.
+func FromContext(ctx context.Context, fallback *zap.SugaredLogger) *zap.SugaredLogger {
+	if logger, ok := ctx.Value(loggerKey).(*zap.SugaredLogger); ok {
+		return logger
+	}
+	return fallback
+}
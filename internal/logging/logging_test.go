@@ -0,0 +1,80 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestOptions_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		o       Options
+		wantErr bool
+	}{
+		{name: "Default", o: Options{}},
+		{name: "JSON", o: Options{Level: "info", Format: FormatJSON}},
+		{name: "Console", o: Options{Level: "warn", Format: FormatConsole}},
+		{name: "Unknown level", o: Options{Level: "verbose"}, wantErr: true},
+		{name: "Unknown format", o: Options{Format: "xml"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.o.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Options.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.json")
+	logger, err := New(Options{Level: "warn", Format: FormatJSON, File: path})
+	require.NoError(t, err, "create logger error")
+	assert.Equal(t, zap.WarnLevel, level.Level(), "level must be set by options")
+
+	ctx := WithLogger(context.Background(), logger.Sugar(), "req-1")
+	log := FromContext(ctx, nil)
+	log.Info("skipped")
+	log.Warn("written")
+	_ = logger.Sync()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err, "read log file error")
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Equal(t, 1, len(lines), "log lines count")
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry), "log line must be JSON")
+	assert.Equal(t, "written", entry["msg"])
+	assert.Equal(t, "req-1", entry[FieldRequestID])
+
+	_, err = New(Options{Format: "xml"})
+	assert.Error(t, err, "unknown format must be rejected")
+
+	require.NoError(t, SetLevel("error"), "set level error")
+	assert.Equal(t, zap.ErrorLevel, level.Level(), "level must be changed for created loggers")
+	assert.False(t, logger.Core().Enabled(zap.WarnLevel), "logger level must be changed")
+	assert.Error(t, SetLevel("verbose"), "unknown level must be rejected")
+}
+
+func TestRequestID(t *testing.T) {
+	id := NewRequestID()
+	assert.True(t, ValidRequestID(id), "generated id must be valid")
+	assert.NotEqual(t, id, NewRequestID(), "generated ids must differ")
+	assert.False(t, ValidRequestID(""), "empty id")
+	assert.False(t, ValidRequestID("with space"), "id with space")
+	assert.False(t, ValidRequestID(strings.Repeat("a", maxRequestIDLen+1)), "too long id")
+
+	ctx := context.Background()
+	assert.Equal(t, "", RequestID(ctx))
+	assert.Equal(t, "abc", RequestID(WithRequestID(ctx, "abc")))
+	fallback := zap.NewNop().Sugar()
+	assert.Same(t, fallback, FromContext(ctx, fallback), "fallback logger")
+}
@@ -0,0 +1,328 @@
+// Package rsakeys generates, saves and reads RSA keys for messages encryption.
+// Private keys are saved in PKCS #1 'RSA PRIVATE KEY' PEM blocks with 0600 permissions,
+// public keys in PKIX 'PUBLIC KEY' PEM blocks. PKCS #8 'PRIVATE KEY' blocks and legacy
+// 'SERVER PRIVATE KEY' and 'AGENT PUBLIC KEY' blocks are read too.
+package rsakeys
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// PEM blocks types.
+const (
+	TypePrivate       = "RSA PRIVATE KEY"    // PKCS #1 private key
+	TypePrivatePKCS8  = "PRIVATE KEY"        // PKCS #8 private key
+	TypePublic        = "PUBLIC KEY"         // PKIX public key
+	typeLegacyPrivate = "SERVER PRIVATE KEY" // private key type of old keys generator
+	typeLegacyPublic  = "AGENT PUBLIC KEY"   // public key type of old keys generator
+)
+
+// Keys files values.
+const (
+	DefaultSize     = 4096          // default key size in bits
+	MinSize         = 2048          // min key size in bits
+	PrivateFileName = "private_key" // private key file name
+	PublicFileName  = "public_key"  // public key file name
+	OldSuffix       = ".old"        // suffix of previous keys files after rotation
+	newSuffix       = ".new"        // suffix of new keys files during rotation
+	privateMode     = 0600          // private key file permissions
+	publicMode      = 0644          // public key file permissions
+	fingerprintName = "SHA256:"     // fingerprint prefix
+	fileFlags       = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+)
+
+// ErrExists is returned, if key file exists and overwriting is not allowed.
+var ErrExists = errors.New("key file already exists")
+
+type (
+	// Pair contains keys files paths.
+	Pair struct {
+		Private string // private key file path
+		Public  string // public key file path
+	}
+
+	// Info is PEM block description.
+	Info struct {
+		Type        string // PEM block type
+		Private     bool   // flag of private key
+		Legacy      bool   // flag of non-standard PEM type
+		Bits        int    // key size in bits
+		Fingerprint string // public key fingerprint
+	}
+)
+
+// NewPair returns keys files paths in dir. Name is files names prefix like 'agent', it may be empty.
+func NewPair(dir, name string) Pair {
+	prefix := ""
+	if name != "" {
+		prefix = name + "_"
+	}
+	return Pair{
+		Private: filepath.Join(dir, prefix+PrivateFileName),
+		Public:  filepath.Join(dir, prefix+PublicFileName),
+	}
+}
+
+// old is private func. Returns paths of previous keys files.
+func (p Pair) old() Pair {
+	return Pair{Private: p.Private + OldSuffix, Public: p.Public + OldSuffix}
+}
+
+// Generate creates RSA private key.
+func Generate(bits int) (*rsa.PrivateKey, error) {
+	if bits < MinSize {
+		return nil, fmt.Errorf("key size must be at least %d bits", MinSize)
+	}
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		return nil, fmt.Errorf("generate key error: %w", err)
+	}
+	return key, nil
+}
+
+// EncodePrivate returns private key in PKCS #1 PEM block.
+func EncodePrivate(key *rsa.PrivateKey) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: TypePrivate, Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
+
+// EncodePublic returns public key in PKIX PEM block.
+func EncodePublic(key *rsa.PublicKey) ([]byte, error) {
+	data, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("marshal public key error: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: TypePublic, Bytes: data}), nil
+}
+
+// Fingerprint returns SHA-256 of public key in PKIX format like 'SHA256:base64'.
+// Private and public keys of one pair have the same fingerprint.
+func Fingerprint(key *rsa.PublicKey) (string, error) {
+	data, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "", fmt.Errorf("marshal public key error: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return fingerprintName + base64.RawStdEncoding.EncodeToString(sum[:]), nil
+}
+
+// parsePrivate is private func. Parses private key from PEM block by its type.
+func parsePrivate(block *pem.Block) (*rsa.PrivateKey, error) {
+	if block.Type != TypePrivatePKCS8 {
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse private key error: %w", err)
+		}
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse private key error: %w", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key type is not RSA")
+	}
+	return rsaKey, nil
+}
+
+// parsePublic is private func. Parses PKIX public key from PEM block.
+func parsePublic(block *pem.Block) (*rsa.PublicKey, error) {
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse public key error: %w", err)
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("public key type is not RSA")
+	}
+	return rsaKey, nil
+}
+
+// ParsePrivate parses the first PEM block as RSA private key.
+func ParsePrivate(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("failed to parse PEM block with private key")
+	}
+	return parsePrivate(block)
+}
+
+// ParsePublic parses the first PEM block as PKIX RSA public key.
+func ParsePublic(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("failed to parse PEM block with public key")
+	}
+	return parsePublic(block)
+}
+
+// ReadPublic reads RSA public key from file.
+func ReadPublic(path string) (*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("file read error: %w", err)
+	}
+	return ParsePublic(data)
+}
+
+// ReadPrivate reads RSA private key from file.
+func ReadPrivate(path string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("file read error: %w", err)
+	}
+	return ParsePrivate(data)
+}
+
+// Inspect describes all PEM blocks in data.
+func Inspect(data []byte) ([]Info, error) {
+	items := make([]Info, 0)
+	for {
+		block, rest := pem.Decode(data)
+		if block == nil {
+			break
+		}
+		data = rest
+		item := Info{Type: block.Type}
+		var pub *rsa.PublicKey
+		switch block.Type {
+		case TypePrivate, TypePrivatePKCS8, typeLegacyPrivate:
+			key, err := parsePrivate(block)
+			if err != nil {
+				return nil, err
+			}
+			item.Private, pub = true, &key.PublicKey
+		case TypePublic, typeLegacyPublic:
+			key, err := parsePublic(block)
+			if err != nil {
+				return nil, err
+			}
+			pub = key
+		default:
+			return nil, fmt.Errorf("unknown PEM block type '%s'", block.Type)
+		}
+		item.Legacy = block.Type == typeLegacyPrivate || block.Type == typeLegacyPublic
+		item.Bits = pub.N.BitLen()
+		fingerprint, err := Fingerprint(pub)
+		if err != nil {
+			return nil, err
+		}
+		item.Fingerprint = fingerprint
+		items = append(items, item)
+	}
+	if len(items) == 0 {
+		return nil, errors.New("PEM blocks not found")
+	}
+	return items, nil
+}
+
+// writeFile is private func. Writes file with permissions, existing file is overwritten only if force is set.
+func writeFile(path string, data []byte, mode os.FileMode, force bool) error {
+	flags := fileFlags | os.O_EXCL
+	if force {
+		flags = fileFlags
+	}
+	file, err := os.OpenFile(path, flags, mode)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("%w: %s", ErrExists, path)
+	}
+	if err != nil {
+		return fmt.Errorf("create key file error: %w", err)
+	}
+	_, err = file.Write(data)
+	// Permissions of overwritten file are not changed by OpenFile.
+	if err = errors.Join(err, file.Chmod(mode), file.Close()); err != nil {
+		return fmt.Errorf("write key file error: %w", err)
+	}
+	return nil
+}
+
+// Save writes keys pair files: private key with 0600 permissions, public key with 0644.
+// Existing files are overwritten only if force is set.
+func (p Pair) Save(key *rsa.PrivateKey, force bool) error {
+	public, err := EncodePublic(&key.PublicKey)
+	if err != nil {
+		return err
+	}
+	if !force {
+		for _, path := range []string{p.Private, p.Public} {
+			if _, err = os.Stat(path); err == nil {
+				return fmt.Errorf("%w: %s", ErrExists, path)
+			}
+		}
+	}
+	if err = writeFile(p.Private, EncodePrivate(key), privateMode, force); err != nil {
+		return err
+	}
+	return writeFile(p.Public, public, publicMode, force)
+}
+
+// Rotate moves current keys files to files with '.old' suffix and saves new key.
+// Server must use both private keys until agents use the new public key.
+// New keys are written to temporary files before current files are moved, current files are restored
+// if moving fails. Existing '.old' files are overwritten only if force is set. Returns paths of previous keys files.
+func (p Pair) Rotate(key *rsa.PrivateKey, force bool) (Pair, error) {
+	old := p.old()
+	if _, err := ReadPrivate(p.Private); err != nil {
+		return old, fmt.Errorf("current private key error: %w", err)
+	}
+	if !force {
+		for _, path := range []string{old.Private, old.Public} {
+			if _, err := os.Stat(path); err == nil {
+				return old, fmt.Errorf("%w: %s", ErrExists, path)
+			}
+		}
+	}
+	next := Pair{Private: p.Private + newSuffix, Public: p.Public + newSuffix}
+	defer next.remove()
+	if err := next.Save(key, true); err != nil {
+		return old, fmt.Errorf("save new keys error: %w", err)
+	}
+	if err := os.Rename(p.Private, old.Private); err != nil {
+		return old, fmt.Errorf("move private key error: %w", err)
+	}
+	hasPublic := true
+	if err := os.Rename(p.Public, old.Public); err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return old, errors.Join(fmt.Errorf("move public key error: %w", err), p.restore(old, false))
+		}
+		hasPublic = false
+	}
+	if err := os.Rename(next.Private, p.Private); err != nil {
+		return old, errors.Join(fmt.Errorf("move new private key error: %w", err), p.restore(old, hasPublic))
+	}
+	if err := os.Rename(next.Public, p.Public); err != nil {
+		return old, errors.Join(fmt.Errorf("move new public key error: %w", err), p.restore(old, hasPublic))
+	}
+	return old, nil
+}
+
+// restore is private func. Moves previous keys files back after failed rotation.
+func (p Pair) restore(old Pair, public bool) error {
+	if err := os.Rename(old.Private, p.Private); err != nil {
+		return fmt.Errorf("restore private key error: %w", err)
+	}
+	if !public {
+		return nil
+	}
+	if err := os.Rename(old.Public, p.Public); err != nil {
+		return fmt.Errorf("restore public key error: %w", err)
+	}
+	return nil
+}
+
+// remove is private func. Removes temporary keys files, which were not moved.
+func (p Pair) remove() {
+	for _, path := range []string{p.Private, p.Public} {
+		os.Remove(path) //nolint:errcheck,gosec //<-files are moved after successful rotation
+	}
+}
@@ -0,0 +1,113 @@
+package rsakeys
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPair(t *testing.T) {
+	_, err := Generate(1024)
+	assert.Error(t, err, "small key size")
+	key, err := Generate(MinSize)
+	require.NoError(t, err, "generate key error")
+
+	pair := NewPair(t.TempDir(), "agent")
+	require.NoError(t, pair.Save(key, false), "save keys error")
+	for path, mode := range map[string]os.FileMode{pair.Private: privateMode, pair.Public: publicMode} {
+		info, err := os.Stat(path)
+		require.NoError(t, err, "key file must be saved")
+		assert.Equal(t, mode, info.Mode().Perm(), "key file permissions: %s", path)
+	}
+	err = pair.Save(key, false)
+	assert.True(t, errors.Is(err, ErrExists), "existing files must not be overwritten")
+	assert.NoError(t, pair.Save(key, true), "forced save error")
+
+	read, err := ReadPrivate(pair.Private)
+	require.NoError(t, err, "read private key error")
+	assert.True(t, key.Equal(read), "read key is not equal to saved")
+
+	private, err := os.ReadFile(pair.Private)
+	require.NoError(t, err)
+	public, err := os.ReadFile(pair.Public)
+	require.NoError(t, err)
+	privateInfo, err := Inspect(private)
+	require.NoError(t, err, "inspect private key error")
+	publicInfo, err := Inspect(public)
+	require.NoError(t, err, "inspect public key error")
+	require.Len(t, privateInfo, 1)
+	require.Len(t, publicInfo, 1)
+	assert.Equal(t, Info{Type: TypePrivate, Private: true, Bits: MinSize, Fingerprint: publicInfo[0].Fingerprint},
+		privateInfo[0], "private key info")
+	assert.Equal(t, TypePublic, publicInfo[0].Type, "public key type")
+	pub, err := ReadPublic(pair.Public)
+	require.NoError(t, err, "read public key error")
+	assert.True(t, key.PublicKey.Equal(pub), "read public key is not equal to saved")
+
+	next, err := Generate(MinSize)
+	require.NoError(t, err, "generate key error")
+	old, err := pair.Rotate(next, false)
+	require.NoError(t, err, "rotate keys error")
+	oldKey, err := ReadPrivate(old.Private)
+	require.NoError(t, err, "read old private key error")
+	assert.True(t, key.Equal(oldKey), "old key must be moved")
+	newKey, err := ReadPrivate(pair.Private)
+	require.NoError(t, err, "read new private key error")
+	assert.True(t, next.Equal(newKey), "new key must be saved")
+	_, err = pair.Rotate(next, false)
+	assert.True(t, errors.Is(err, ErrExists), "old files must not be overwritten")
+}
+
+func TestPair_RotateSaveError(t *testing.T) {
+	key, err := Generate(MinSize)
+	require.NoError(t, err, "generate key error")
+	pair := NewPair(t.TempDir(), "")
+	require.NoError(t, pair.Save(key, false), "save keys error")
+	// New public key can't be written over not empty directory.
+	require.NoError(t, os.MkdirAll(filepath.Join(pair.Public+newSuffix, "dir"), 0o700))
+
+	next, err := Generate(MinSize)
+	require.NoError(t, err, "generate key error")
+	old, err := pair.Rotate(next, false)
+	assert.Error(t, err, "save new keys error expected")
+	read, err := ReadPrivate(pair.Private)
+	require.NoError(t, err, "current private key must be kept")
+	assert.True(t, key.Equal(read), "current private key must not be changed")
+	pub, err := ReadPublic(pair.Public)
+	require.NoError(t, err, "current public key must be kept")
+	assert.True(t, key.PublicKey.Equal(pub), "current public key must not be changed")
+	for _, path := range []string{old.Private, old.Public, pair.Private + newSuffix} {
+		_, err = os.Stat(path)
+		assert.ErrorIs(t, err, os.ErrNotExist, "file must not exist: %s", path)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	key, err := Generate(MinSize)
+	require.NoError(t, err, "generate key error")
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	block := pem.EncodeToMemory(&pem.Block{Type: TypePrivatePKCS8, Bytes: pkcs8})
+	data := pem.EncodeToMemory(&pem.Block{Type: typeLegacyPrivate, Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	data = append(data, block...)
+
+	items, err := Inspect(data)
+	require.NoError(t, err, "inspect error")
+	require.Len(t, items, 2)
+	assert.True(t, items[0].Legacy, "legacy PEM type")
+	assert.False(t, items[1].Legacy, "PKCS #8 PEM type")
+	assert.Equal(t, items[0].Fingerprint, items[1].Fingerprint, "fingerprints of one key")
+
+	read, err := ParsePrivate(block)
+	if assert.NoError(t, err, "parse PKCS #8 key error") {
+		assert.True(t, key.Equal(read))
+	}
+	_, err = Inspect([]byte("not a key"))
+	assert.Error(t, err, "PEM blocks not found")
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"go.uber.org/zap"
+
+	"github.com/gostuding/go-metrics/internal/agentkeys"
+	"github.com/gostuding/go-metrics/internal/logging"
+)
+
+// Admin API values.
+const (
+	authorizationHeader = "Authorization" // admin token header
+	bearerPrefix        = "Bearer "       // admin token prefix
+	agentIDString       = "agentID"       // URL param name
+)
+
+// agentKey is private type. Body of add key request and response.
+type agentKey struct {
+	AgentID   string `json:"agent_id,omitempty"`   // agent ID
+	Key       string `json:"key,omitempty"`        // agent key, generated if empty in request
+	PublicKey string `json:"public_key,omitempty"` // agent's RSA public key in PEM format for responses encryption
+}
+
+// makeAdminRouter is private func. Creates admin API for agents keys:
+//
+//	GET /keys - agents IDs list;
+//	PUT /keys/{agentID} - adds or replaces agent's keys, body '{"key": "...", "public_key": "..."}' is optional,
+//	the key is generated if empty, the public key is used for gRPC responses encryption;
+//	DELETE /keys/{agentID} - revokes agent's key.
+//
+// Requests must contain header 'Authorization: Bearer <token>'. Changes are saved in registry file.
+func makeAdminRouter(registry *agentkeys.Registry, token string, logger *zap.SugaredLogger) http.Handler {
+	router := chi.NewRouter()
+	router.Use(adminAuth(token, logger))
+
+	router.Get("/keys", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, logging.FromContext(r.Context(), logger), registry.IDs())
+	})
+
+	router.Put("/keys/{agentID}", func(w http.ResponseWriter, r *http.Request) {
+		log := logging.FromContext(r.Context(), logger)
+		var item agentKey
+		body, err := io.ReadAll(r.Body)
+		if err == nil && len(body) > 0 {
+			err = json.Unmarshal(body, &item)
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			log.Warnf("add agent key request error: %v", err)
+			return
+		}
+		id := chi.URLParam(r, agentIDString)
+		key, err := registry.Add(id, item.Key, item.PublicKey)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			log.Warnf("add agent '%s' key error: %v", id, err)
+			return
+		}
+		log.Infof("agent '%s' key added", id)
+		writeJSON(w, log, agentKey{AgentID: id, Key: key})
+	})
+
+	router.Delete("/keys/{agentID}", func(w http.ResponseWriter, r *http.Request) {
+		log := logging.FromContext(r.Context(), logger)
+		id := chi.URLParam(r, agentIDString)
+		err := registry.Revoke(id)
+		switch {
+		case errors.Is(err, agentkeys.ErrUnknownAgent):
+			w.WriteHeader(http.StatusNotFound)
+		case err != nil:
+			w.WriteHeader(http.StatusInternalServerError)
+			log.Warnf("revoke agent '%s' key error: %v", id, err)
+		default:
+			log.Infof("agent '%s' key revoked", id)
+		}
+	})
+	return router
+}
+
+// adminAuth is private func. Checks admin token in 'Authorization' header.
+func adminAuth(token string, logger *zap.SugaredLogger) func(h http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			value, ok := strings.CutPrefix(r.Header.Get(authorizationHeader), bearerPrefix)
+			if !ok || subtle.ConstantTimeCompare([]byte(value), []byte(token)) != 1 {
+				w.WriteHeader(http.StatusUnauthorized)
+				logging.FromContext(r.Context(), logger).Warnf("admin request unauthorized: %s %s", r.Method, r.URL.Path)
+				return
+			}
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// writeJSON is private func. Writes value as JSON response.
+func writeJSON(w http.ResponseWriter, logger *zap.SugaredLogger, value any) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		logger.Warnf("response convert error: %v", err)
+		return
+	}
+	w.Header().Set(contentType, applicationJSON)
+	if _, err = w.Write(data); err != nil {
+		logger.Warnf(writeErrorString, err)
+	}
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/gostuding/go-metrics/internal/agentkeys"
+	"github.com/gostuding/go-metrics/internal/server/storage"
+)
+
+func TestAdminRouter(t *testing.T) {
+	dir := t.TempDir()
+	strg, err := storage.NewMemStorage(false, filepath.Join(dir, "metrics.json"), 300)
+	require.NoError(t, err, "storage create error")
+	registry, err := agentkeys.NewRegistry(filepath.Join(dir, "keys.json"))
+	require.NoError(t, err, "registry create error")
+	logger := zap.NewNop().Sugar()
+	router := makeRouter(strg, logger, registry.KeyFunc(nil), nil, nil, nil, storage.NewSelfMetrics(), nil, "token",
+		makeAdminRouter(registry, "token", logger))
+
+	send := func(method, url, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set(authorizationHeader, bearerPrefix+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	assert.Equal(t, http.StatusUnauthorized, send(http.MethodGet, "/admin/keys", "", "").Code, "no token")
+	assert.Equal(t, http.StatusUnauthorized, send(http.MethodGet, "/admin/keys", "bad", "").Code, "bad token")
+
+	w := send(http.MethodPut, "/admin/keys/agent-1", "token", "")
+	require.Equal(t, http.StatusOK, w.Code, "add generated key status")
+	var item agentKey
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &item), "add key response convert error")
+	assert.Equal(t, "agent-1", item.AgentID)
+	assert.NotEmpty(t, item.Key, "generated key")
+	w = send(http.MethodPut, "/admin/keys/agent-2", "token", `{"key":"key2"}`)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &item), "add key response convert error")
+	assert.Equal(t, "key2", item.Key, "key from request")
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPut, "/admin/keys/agent-3", "token", "{").Code)
+
+	w = send(http.MethodGet, "/admin/keys", "token", "")
+	assert.JSONEq(t, `["agent-1","agent-2"]`, w.Body.String(), "agents list")
+
+	assert.Equal(t, http.StatusOK, send(http.MethodDelete, "/admin/keys/agent-2", "token", "").Code, "revoke")
+	assert.Equal(t, http.StatusNotFound, send(http.MethodDelete, "/admin/keys/agent-2", "token", "").Code, "revoke again")
+	assert.Equal(t, []string{"agent-1"}, registry.IDs(), "registry after revoke")
+
+	req := httptest.NewRequest(http.MethodPost, "/update/counter/item/1", nil)
+	req.Header.Set(agentkeys.HeaderAgentID, "agent-2")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "revoked agent request")
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"github.com/gostuding/go-metrics/internal/agentkeys"
+	"github.com/gostuding/go-metrics/internal/logging"
+	"github.com/gostuding/go-metrics/internal/server/audit"
+)
+
+// maxSourceLen is max length of source IP in audit record, like 'source' column of SQL audit.
+const maxSourceLen = 50
+
+// auditRequest is private struct. Contains mutation request's source for audit record.
+type auditRequest struct {
+	auditor audit.Auditor      // audit records writer, audit is disabled if nil
+	logger  *zap.SugaredLogger // logger for write errors
+	source  string             // source IP
+	agent   string             // agent identity
+}
+
+// newHTTPAudit is private func. Source IP is taken from request's RemoteAddr,
+// which is set by middleware.RealIP, agent identity from 'X-Agent-ID' header.
+func newHTTPAudit(auditor audit.Auditor, logger *zap.SugaredLogger, r *http.Request) *auditRequest {
+	return &auditRequest{
+		auditor: auditor,
+		logger:  logger,
+		source:  auditValue(hostOnly(r.RemoteAddr), maxSourceLen),
+		agent:   auditValue(r.Header.Get(agentkeys.HeaderAgentID), agentkeys.MaxAgentIDLen),
+	}
+}
+
+// newRPCAudit is private func. Source IP is taken from peer address, agent identity from 'x-agent-id' metadata.
+func newRPCAudit(ctx context.Context, auditor audit.Auditor, logger *zap.SugaredLogger) *auditRequest {
+	a := auditRequest{auditor: auditor, logger: logger}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		a.source = auditValue(hostOnly(p.Addr.String()), maxSourceLen)
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(agentkeys.MetadataAgentID); len(values) > 0 {
+			a.agent = auditValue(values[0], agentkeys.MaxAgentIDLen)
+		}
+	}
+	return &a
+}
+
+// auditValue is private func. Returns valid UTF-8 value truncated to size bytes.
+// Agent ID and source are not validated by request handlers, but audit record must be written.
+func auditValue(value string, size int) string {
+	value = strings.ToValidUTF8(value, "")
+	if len(value) <= size {
+		return value
+	}
+	for size > 0 && !utf8.RuneStart(value[size]) {
+		size--
+	}
+	return value[:size]
+}
+
+// hostOnly is private func. Returns host from address like 'host:port' or address itself.
+func hostOnly(address string) string {
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		return host
+	}
+	return address
+}
+
+// write is private func. Writes audit record with mutation outcome. Write errors are logged.
+func (a *auditRequest) write(ctx context.Context, action string, count int, err error) {
+	if a.auditor == nil {
+		return
+	}
+	r := audit.Record{
+		Time:      time.Now().UTC(),
+		Action:    action,
+		Source:    a.source,
+		Agent:     a.agent,
+		RequestID: logging.RequestID(ctx),
+		Count:     count,
+		Status:    audit.StatusOK,
+	}
+	if err != nil {
+		r.Status, r.Error = audit.StatusError, err.Error()
+	}
+	if err = a.auditor.Write(ctx, r); err != nil {
+		a.logger.Warnf("write audit record error: %v", err)
+	}
+}
+
+// countMetrics is private func. Returns count of metrics in JSON list, 0 if body is not a list.
+func countMetrics(body []byte) int {
+	var items []json.RawMessage
+	if err := json.Unmarshal(body, &items); err != nil {
+		return 0
+	}
+	return len(items)
+}
+
+// GetAudit is processing audit records request. Filter is set by query values:
+// 'from' and 'to' in RFC3339 format, 'agent', 'action', 'source' and 'limit'.
+func GetAudit(ctx context.Context, auditor audit.Auditor, query url.Values) ([]byte, int, error) {
+	f := audit.Filter{
+		Agent:  query.Get("agent"),
+		Action: query.Get("action"),
+		Source: query.Get("source"),
+	}
+	var err error
+	for name, value := range map[string]*time.Time{"from": &f.From, "to": &f.To} {
+		if v := query.Get(name); v != "" {
+			if *value, err = time.Parse(time.RFC3339, v); err != nil {
+				return nil, http.StatusBadRequest, fmt.Errorf("audit '%s' value error: %w", name, err)
+			}
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		if f.Limit, err = strconv.Atoi(v); err != nil || f.Limit < 0 {
+			return nil, http.StatusBadRequest, fmt.Errorf("audit limit '%s' must be positive integer", v)
+		}
+	}
+	records, err := auditor.Query(ctx, f)
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("get audit records error: %w", err)
+	}
+	data, err := json.Marshal(records)
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("audit records convert error: %w", err)
+	}
+	return data, http.StatusOK, nil
+}
@@ -0,0 +1,145 @@
+// Package audit records metrics mutations: updates, batch updates and storage clearing.
+// Every record contains time, action, source IP, agent identity, metrics count and outcome.
+// Records are written to append-only rotating file as JSON lines or to 'audit' SQL table.
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Audit storage types.
+const (
+	TypeNone = ""     // audit is disabled
+	TypeFile = "file" // JSON lines in rotating file
+	TypeSQL  = "sql"  // 'audit' table in database
+)
+
+// Actions names.
+const (
+	ActionUpdate     = "update"      // one metric update by URL
+	ActionUpdateJSON = "update_json" // one metric update by JSON
+	ActionUpdates    = "updates"     // metrics list update
+	ActionClear      = "clear"       // storage clearing
+)
+
+// Outcomes.
+const (
+	StatusOK    = "ok"    // mutation is applied
+	StatusError = "error" // mutation is failed
+)
+
+// DefaultLimit is max count of records returned by Query if Filter.Limit is not set.
+const DefaultLimit = 100
+
+type (
+	// Record is one mutation.
+	Record struct {
+		Time      time.Time `json:"time"`                 // request time
+		Action    string    `json:"action"`               // action name
+		Source    string    `json:"source"`               // source IP
+		Agent     string    `json:"agent,omitempty"`      // agent identity
+		RequestID string    `json:"request_id,omitempty"` // request ID
+		Count     int       `json:"count"`                // count of metrics in request
+		Status    string    `json:"status"`               // outcome: ok or error
+		Error     string    `json:"error,omitempty"`      // error text
+	}
+
+	// Filter contains Query conditions. Empty values are not checked.
+	Filter struct {
+		From   time.Time // records from time, inclusive
+		To     time.Time // records before time, exclusive
+		Agent  string    // agent identity
+		Action string    // action name
+		Source string    // source IP
+		Limit  int       // max count of the latest records, DefaultLimit if 0
+	}
+
+	// Auditor writes and reads records.
+	Auditor interface {
+		Write(ctx context.Context, r Record) error
+		// Query returns the latest records, which match filter, in time order.
+		Query(ctx context.Context, f Filter) ([]Record, error)
+		Close() error
+	}
+
+	// Options contains audit settings.
+	Options struct {
+		Type     string // audit storage type: file or sql
+		Path     string // file path for file type
+		DSN      string // database connection string for sql type
+		MaxSize  int64  // file max size in bytes, the file is rotated when it is exceeded
+		MaxFiles int    // count of rotated files which are kept
+	}
+)
+
+// Validate checks options.
+func (o Options) Validate() error {
+	switch o.Type {
+	case TypeNone:
+	case TypeFile:
+		if o.Path == "" {
+			return errors.New("audit file path must be set for file audit")
+		}
+		if o.MaxSize < 0 || o.MaxFiles < 0 {
+			return errors.New("audit file limits must not be negative")
+		}
+	case TypeSQL:
+		if o.DSN == "" {
+			return errors.New("database connection string must be set for sql audit")
+		}
+	default:
+		return fmt.Errorf("audit type must be empty, '%s' or '%s'", TypeFile, TypeSQL)
+	}
+	return nil
+}
+
+// New creates Auditor by options. Returns nil if audit is disabled.
+func New(o Options) (Auditor, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	var (
+		a   Auditor
+		err error
+	)
+	switch o.Type {
+	case TypeFile:
+		a, err = NewFileAuditor(o.Path, o.MaxSize, o.MaxFiles)
+	case TypeSQL:
+		a, err = NewSQLAuditor(o.DSN)
+	default:
+		return nil, nil //nolint:all //<-audit is disabled
+	}
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Match checks if record matches filter.
+func (f Filter) Match(r Record) bool {
+	switch {
+	case !f.From.IsZero() && r.Time.Before(f.From):
+		return false
+	case !f.To.IsZero() && !r.Time.Before(f.To):
+		return false
+	case f.Agent != "" && r.Agent != f.Agent:
+		return false
+	case f.Action != "" && r.Action != f.Action:
+		return false
+	case f.Source != "" && r.Source != f.Source:
+		return false
+	}
+	return true
+}
+
+// limit is private func. Returns max count of records.
+func (f Filter) limit() int {
+	if f.Limit <= 0 {
+		return DefaultLimit
+	}
+	return f.Limit
+}
@@ -0,0 +1,93 @@
+package audit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptions_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		o       Options
+		wantErr bool
+	}{
+		{name: "Disabled", o: Options{}},
+		{name: "File", o: Options{Type: TypeFile, Path: "audit.log"}},
+		{name: "File without path", o: Options{Type: TypeFile}, wantErr: true},
+		{name: "File negative size", o: Options{Type: TypeFile, Path: "audit.log", MaxSize: -1}, wantErr: true},
+		{name: "SQL", o: Options{Type: TypeSQL, DSN: "host=localhost"}},
+		{name: "SQL without DSN", o: Options{Type: TypeSQL}, wantErr: true},
+		{name: "Unknown type", o: Options{Type: "kafka"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.o.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Options.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFileAuditor(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "audit", "audit.log")
+	a, err := NewFileAuditor(path, 300, 2)
+	require.NoError(t, err, "create auditor error")
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 6; i++ {
+		r := Record{
+			Time:   start.Add(time.Duration(i) * time.Minute),
+			Action: ActionUpdates,
+			Source: "10.0.0.1",
+			Agent:  "agent-" + string(rune('a'+i%2)),
+			Count:  i,
+			Status: StatusOK,
+		}
+		require.NoError(t, a.Write(ctx, r), "write record error")
+	}
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(fileMode), info.Mode().Perm(), "audit file permissions")
+	_, err = os.Stat(path + ".1")
+	assert.NoError(t, err, "audit file must be rotated")
+
+	records, err := a.Query(ctx, Filter{})
+	require.NoError(t, err)
+	counts := make([]int, 0, len(records))
+	for _, r := range records {
+		counts = append(counts, r.Count)
+	}
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5}, counts, "records must be in time order")
+
+	records, err = a.Query(ctx, Filter{Agent: "agent-b", From: start.Add(2 * time.Minute), Limit: 1})
+	require.NoError(t, err)
+	if assert.Equal(t, 1, len(records), "records count") {
+		assert.Equal(t, 5, records[0].Count, "the latest record must be returned")
+	}
+
+	require.NoError(t, a.Close())
+	assert.Error(t, a.Write(ctx, Record{}), "write to closed auditor")
+	a, err = NewFileAuditor(path, 0, 0)
+	require.NoError(t, err, "reopen auditor error")
+	defer a.Close() //nolint:errcheck //<-senselessly
+	records, err = a.Query(ctx, Filter{To: start.Add(time.Minute)})
+	require.NoError(t, err)
+	assert.Empty(t, records, "rotated files are not read if they are not kept")
+}
+
+func Test_selectArgs(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	query, args := selectArgs(Filter{From: from, Agent: "agent-1", Limit: 10})
+	assert.True(t, strings.HasSuffix(query, " WHERE time >= $1 AND agent = $2 ORDER BY id DESC LIMIT $3;"), query)
+	assert.Equal(t, []any{from, "agent-1", 10}, args)
+	query, args = selectArgs(Filter{})
+	assert.True(t, strings.HasSuffix(query, "FROM audit ORDER BY id DESC LIMIT $1;"), query)
+	assert.Equal(t, []any{DefaultLimit}, args)
+}
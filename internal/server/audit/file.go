@@ -0,0 +1,183 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// File permissions.
+const (
+	fileMode = 0600 // audit file permissions
+	dirMode  = 0700 // audit directory permissions
+)
+
+// FileAuditor writes records as JSON lines to append-only file, which is rotated by size.
+// Rotated files have '.1', '.2' ... suffixes, '.1' is the newest one.
+type FileAuditor struct {
+	file     *os.File   // current file
+	path     string     // current file path
+	maxSize  int64      // file max size in bytes, the file is not rotated if 0
+	maxFiles int        // count of rotated files which are kept
+	size     int64      // current file size
+	mx       sync.Mutex // mutex
+}
+
+// NewFileAuditor creates or opens audit file.
+//
+// Args:
+// path string - audit file path
+// maxSize int64 - file max size in bytes, the file is rotated when it is exceeded
+// maxFiles int - count of rotated files which are kept.
+func NewFileAuditor(path string, maxSize int64, maxFiles int) (*FileAuditor, error) {
+	if err := os.MkdirAll(filepath.Dir(path), dirMode); err != nil {
+		return nil, fmt.Errorf("create audit dir error: %w", err)
+	}
+	a := FileAuditor{path: path, maxSize: maxSize, maxFiles: maxFiles}
+	if err := a.open(); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// open is private func. Opens audit file for appending.
+func (a *FileAuditor) open() error {
+	f, err := os.OpenFile(a.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, fileMode)
+	if err != nil {
+		return fmt.Errorf("open audit file error: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return errors.Join(fmt.Errorf("audit file stat error: %w", err), f.Close())
+	}
+	a.file, a.size = f, info.Size()
+	return nil
+}
+
+// rotate is private func. Renames current file to '.1', previous rotated files are shifted,
+// the oldest file is removed.
+func (a *FileAuditor) rotate() error {
+	if err := a.file.Close(); err != nil {
+		return fmt.Errorf("close audit file error: %w", err)
+	}
+	if a.maxFiles < 1 {
+		if err := os.Remove(a.path); err != nil {
+			return fmt.Errorf("remove audit file error: %w", err)
+		}
+		return a.open()
+	}
+	for i := a.maxFiles - 1; i > 0; i-- {
+		err := os.Rename(a.rotated(i), a.rotated(i+1))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("rotate audit file error: %w", err)
+		}
+	}
+	if err := os.Rename(a.path, a.rotated(1)); err != nil {
+		return fmt.Errorf("rotate audit file error: %w", err)
+	}
+	return a.open()
+}
+
+// rotated is private func. Returns path of rotated file with index i.
+func (a *FileAuditor) rotated(i int) string {
+	return fmt.Sprintf("%s.%d", a.path, i)
+}
+
+// Write writes record as JSON line. The file is rotated before writing if max size is exceeded.
+func (a *FileAuditor) Write(_ context.Context, r Record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("audit record convert error: %w", err)
+	}
+	data = append(data, '\n')
+	a.mx.Lock()
+	defer a.mx.Unlock()
+	if a.file == nil {
+		return errors.New("audit file is closed")
+	}
+	if a.maxSize > 0 && a.size > 0 && a.size+int64(len(data)) > a.maxSize {
+		if err = a.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := a.file.Write(data)
+	a.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("audit file write error: %w", err)
+	}
+	return nil
+}
+
+// Query reads current and rotated files and returns the latest records, which match filter.
+func (a *FileAuditor) Query(ctx context.Context, f Filter) ([]Record, error) {
+	a.mx.Lock()
+	defer a.mx.Unlock()
+	limit := f.limit()
+	records := make([]Record, 0)
+	paths := []string{a.path}
+	for i := 1; i <= a.maxFiles; i++ {
+		paths = append(paths, a.rotated(i))
+	}
+	// files are read from the newest one, so older files are skipped when limit is reached.
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("audit query error: %w", err)
+		}
+		items, err := readRecords(path, f)
+		if err != nil {
+			return nil, err
+		}
+		records = append(items, records...)
+		if len(records) >= limit {
+			return records[len(records)-limit:], nil
+		}
+	}
+	return records, nil
+}
+
+// readRecords is private func. Reads records from file, which match filter. Absent file has no records.
+func readRecords(path string, f Filter) ([]Record, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open audit file error: %w", err)
+	}
+	defer file.Close() //nolint:errcheck //<-senselessly
+	records := make([]Record, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var r Record
+		if err = json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return nil, fmt.Errorf("audit file '%s' record convert error: %w", path, err)
+		}
+		if f.Match(r) {
+			records = append(records, r)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("audit file read error: %w", err)
+	}
+	return records, nil
+}
+
+// Close closes audit file.
+func (a *FileAuditor) Close() error {
+	a.mx.Lock()
+	defer a.mx.Unlock()
+	if a.file == nil {
+		return nil
+	}
+	err := a.file.Close()
+	a.file = nil
+	if err != nil {
+		return fmt.Errorf("close audit file error: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// SQL values.
+const (
+	databaseType = "pgx"           // database driver name
+	createTimout = 3 * time.Second // timeout to create audit table
+	createQuery  = `CREATE TABLE IF NOT EXISTS audit (
+		id bigserial PRIMARY KEY,
+		time timestamptz NOT NULL,
+		action varchar(20) NOT NULL,
+		source varchar(50) NOT NULL,
+		agent varchar(100) NOT NULL,
+		request_id varchar(128) NOT NULL,
+		count integer NOT NULL,
+		status varchar(10) NOT NULL,
+		error text NOT NULL
+	);`
+	insertQuery = `INSERT INTO audit (time, action, source, agent, request_id, count, status, error)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8);`
+	selectQuery = `SELECT time, action, source, agent, request_id, count, status, error FROM audit`
+)
+
+// SQLAuditor writes records to 'audit' table. Records are only inserted.
+type SQLAuditor struct {
+	con *sql.DB
+}
+
+// NewSQLAuditor connects to database and creates 'audit' table if it is absent.
+func NewSQLAuditor(dsn string) (*SQLAuditor, error) {
+	db, err := sql.Open(databaseType, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("connect database error: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), createTimout)
+	defer cancel()
+	if _, err = db.ExecContext(ctx, createQuery); err != nil {
+		return nil, fmt.Errorf("create audit table error: %w", err)
+	}
+	return &SQLAuditor{con: db}, nil
+}
+
+// Write inserts record in 'audit' table.
+func (a *SQLAuditor) Write(ctx context.Context, r Record) error {
+	_, err := a.con.ExecContext(ctx, insertQuery,
+		r.Time, r.Action, r.Source, r.Agent, r.RequestID, r.Count, r.Status, r.Error)
+	if err != nil {
+		return fmt.Errorf("insert audit record error: %w", err)
+	}
+	return nil
+}
+
+// Query selects the latest records, which match filter.
+func (a *SQLAuditor) Query(ctx context.Context, f Filter) ([]Record, error) {
+	query, args := selectArgs(f)
+	rows, err := a.con.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("select audit records error: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck //<-senselessly
+	records := make([]Record, 0)
+	for rows.Next() {
+		var r Record
+		err = rows.Scan(&r.Time, &r.Action, &r.Source, &r.Agent, &r.RequestID, &r.Count, &r.Status, &r.Error)
+		if err != nil {
+			return nil, fmt.Errorf("scan audit record error: %w", err)
+		}
+		records = append(records, r)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("audit records rows error: %w", err)
+	}
+	// records are selected from the newest one.
+	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+		records[i], records[j] = records[j], records[i]
+	}
+	return records, nil
+}
+
+// selectArgs is private func. Returns select query with filter conditions and its args.
+func selectArgs(f Filter) (string, []any) {
+	conditions := make([]string, 0)
+	args := make([]any, 0)
+	add := func(condition string, value any) {
+		args = append(args, value)
+		conditions = append(conditions, condition+" $"+strconv.Itoa(len(args)))
+	}
+	if !f.From.IsZero() {
+		add("time >=", f.From)
+	}
+	if !f.To.IsZero() {
+		add("time <", f.To)
+	}
+	if f.Agent != "" {
+		add("agent =", f.Agent)
+	}
+	if f.Action != "" {
+		add("action =", f.Action)
+	}
+	if f.Source != "" {
+		add("source =", f.Source)
+	}
+	query := selectQuery
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, f.limit())
+	query += " ORDER BY id DESC LIMIT $" + strconv.Itoa(len(args)) + ";"
+	return query, args
+}
+
+// Close closes database connection.
+func (a *SQLAuditor) Close() error {
+	if err := a.con.Close(); err != nil {
+		return fmt.Errorf("close audit database error: %w", err)
+	}
+	return nil
+}
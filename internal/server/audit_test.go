@@ -0,0 +1,84 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/gostuding/go-metrics/internal/agentkeys"
+	"github.com/gostuding/go-metrics/internal/logging"
+	"github.com/gostuding/go-metrics/internal/server/audit"
+	"github.com/gostuding/go-metrics/internal/server/storage"
+)
+
+func TestAuditRouter(t *testing.T) {
+	dir := t.TempDir()
+	strg, err := storage.NewMemStorage(false, filepath.Join(dir, "metrics.json"), 300)
+	require.NoError(t, err, "storage create error")
+	auditor, err := audit.NewFileAuditor(filepath.Join(dir, "audit.log"), 0, 0)
+	require.NoError(t, err, "audit create error")
+	defer auditor.Close() //nolint:errcheck //<-senselessly
+	var keys *agentkeys.Registry
+	router := makeRouter(strg, zap.NewNop().Sugar(), keys.KeyFunc(nil), nil, nil, nil, storage.NewSelfMetrics(), auditor,
+		"token", nil)
+
+	send := func(method, url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("X-Real-IP", "10.0.0.7")
+		req.Header.Set(agentkeys.HeaderAgentID, "agent-1")
+		req.Header.Set(logging.HeaderRequestID, "req-"+method)
+		req.Header.Set(authorizationHeader, bearerPrefix+"token")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	send(http.MethodPost, "/update/counter/item/1", "")
+	send(http.MethodPost, "/updates/", `[{"id":"a","type":"counter","delta":1},{"id":"b","type":"gauge","value":1.5}]`)
+	send(http.MethodPost, "/update/counter/item/abc", "")
+	send(http.MethodGet, "/clear", "")
+
+	req := httptest.NewRequest(http.MethodGet, "/audit", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "audit request without admin token")
+
+	w = send(http.MethodGet, "/audit?agent=agent-1", "")
+	require.Equal(t, http.StatusOK, w.Code, "audit request status")
+	var records []audit.Record
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &records), "audit response convert error")
+	if assert.Equal(t, 4, len(records), "audit records count") {
+		assert.Equal(t, audit.ActionUpdate, records[0].Action)
+		assert.Equal(t, "10.0.0.7", records[0].Source, "source ip")
+		assert.Equal(t, "req-POST", records[0].RequestID, "request id")
+		assert.Equal(t, 2, records[1].Count, "batch metrics count")
+		assert.Equal(t, audit.StatusError, records[2].Status, "failed update status")
+		assert.NotEmpty(t, records[2].Error, "failed update error")
+		assert.Equal(t, audit.ActionClear, records[3].Action)
+		assert.Equal(t, audit.StatusOK, records[3].Status)
+	}
+
+	w = send(http.MethodGet, "/audit?action=clear&limit=x", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code, "incorrect limit")
+	w = send(http.MethodGet, "/audit?from=2000-01-01T00:00:00Z&action=clear", "")
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &records), "audit response convert error")
+	assert.Equal(t, 1, len(records), "filtered records count")
+
+	t.Run("Long agent ID", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/clear", nil)
+		req.Header.Set(agentkeys.HeaderAgentID, strings.Repeat("я", agentkeys.MaxAgentIDLen))
+		router.ServeHTTP(httptest.NewRecorder(), req)
+		records, err := auditor.Query(req.Context(), audit.Filter{Action: audit.ActionClear})
+		require.NoError(t, err, "audit query error")
+		agent := records[len(records)-1].Agent
+		assert.Equal(t, agentkeys.MaxAgentIDLen/2, len([]rune(agent)), "agent ID must be truncated by runes")
+		assert.True(t, utf8.ValidString(agent), "agent ID must be valid UTF-8")
+	})
+}
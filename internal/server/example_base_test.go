@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"time"
 
+	"github.com/gostuding/go-metrics/internal/logging"
 	"github.com/gostuding/go-metrics/internal/server/storage"
 )
 
@@ -17,18 +18,18 @@ var (
 )
 
 func Example() {
-	logger, err := NewLogger()
+	logger, err := NewLogger(logging.Options{})
 	if err != nil {
 		log.Fatalf("logger create error: %v", err)
 	}
 	cfg, err := NewConfig()
 	if err != nil {
-		logger.Warnf("config create error: %w", err)
+		logger.Warnf("config create error: %v", err)
 		return
 	}
 	storage, err := storage.NewMemStorage(restoreStorage, defFileName, saveInterval)
 	if err != nil {
-		logger.Warnf("storage create error: %w", err)
+		logger.Warnf("storage create error: %v", err)
 		return
 	}
 	srv := NewServer(cfg, logger, storage)
@@ -36,7 +37,7 @@ func Example() {
 	go func() {
 		fmt.Println("Run server")
 		if err = srv.RunServer(); err != nil {
-			logger.Warnf("Run server errro: %w", err)
+			logger.Warnf("Run server errro: %v", err)
 		}
 	}()
 	time.Sleep(time.Second)
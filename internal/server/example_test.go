@@ -5,18 +5,19 @@ import (
 	"log"
 	"time"
 
+	"github.com/gostuding/go-metrics/internal/logging"
 	"github.com/gostuding/go-metrics/internal/server/storage"
 )
 
 func ExampleNewServer() {
-	logger, err := NewLogger()
+	logger, err := NewLogger(logging.Options{})
 	if err != nil {
 		log.Fatalf("logger create error: %v", err)
 	}
 	cfg := &Config{IPAddress: defaultAddress, StoreInterval: saveInterval}
 	storage, err := storage.NewMemStorage(restoreStorage, defFileName, saveInterval)
 	if err != nil {
-		logger.Warnf("storage create error: %w", err)
+		logger.Warnf("storage create error: %v", err)
 		return
 	}
 	srv := NewServer(cfg, logger, storage)
@@ -39,7 +40,7 @@ func ExampleNewConfig() {
 }
 
 func ExampleNewLogger() {
-	logger, err := NewLogger()
+	logger, err := NewLogger(logging.Options{Format: logging.FormatJSON})
 	if err != nil {
 		fmt.Printf("create logger error: %v", err)
 	} else {
@@ -51,7 +52,7 @@ func ExampleNewLogger() {
 }
 
 func ExampleServer_RunServer() {
-	logger, err := NewLogger()
+	logger, err := NewLogger(logging.Options{})
 	if err != nil {
 		log.Fatalf("logger create error: %v", err)
 	}
@@ -61,7 +62,7 @@ func ExampleServer_RunServer() {
 	}
 	storage, err := storage.NewMemStorage(cfg.Restore, cfg.FileStorePath, cfg.StoreInterval)
 	if err != nil {
-		logger.Warnf("storage create error: %w", err)
+		logger.Warnf("storage create error: %v", err)
 		return
 	}
 	srv := NewServer(cfg, logger, storage)
@@ -71,7 +72,7 @@ func ExampleServer_RunServer() {
 		fmt.Println("Run server success")
 		if err = srv.RunServer(); err != nil {
 			fmt.Printf("Run error: %v", err)
-			logger.Warnf("Run server errro: %w", err)
+			logger.Warnf("Run server errro: %v", err)
 		}
 	}()
 	time.Sleep(time.Second)
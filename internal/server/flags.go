@@ -2,203 +2,282 @@ package server
 
 import (
 	"crypto/rsa"
-	"crypto/x509"
-	"encoding/json"
-	"encoding/pem"
 	"errors"
 	"flag"
 	"fmt"
+	"net"
 	"os"
-	"strconv"
+	"path/filepath"
 	"strings"
 
-	"path/filepath"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/gostuding/go-metrics/internal/config"
+	"github.com/gostuding/go-metrics/internal/logging"
+	"github.com/gostuding/go-metrics/internal/rsakeys"
+	"github.com/gostuding/go-metrics/internal/server/audit"
+	"github.com/gostuding/go-metrics/internal/signing"
+	"github.com/gostuding/go-metrics/internal/tracing"
 )
 
 // Defaulf constans for Config.
 const (
 	defaultAddress       = ":8080"           // Server address
 	defaultFileName      = "metrics-db.json" // MemStorage file name
-	defaultKey           = "default"         // Key for hash
 	defaultStoreInterval = 300               // Save MemStore interval
-	falseString          = "false"
+	defaultLogLevel      = "debug"           // Logger level
+	defaultAuditMaxSize  = 10 << 20          // Audit file max size in bytes
+	defaultAuditMaxFiles = 5                 // Count of rotated audit files
 )
 
 // Config is struct, which contains server options.
-type (
-	Config struct {
-		PrivateKey      *rsa.PrivateKey `json:"-"`                        // rsa private key
-		PrivateKeyPath  string          `json:"crypto_key,omitempty"`     //
-		IPAddress       string          `json:"address,omitempty"`        // server addres in format 'ip:port'.
-		FileStorePath   string          `json:"store_file,omitempty"`     // file path if used memory storage type.
-		ConnectDBString string          `json:"database_dsn,omitempty"`   // database connection string.
-		resString       string          `json:"-"`                        //
-		Key             string          `json:"key,omitempty"`            // key for requests hash check.
-		TrustedSubnet   string          `json:"trusted_subnet"`           // trusted subnet for agents
-		StoreInterval   int             `json:"store_interval,omitempty"` // save storage interval.
-		Restore         bool            `json:"restore,omitempty"`        // restore mem storage flag.
-		SendByRPC       bool            `json:"-"`                        //
-	}
-	// Internal struct.
-	keysStruct struct {
-		HashKey        string
-		PrivateKeyPath string
-	}
-)
+type Config struct {
+	PrivateKeys     []*rsa.PrivateKey `json:"-"`                                                       // rsa private keys, current is first
+	PrivateKeyPath  string            `json:"crypto_key,omitempty" env:"CRYPTO_KEY"`                   // comma separated paths to rsa private keys
+	IPAddress       string            `json:"address,omitempty" env:"ADDRESS"`                         // server addres in format 'ip:port'.
+	FileStorePath   string            `json:"store_file,omitempty" env:"FILE_STORAGE_PATH"`            // file path if used memory storage type.
+	ConnectDBString string            `json:"database_dsn,omitempty" env:"DATABASE_DSN" secret:"true"` // database connection string.
+	Key             string            `json:"key,omitempty" env:"KEY" secret:"true"`                   // key for requests hash check.
+	HashScheme      string            `json:"hash_scheme,omitempty" env:"HASH_SCHEME"`                 // requests signature scheme: nonce, body or any
+	HashSkew        int               `json:"hash_skew,omitempty" env:"HASH_SKEW"`                     // clock skew window in seconds
+	TrustedSubnet   string            `json:"trusted_subnet" env:"TRUSTED_SUBNET"`                     // trusted subnet for agents
+	LogLevel        string            `json:"log_level,omitempty" env:"LOG_LEVEL"`                     // logger level: debug, info, warn or error.
+	LogFormat       string            `json:"log_format,omitempty" env:"LOG_FORMAT"`                   // logger format: console or json.
+	LogFile         string            `json:"log_file,omitempty" env:"LOG_FILE"`                       // logger output file, stderr if empty.
+	StoreInterval   int               `json:"store_interval" env:"STORE_INTERVAL"`                     // save storage interval.
+	Restore         bool              `json:"restore" env:"RESTORE"`                                   // restore mem storage flag.
+	TraceExporter   string            `json:"trace_exporter,omitempty" env:"TRACE_EXPORTER"`           // spans exporter: stdout or otlp
+	TraceEndpoint   string            `json:"trace_endpoint,omitempty" env:"TRACE_ENDPOINT"`           // OTLP collector address
+	TraceInsecure   bool              `json:"trace_insecure,omitempty" env:"TRACE_INSECURE"`           // flag to use OTLP without TLS
+	AuditType       string            `json:"audit,omitempty" env:"AUDIT"`                             // audit storage: file or sql
+	AuditPath       string            `json:"audit_path,omitempty" env:"AUDIT_PATH"`                   // audit file path
+	AuditMaxSize    int64             `json:"audit_max_size,omitempty" env:"AUDIT_MAX_SIZE"`           // audit file max size in bytes
+	AuditMaxFiles   int               `json:"audit_max_files,omitempty" env:"AUDIT_MAX_FILES"`         // count of rotated audit files
+	AgentKeysPath   string            `json:"agent_keys,omitempty" env:"AGENT_KEYS"`                   // agents keys registry file
+	AdminToken      string            `json:"admin_token,omitempty" env:"ADMIN_TOKEN" secret:"true"`   // token for admin API
+	SendByRPC       bool              `json:"-"`                                                       //
+	args            []string          `json:"-"`                                                       // startup variables, used for reload config
+}
 
 // SetDefault values for Config.
 func (c *Config) setDefault() {
-	if c.IPAddress == "" {
-		c.IPAddress = defaultAddress
+	c.IPAddress = defaultAddress
+	c.FileStorePath = filepath.Join(os.TempDir(), defaultFileName)
+	c.StoreInterval = defaultStoreInterval
+	c.Restore = true
+	c.LogLevel = defaultLogLevel
+	c.HashScheme = signing.SchemeNonce
+	c.HashSkew = signing.DefaultSkew
+	c.AuditMaxSize = defaultAuditMaxSize
+	c.AuditMaxFiles = defaultAuditMaxFiles
+}
+
+// Validate checks Config's values. All found errors are returned together.
+func (c *Config) Validate() error {
+	errs := make([]error, 0)
+	if _, _, err := net.SplitHostPort(c.IPAddress); err != nil {
+		errs = append(errs, fmt.Errorf("address ('%s') incorrect: %w", c.IPAddress, err))
 	}
-	if c.FileStorePath == "" {
-		c.FileStorePath = filepath.Join(os.TempDir(), defaultFileName)
+	if c.StoreInterval < 0 {
+		errs = append(errs, errors.New("store interval must not be negative"))
 	}
-	if c.Key == "" {
-		c.Key = defaultKey
+	if _, err := parseSubnet(c.TrustedSubnet); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := zapcore.ParseLevel(c.LogLevel); err != nil {
+		errs = append(errs, fmt.Errorf("logger level error: %w", err))
+	} else if err = c.Logging().Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.Tracing().Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.Audit().Validate(); err != nil {
+		errs = append(errs, err)
 	}
-	if c.StoreInterval == 0 {
-		c.StoreInterval = defaultStoreInterval
+	if err := c.Signing().Validate(); err != nil {
+		errs = append(errs, err)
 	}
-	if c.resString != falseString {
-		c.Restore = true
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("config error: %w", err)
 	}
+	return nil
 }
 
-// Private func for get Enviroment values.
-func stringEnvCheck(val string, name string) string {
-	v, ok := os.LookupEnv(name)
-	if ok {
-		return v
-	}
-	return val
+// Logging returns logger options.
+func (c *Config) Logging() logging.Options {
+	return logging.Options{Level: c.LogLevel, Format: c.LogFormat, File: c.LogFile}
 }
 
-// parcePrivateKey reads rsa private key from file.
-func parcePrivateKey(filePath string) (*rsa.PrivateKey, error) {
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("file read error: %w", err)
-	}
-	block, _ := pem.Decode([]byte(data)) //nolint:all //<-senselessly
-	if block == nil {
-		return nil, errors.New("failed to parse PEM block with private key")
+// Audit returns audit options. SQL audit uses storage database.
+func (c *Config) Audit() audit.Options {
+	return audit.Options{
+		Type:     c.AuditType,
+		Path:     c.AuditPath,
+		DSN:      c.ConnectDBString,
+		MaxSize:  c.AuditMaxSize,
+		MaxFiles: c.AuditMaxFiles,
 	}
-	pKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
-	if err != nil {
-		return nil, fmt.Errorf("parce private key error: %w", err)
+}
+
+// Signing returns requests signature options.
+func (c *Config) Signing() signing.Options {
+	return signing.Options{Scheme: c.HashScheme, Skew: c.HashSkew}
+}
+
+// Tracing returns tracing options.
+func (c *Config) Tracing() tracing.Options {
+	return tracing.Options{Exporter: c.TraceExporter, Endpoint: c.TraceEndpoint, Insecure: c.TraceInsecure}
+}
+
+// hashKey is private func. Returns nil if key is empty, so requests hash is not checked.
+func (c *Config) hashKey() []byte {
+	if c.Key == "" {
+		return nil
 	}
-	return pKey, nil
+	return []byte(c.Key)
 }
 
-// lookEnviroment gets options from Enviroment.
-func lookEnviroment(cfg *Config, keys *keysStruct) error {
-	if val, ok := os.LookupEnv("STORE_INTERVAL"); ok {
-		interval, err := strconv.Atoi(val)
-		if err != nil {
-			return fmt.Errorf("STORE INTERVAL enviroment incorrect: %w", err)
-		}
-		cfg.StoreInterval = interval
-	}
-	cfg.IPAddress = stringEnvCheck(cfg.IPAddress, "ADDRESS")
-	cfg.FileStorePath = stringEnvCheck(cfg.FileStorePath, "FILE_STORAGE_PATH")
-	cfg.ConnectDBString = stringEnvCheck(cfg.ConnectDBString, "DATABASE_DSN")
-	keys.HashKey = stringEnvCheck(keys.HashKey, "KEY")
-	if keys.HashKey != "" {
-		cfg.Key = keys.HashKey
-	}
-	val := strings.ToLower(os.Getenv("RESTORE"))
-	switch val {
-	case "true":
-		cfg.Restore = true
-	case "false":
-		cfg.Restore = false
-	default:
-		if val != "" {
-			return fmt.Errorf("enviroment RESTORE error. Use 'true' or 'false' value instead of '%s'", val)
+// parcePrivateKeys reads rsa private keys from comma separated files paths.
+func parcePrivateKeys(paths string) ([]*rsa.PrivateKey, error) {
+	keys := make([]*rsa.PrivateKey, 0)
+	for _, path := range strings.Split(paths, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
 		}
-	}
-	keys.PrivateKeyPath = stringEnvCheck(keys.PrivateKeyPath, "CRYPTO_KEY")
-	if keys.PrivateKeyPath != "" {
-		key, err := parcePrivateKey(keys.PrivateKeyPath)
+		key, err := rsakeys.ReadPrivate(path)
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("private key '%s' error: %w", path, err)
 		}
-		cfg.PrivateKey = key
+		keys = append(keys, key)
 	}
-	cfg.TrustedSubnet = stringEnvCheck(cfg.TrustedSubnet, "TRUSTED_SUBNET")
-	return nil
+	return keys, nil
 }
 
-// lookFileConfig gets options from json file.
-func lookFileConfig(path string, cfg *Config, keys *keysStruct) error {
-	defer cfg.setDefault()
-	if val, ok := os.LookupEnv("CONFIG"); ok {
-		path = val
+// NewConfig reads startup parameters, runtime environment variables and config file.
+// Returns Config object with server options.
+// Values priority: startup parameters > environment > config file > defaults.
+// Config file path is set by '-c' ('-config') startup parameter or CONFIG enviroment,
+// file format is JSON, YAML or TOML by file extension.
+// '-print-config' startup parameter prints effective config with redacted secrets,
+// in this case config.ErrPrinted is returned.
+//
+// Enviroment values:
+//
+//	ADDRESS - server address in format ip:port
+//	STORE_INTERVAL - save storage interval in seconds, 0 for saving on every update
+//	FILE_STORAGE_PATH - file for memory storage
+//	RESTORE - restore memory storage on start: 'true' or 'false'
+//	DATABASE_DSN - database connection string, memory storage is used if empty
+//	KEY - key for requests hash check, hash is not checked if empty
+//	HASH_SCHEME - requests signature: 'nonce' (default) - hash of timestamp, nonce and body,
+//	'body' - hash of body only for legacy agents, 'any' - both schemes are accepted
+//	HASH_SKEW - clock skew window in seconds for 'nonce' signature, 300 by default
+//	AGENT_KEYS - JSON file with agents keys like {"agent-id": "key"}, only KEY is used if empty
+//	ADMIN_TOKEN - token for admin API to add and revoke agents keys, admin API is disabled if empty
+//	CRYPTO_KEY - comma separated paths to RSA private keys, the first is current,
+//	others are previous keys accepted while agents migrate to the new public key
+//	TRUSTED_SUBNET - agents subnet in CIDR format
+//	LOG_LEVEL - logger level: debug, info, warn or error
+//	LOG_FORMAT - logger format: 'console' (default) or 'json'
+//	LOG_FILE - logger output file, stderr if empty
+//	AUDIT - audit of metrics mutations: 'file' or 'sql' ('audit' table in DATABASE_DSN), disabled if empty
+//	AUDIT_PATH - audit file path for 'file' audit
+//	AUDIT_MAX_SIZE - audit file max size in bytes, the file is rotated when it is exceeded
+//	AUDIT_MAX_FILES - count of rotated audit files
+//	TRACE_EXPORTER - spans exporter: 'stdout' or 'otlp', spans are not exported if empty
+//	TRACE_ENDPOINT - OTLP gRPC collector address like 'localhost:4317'
+//	TRACE_INSECURE - 'true' to connect to OTLP collector without TLS
+func NewConfig() (*Config, error) {
+	args := os.Args[1:]
+	if flag.Parsed() {
+		// Command line was parsed by caller, for example by tests.
+		args = flag.Args()
 	}
-	if path == "" {
-		return nil
+	return LoadConfig(args)
+}
+
+// LoadConfig creates Config object from args, environment and config file.
+func LoadConfig(args []string) (*Config, error) {
+	cfg := Config{args: args}
+	cfg.setDefault()
+	loader := config.NewLoader("server")
+	fs := loader.Flags
+	fs.StringVar(&cfg.IPAddress, "a", cfg.IPAddress, "address and port to run server like address:port")
+	fs.IntVar(&cfg.StoreInterval, "i", cfg.StoreInterval, "store interval in seconds")
+	fs.StringVar(&cfg.FileStorePath, "f", cfg.FileStorePath, "file path for save the storage")
+	loader.Var(&cfg.Restore, "r", "restore storage on start server (true or false)")
+	fs.StringVar(&cfg.ConnectDBString, "d", cfg.ConnectDBString, "database connect string")
+	fs.StringVar(&cfg.TrustedSubnet, "t", cfg.TrustedSubnet, "trusted subnet")
+	fs.StringVar(&cfg.Key, "k", cfg.Key, "Key for SHA256 checks")
+	fs.StringVar(&cfg.HashScheme, "hash-scheme", cfg.HashScheme, "requests signature scheme: nonce, body or any")
+	fs.IntVar(&cfg.HashSkew, "hash-skew", cfg.HashSkew, "clock skew window in seconds for nonce signature")
+	fs.StringVar(&cfg.AgentKeysPath, "agent-keys", cfg.AgentKeysPath, "JSON file with agents keys")
+	fs.StringVar(&cfg.AdminToken, "admin-token", cfg.AdminToken, "token for admin API")
+	fs.StringVar(&cfg.PrivateKeyPath, "crypto-key", cfg.PrivateKeyPath, "comma separated paths to RSA private keys, current is first")
+	fs.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "logger level: debug, info, warn or error")
+	fs.StringVar(&cfg.LogFormat, "log-format", cfg.LogFormat, "logger format: console or json")
+	fs.StringVar(&cfg.LogFile, "log-file", cfg.LogFile, "logger output file, stderr if empty")
+	fs.StringVar(&cfg.TraceExporter, "trace", cfg.TraceExporter, "spans exporter: stdout or otlp")
+	fs.StringVar(&cfg.TraceEndpoint, "trace-endpoint", cfg.TraceEndpoint, "OTLP collector address like host:4317")
+	fs.BoolVar(&cfg.TraceInsecure, "trace-insecure", cfg.TraceInsecure, "connect to OTLP collector without TLS")
+	fs.StringVar(&cfg.AuditType, "audit", cfg.AuditType, "audit of metrics mutations: file or sql")
+	fs.StringVar(&cfg.AuditPath, "audit-path", cfg.AuditPath, "audit file path")
+	fs.Int64Var(&cfg.AuditMaxSize, "audit-size", cfg.AuditMaxSize, "audit file max size in bytes")
+	fs.IntVar(&cfg.AuditMaxFiles, "audit-files", cfg.AuditMaxFiles, "count of rotated audit files")
+	fs.BoolVar(&cfg.SendByRPC, "rpc", cfg.SendByRPC, "Use RPC for get data from agents. Sets only by this arg")
+	if err := loader.Load(&cfg, args); err != nil {
+		return nil, err //nolint:wrapcheck //<-senselessly
 	}
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return fmt.Errorf("config file read error: %w", err)
+	if cfg.PrivateKeyPath != "" {
+		keys, err := parcePrivateKeys(cfg.PrivateKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		cfg.PrivateKeys = keys
 	}
-	c := Config{Restore: true}
-	err = json.Unmarshal(data, &c)
-	if err != nil {
-		return fmt.Errorf("config file convert error: %w", err)
+	return &cfg, nil
+}
+
+// Reload reads config file and enviroment again with the same startup parameters.
+// Returns new Config object, c is not changed.
+func (c *Config) Reload() (*Config, error) {
+	return LoadConfig(c.args)
+}
+
+// restartChanges is private func. Returns names of changed options, which can't be applied without restart.
+func (c *Config) restartChanges(n *Config) []string {
+	var names []string
+	if c.IPAddress != n.IPAddress {
+		names = append(names, "address")
 	}
-	if cfg.IPAddress == "" {
-		cfg.IPAddress = c.IPAddress
+	if c.FileStorePath != n.FileStorePath {
+		names = append(names, "store_file")
 	}
-	if cfg.ConnectDBString == "" {
-		cfg.ConnectDBString = c.ConnectDBString
+	if c.ConnectDBString != n.ConnectDBString {
+		names = append(names, "database_dsn")
 	}
-	if cfg.StoreInterval == 0 {
-		cfg.StoreInterval = c.StoreInterval
+	if c.Restore != n.Restore {
+		names = append(names, "restore")
 	}
-	if cfg.FileStorePath == "" {
-		cfg.FileStorePath = c.FileStorePath
+	if c.StoreInterval != n.StoreInterval && (c.StoreInterval < 1 || n.StoreInterval < 1) {
+		names = append(names, "store_interval")
 	}
-	if cfg.TrustedSubnet == "" {
-		cfg.TrustedSubnet = c.TrustedSubnet
+	if c.Tracing() != n.Tracing() {
+		names = append(names, "trace")
 	}
-	if cfg.resString == "" && !c.Restore {
-		cfg.resString = falseString
+	if c.LogFormat != n.LogFormat {
+		names = append(names, "log_format")
 	}
-	if keys.HashKey == "" {
-		keys.HashKey = c.Key
+	if c.LogFile != n.LogFile {
+		names = append(names, "log_file")
 	}
-	if keys.PrivateKeyPath == "" {
-		keys.PrivateKeyPath = c.PrivateKeyPath
+	if c.Audit() != n.Audit() {
+		names = append(names, "audit")
 	}
-	return nil
-}
-
-// NewConfig reads startup parameters and runtime environment variables.
-// Returns Config object with server options.
-func NewConfig() (*Config, error) {
-	cfg := Config{}
-	keys := keysStruct{}
-	var cfgFilePath string
-	if !flag.Parsed() {
-		flag.StringVar(&cfg.IPAddress, "a", "", "address and port to run server like address:port")
-		flag.IntVar(&cfg.StoreInterval, "i", 0, "store interval in seconds")
-		flag.StringVar(&cfg.FileStorePath, "f", "", "file path for save the storage")
-		flag.StringVar(&cfg.resString, "r", "", "restore storage on start server (true or false)")
-		flag.StringVar(&cfg.ConnectDBString, "d", "", "database connect string")
-		flag.StringVar(&cfg.TrustedSubnet, "t", "", "trusted subnet")
-		flag.StringVar(&keys.HashKey, "k", "", "Key for SHA256 checks")
-		flag.StringVar(&keys.PrivateKeyPath, "crypto-key", "", "path to file with RSA private key")
-		flag.StringVar(&cfgFilePath, "c", "", "path to file with config for server")
-		flag.BoolVar(&cfg.SendByRPC, "rpc", cfg.SendByRPC, "Use RPC for get data from agents. Sets only by this arg")
-		flag.Parse()
-	}
-	if err := lookFileConfig(cfgFilePath, &cfg, &keys); err != nil {
-		return nil, err
-	}
-	if err := lookEnviroment(&cfg, &keys); err != nil {
-		return nil, err
+	if c.AgentKeysPath != n.AgentKeysPath {
+		names = append(names, "agent_keys")
 	}
-	return &cfg, nil
+	return names
 }
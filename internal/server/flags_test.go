@@ -0,0 +1,31 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gostuding/go-metrics/internal/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	cfg, err := LoadConfig([]string{"-r", "false", "-i", "0"})
+	require.NoError(t, err)
+	assert.Empty(t, cfg.Key, "key must be empty by default")
+	assert.Nil(t, cfg.hashKey(), "empty key must not be used for hash")
+	assert.False(t, cfg.Restore)
+	assert.Equal(t, 0, cfg.StoreInterval, "zero store interval must not be replaced by default")
+
+	_, err = LoadConfig([]string{"-a", "host", "-t", "10.0.0.1", "-log-level", "verbose"})
+	require.Error(t, err)
+	for _, want := range []string{"address", "subnet", "logger level"} {
+		assert.True(t, strings.Contains(err.Error(), want), "error must contain '%s': %v", want, err)
+	}
+
+	cfg, err = LoadConfig([]string{"-log-format", "json", "-log-file", "server.log"})
+	require.NoError(t, err)
+	assert.Equal(t, logging.Options{Level: defaultLogLevel, Format: logging.FormatJSON, File: "server.log"}, cfg.Logging())
+	_, err = LoadConfig([]string{"-log-format", "xml"})
+	assert.ErrorContains(t, err, "log format")
+}
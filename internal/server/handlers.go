@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/gostuding/go-metrics/internal/logging"
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5/pgconn"
 )
@@ -29,6 +30,7 @@ type (
 		GetMetric(context.Context, string, string) (string, error)
 		GetMetricJSON(context.Context, []byte) ([]byte, error)
 		GetMetricsHTML(context.Context) (string, error)
+		GetMetricsPrometheus(context.Context) (string, error)
 	}
 
 	// StorageDB is additions storage work interface.
@@ -83,10 +85,13 @@ func getError(errType any, values ...any) error {
 	}
 }
 
-// Private func.
-func isRepeat(err error, t *int) bool {
+// Private func. Request logger from ctx writes retries.
+func isRepeat(ctx context.Context, err error, t *int) bool {
 	var pgErr *pgconn.PgError
 	if errors.As(err, &pgErr) && pgerrcode.IsConnectionException(pgErr.Code) {
+		if log := logging.FromContext(ctx, nil); log != nil {
+			log.Warnf("storage connection error, retry after %d sec.: %v", *t, err)
+		}
 		time.Sleep(time.Duration(*t) * time.Second)
 		*t += 2
 	} else {
@@ -105,9 +110,10 @@ func bytesErrorRepeater(ctx context.Context, f fbe, data []byte) ([]byte, error)
 			case <-ctx.Done():
 				return nil, errors.New("context done error")
 			default:
-				if !isRepeat(err, &waitTime) {
+				if !isRepeat(ctx, err, &waitTime) {
 					return value, err
 				}
+				repeaterRetries.Add(1)
 				value, err = f(ctx, data)
 				if err == nil {
 					return value, nil
@@ -128,7 +134,7 @@ func seRepeater(ctx context.Context, f fse) (string, error) {
 			case <-ctx.Done():
 				return "", getError(contextErrType, ctx.Err())
 			default:
-				if !isRepeat(err, &waitTime) {
+				if !isRepeat(ctx, err, &waitTime) {
 					return value, err
 				}
 				value, err = f(ctx)
@@ -151,7 +157,7 @@ func sseRepeater(ctx context.Context, f fsse, t string, n string) (string, error
 			case <-ctx.Done():
 				return "", getError(contextErrType, ctx.Err())
 			default:
-				if !isRepeat(err, &waitTime) {
+				if !isRepeat(ctx, err, &waitTime) {
 					return value, err
 				}
 				value, err = f(ctx, t, n)
@@ -174,7 +180,7 @@ func ssseRepeater(ctx context.Context, f fssse, t string, n string, v string) er
 			case <-ctx.Done():
 				return getError(contextErrType, ctx.Err())
 			default:
-				if !isRepeat(err, &waitTime) {
+				if !isRepeat(ctx, err, &waitTime) {
 					return err
 				}
 				err = f(ctx, t, n, v)
@@ -225,6 +231,18 @@ func GetAllMetrics(
 	return []byte(body), nil
 }
 
+// GetAllMetricsPrometheus is processing an get all metrics in Prometheus text format request.
+func GetAllMetricsPrometheus(
+	ctx context.Context,
+	storage StorageGetter,
+) ([]byte, error) {
+	body, err := seRepeater(ctx, storage.GetMetricsPrometheus)
+	if err != nil {
+		return nil, fmt.Errorf("get metrics in storage error: %w", err)
+	}
+	return []byte(body), nil
+}
+
 // UpdateJSON is processing an update metric by JSON request.
 func UpdateJSON(
 	ctx context.Context,
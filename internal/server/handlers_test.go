@@ -377,7 +377,7 @@ func Test_isRepeat(t *testing.T) {
 	for _, tt := range tests {
 		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
-			if got := isRepeat(tt.err, &val); got != tt.want {
+			if got := isRepeat(context.Background(), tt.err, &val); got != tt.want {
 				t.Errorf("isRepeat() = %v, want %v", got, tt.want)
 			}
 		})
@@ -60,14 +60,27 @@ func decript(key *rsa.PrivateKey, msg []byte) ([]byte, error) {
 	return dectipted, nil
 }
 
-func DecriptInterceptor(key *rsa.PrivateKey) grpc.UnaryServerInterceptor {
+// decriptByKeys is private func. Decripts message by the first suitable key.
+func decriptByKeys(keys []*rsa.PrivateKey, msg []byte) ([]byte, error) {
+	err := errors.New("private keys are not set")
+	for _, key := range keys {
+		var data []byte
+		if data, err = decript(key, msg); err == nil {
+			return data, nil
+		}
+	}
+	return nil, err
+}
+
+// DecriptInterceptor decripts metrics requests. Keys are tried in order, so the current key should be the first.
+func DecriptInterceptor(keys []*rsa.PrivateKey) grpc.UnaryServerInterceptor {
 	return func(
 		ctx context.Context,
 		req interface{},
 		info *grpc.UnaryServerInfo,
 		handler grpc.UnaryHandler,
 	) (interface{}, error) {
-		if key == nil {
+		if len(keys) == 0 {
 			return handler(ctx, req)
 		}
 		var err error
@@ -75,7 +88,7 @@ func DecriptInterceptor(key *rsa.PrivateKey) grpc.UnaryServerInterceptor {
 		if !ok {
 			return nil, status.Error(codes.FailedPrecondition, makeError(NotByteError, nil).Error()) //nolint:wrapcheck //<-
 		}
-		data.Metrics, err = decript(key, data.Metrics)
+		data.Metrics, err = decriptByKeys(keys, data.Metrics)
 		if err != nil {
 			return nil, status.Error(codes.FailedPrecondition, makeError(DecriptError, err).Error()) //nolint:wrapcheck //<-
 		}
@@ -2,11 +2,11 @@ package interseptors
 
 import (
 	"context"
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
+	"errors"
 
+	"github.com/gostuding/go-metrics/internal/agentkeys"
 	pb "github.com/gostuding/go-metrics/internal/proto"
+	"github.com/gostuding/go-metrics/internal/signing"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
@@ -17,47 +17,53 @@ const (
 	hashVarName = "HashSHA256" // Header name for hash check.
 )
 
-func checkHash(data, key []byte, hash string) error {
-	if len(data) > 0 && hash != "" {
-		h := hmac.New(sha256.New, key)
-		_, err := h.Write(data)
-		if err != nil {
-			return makeError(WriteError, err)
-		}
-		if hash != hex.EncodeToString(h.Sum(nil)) {
-			return makeError(HashIncorrectError, hash)
-		}
-	}
-	return nil
-}
-
-func HashInterceptor(key []byte) grpc.UnaryServerInterceptor {
+// HashInterceptor checks hash summ for request metrics. Key is selected by agent ID from "x-agent-id" metadata.
+// Hash is not checked if key is nil, requests are rejected if key is not found.
+// Timestamp and nonce are taken from "x-timestamp" and "x-nonce" metadata and are checked by verifier,
+// nil verifier checks only metrics hash.
+func HashInterceptor(keys agentkeys.KeyFunc, verifier *signing.Verifier) grpc.UnaryServerInterceptor {
 	return func(
 		ctx context.Context,
 		req interface{},
 		info *grpc.UnaryServerInfo,
 		handler grpc.UnaryHandler,
 	) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		agentID := firstValue(md, agentkeys.MetadataAgentID)
+		key, err := keys(agentID)
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error()) //nolint:wrapcheck //<-
+		}
 		if key == nil {
 			return handler(ctx, req)
 		}
-		md, ok := metadata.FromIncomingContext(ctx)
-		if !ok {
-			return nil, status.Error(codes.Internal, "get value from context error") //nolint:wrapcheck //<-
+		sign := signing.Signature{
+			Hash:      firstValue(md, hashVarName),
+			Timestamp: firstValue(md, signing.MetadataTimestamp),
+			Nonce:     firstValue(md, signing.MetadataNonce),
 		}
-		values := md.Get(hashVarName)
-		if len(values) == 0 {
+		if sign.Hash == "" {
 			return nil, status.Error(codes.InvalidArgument, "hash undefined") //nolint:wrapcheck //<-
 		}
-		var err error
 		data, ok := req.(*pb.MetricsRequest)
 		if !ok {
 			return nil, status.Error(codes.Canceled, makeError(NotByteError, nil).Error()) //nolint:wrapcheck //<-
 		}
-		err = checkHash(data.Metrics, key, values[0])
-		if err != nil {
+		err = verifier.Verify(key, data.Metrics, sign)
+		switch {
+		case errors.Is(err, signing.ErrBadHash):
 			return nil, status.Error(codes.Aborted, "incorrect hash") //nolint:wrapcheck //<-
+		case err != nil:
+			return nil, status.Error(codes.InvalidArgument, err.Error()) //nolint:wrapcheck //<-
 		}
 		return handler(ctx, data)
 	}
 }
+
+// firstValue is private func. Returns the first metadata value or empty string.
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -4,17 +4,42 @@ import (
 	"context"
 	"time"
 
+	"github.com/gostuding/go-metrics/internal/logging"
 	pb "github.com/gostuding/go-metrics/internal/proto"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
+// RequestIDInterceptor takes request ID from 'x-request-id' metadata or generates new one.
+// Request ID is sent in response header metadata and stored in context with logger,
+// which adds 'request_id' field to all lines. Use logging.FromContext to get the logger.
+func RequestIDInterceptor(logger *zap.SugaredLogger) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		id := ""
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(logging.MetadataRequestID); len(values) > 0 {
+				id = values[0]
+			}
+		}
+		if !logging.ValidRequestID(id) {
+			id = logging.NewRequestID()
+		}
+		if err := grpc.SetHeader(ctx, metadata.Pairs(logging.MetadataRequestID, id)); err != nil {
+			logger.Debugf("set request id header error: %v", err)
+		}
+		return handler(logging.WithLogger(ctx, logger, id), req)
+	}
+}
+
+// LogInterceptor writes in logger one line for request: method, request size, answer and duration.
 func LogInterceptor(logger *zap.SugaredLogger) grpc.UnaryServerInterceptor {
-	var (
-		urlString  = "url"
-		respLogger = "Response logger"
-		answer     = "answer"
-	)
 	return func(
 		ctx context.Context,
 		req interface{},
@@ -26,34 +51,21 @@ func LogInterceptor(logger *zap.SugaredLogger) grpc.UnaryServerInterceptor {
 		if v, ok := req.(*pb.MetricsRequest); ok {
 			reqSize = len(v.Metrics)
 		}
-		logger.Infow(
-			"Request logger",
-			urlString, info.FullMethod,
-			"duration", time.Since(start),
-			"size", reqSize,
-		)
 		resp, err := handler(ctx, req)
+		answer := "undefined type"
 		if err != nil {
-			logger.Infow(
-				respLogger,
-				urlString, info.FullMethod,
-				"error", err.Error(),
-			)
-		} else {
-			if v, ok := resp.(*pb.MetricsResponse); ok {
-				logger.Infow(
-					respLogger,
-					urlString, info.FullMethod,
-					answer, v.Error,
-				)
-			} else {
-				logger.Infow(
-					respLogger,
-					urlString, info.FullMethod,
-					answer, "undefined type",
-				)
-			}
+			answer = err.Error()
+		} else if v, ok := resp.(*pb.MetricsResponse); ok {
+			answer = v.Error
 		}
+		logging.FromContext(ctx, logger).Infow(
+			"Request",
+			"url", info.FullMethod,
+			"size", reqSize,
+			"code", status.Code(err).String(),
+			"answer", answer,
+			"duration", time.Since(start),
+		)
 		return resp, err
 	}
 }
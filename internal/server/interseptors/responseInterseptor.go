@@ -0,0 +1,83 @@
+package interseptors
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/gostuding/go-metrics/internal/agentkeys"
+	pb "github.com/gostuding/go-metrics/internal/proto"
+	"github.com/gostuding/go-metrics/internal/signing"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// encrypt is private func. Encrypts message by RSA-OAEP blocks, like agent encrypts requests.
+func encrypt(key *rsa.PublicKey, msg []byte) ([]byte, error) {
+	size := key.Size() - 2*sha256.Size - 2 //nolint:gomnd //<-default values
+	hash := sha256.New()
+	encrypted := make([]byte, 0)
+	for i := 0; i < len(msg); i += size {
+		end := i + size
+		if end > len(msg) {
+			end = len(msg)
+		}
+		data, err := rsa.EncryptOAEP(hash, rand.Reader, key, msg[i:end], []byte(""))
+		if err != nil {
+			return nil, fmt.Errorf("encrypt error: %w", err)
+		}
+		encrypted = append(encrypted, data...)
+	}
+	return encrypted, nil
+}
+
+// ResponseInterceptor signs and encrypts metrics responses.
+// If agent's RSA public key is registered, response error text is encrypted and moved to Data field.
+// If agent's key is set, HMAC of deterministic marshaled response is sent in "HashSHA256" header metadata.
+// Request's timestamp and nonce are signed with response, so response can't be used for other request.
+func ResponseInterceptor(
+	keys agentkeys.KeyFunc,
+	publicKeys func(agentID string) *rsa.PublicKey,
+) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return resp, err
+		}
+		data, ok := resp.(*pb.MetricsResponse)
+		if !ok {
+			return resp, nil
+		}
+		md, _ := metadata.FromIncomingContext(ctx)
+		agentID := firstValue(md, agentkeys.MetadataAgentID)
+		if pub := publicKeys(agentID); pub != nil {
+			if data.Data, err = encrypt(pub, []byte(data.Error)); err != nil {
+				return nil, status.Error(codes.Internal, err.Error()) //nolint:wrapcheck //<-
+			}
+			data.Error = ""
+		}
+		key, err := keys(agentID)
+		if err != nil || key == nil {
+			return data, nil //nolint:nilerr //<-request hash is checked by HashInterceptor
+		}
+		body, err := proto.MarshalOptions{Deterministic: true}.Marshal(data)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error()) //nolint:wrapcheck //<-
+		}
+		hash := signing.Hash(key, body, firstValue(md, signing.MetadataTimestamp), firstValue(md, signing.MetadataNonce))
+		if err = grpc.SetHeader(ctx, metadata.Pairs(hashVarName, hash)); err != nil {
+			return nil, status.Error(codes.Internal, err.Error()) //nolint:wrapcheck //<-
+		}
+		return data, nil
+	}
+}
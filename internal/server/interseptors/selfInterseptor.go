@@ -0,0 +1,33 @@
+package interseptors
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	pb "github.com/gostuding/go-metrics/internal/proto"
+	"github.com/gostuding/go-metrics/internal/server/storage"
+	"google.golang.org/grpc"
+)
+
+// SelfMetricsInterceptor counts requests and errors and observes requests latency for each gRPC method.
+// Metrics names are like 'grpc_<method>_requests_total', 'grpc_<method>_errors_total' and 'grpc_<method>_seconds'.
+// Responses with error or with not empty MetricsResponse.Error are counted as errors.
+func SelfMetricsInterceptor(self *storage.SelfMetrics) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		start := time.Now()
+		method := strings.NewReplacer("/", "_", ".", "_").Replace(strings.TrimPrefix(info.FullMethod, "/"))
+		resp, err := handler(ctx, req)
+		self.ObserveSince("grpc_"+method+"_seconds", start)
+		self.Add("grpc_"+method+"_requests_total", 1)
+		if v, ok := resp.(*pb.MetricsResponse); err != nil || (ok && v.Error != "") {
+			self.Add("grpc_"+method+"_errors_total", 1)
+		}
+		return resp, err
+	}
+}
@@ -0,0 +1,36 @@
+package interseptors
+
+import (
+	"context"
+	"errors"
+
+	pb "github.com/gostuding/go-metrics/internal/proto"
+	"github.com/gostuding/go-metrics/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+)
+
+// tracerName is tracer name for server's gRPC spans.
+const tracerName = "github.com/gostuding/go-metrics/internal/server/interseptors"
+
+// TracingInterceptor starts span for request with trace context from request metadata.
+// Span name is gRPC method.
+func TracingInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	ctx, span := tracing.Start(tracing.ExtractGRPC(ctx), tracerName, info.FullMethod, trace.SpanKindServer,
+		attribute.String("rpc.system", "grpc"),
+		attribute.String("rpc.method", info.FullMethod),
+	)
+	resp, err := handler(ctx, req)
+	if v, ok := resp.(*pb.MetricsResponse); ok && err == nil && v.Error != "" {
+		tracing.End(span, errors.New(v.Error))
+	} else {
+		tracing.End(span, err)
+	}
+	return resp, err
+}
@@ -1,16 +1,16 @@
 package server
 
 import (
-	"fmt"
-
+	"github.com/gostuding/go-metrics/internal/logging"
 	"go.uber.org/zap"
 )
 
 // NewLogger is create new logger with Suger type.
-func NewLogger() (*zap.SugaredLogger, error) {
-	logger, err := zap.NewDevelopment()
+// Format and output file are set by options, see Config.Logging. Empty options create console logger.
+func NewLogger(o logging.Options) (*zap.SugaredLogger, error) {
+	logger, err := logging.New(o)
 	if err != nil {
-		return nil, fmt.Errorf("logger init error: %w", err)
+		return nil, err //nolint:wrapcheck //<-senselessly
 	}
 	return logger.Sugar(), nil
 }
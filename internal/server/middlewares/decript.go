@@ -9,6 +9,7 @@ import (
 	"io"
 	"net/http"
 
+	"github.com/gostuding/go-metrics/internal/logging"
 	"go.uber.org/zap"
 )
 
@@ -62,27 +63,42 @@ func decriptMessage(key *rsa.PrivateKey, msg []byte) ([]byte, error) {
 	return dectipted, nil
 }
 
+// decriptByKeys is private func. Decripts message by the first suitable key.
+// Several keys are used while agents migrate to the new public key.
+func decriptByKeys(keys []*rsa.PrivateKey, msg []byte) ([]byte, error) {
+	err := errors.New("private keys are not set")
+	for _, key := range keys {
+		var data []byte
+		if data, err = decriptMessage(key, msg); err == nil {
+			return data, nil
+		}
+	}
+	return nil, err
+}
+
 // DecriptMiddleware decripts messages from clients.
+// Keys are tried in order, so the current key should be the first.
 func DecriptMiddleware(
-	key *rsa.PrivateKey,
+	keys []*rsa.PrivateKey,
 	logger *zap.SugaredLogger,
 ) func(h http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		fn := func(w http.ResponseWriter, r *http.Request) {
-			if key != nil && r.Method == http.MethodPost {
+			log := logging.FromContext(r.Context(), logger)
+			if len(keys) > 0 && r.Method == http.MethodPost {
 				data, err := io.ReadAll(r.Body)
 				if err != nil {
-					logger.Warnf(getError(ReadBodyError, err).Error())
+					log.Warnf(getError(ReadBodyError, err).Error())
 					return
 				}
 				if err = r.Body.Close(); err != nil {
-					logger.Warnf(getError(ReadBodyError, err).Error())
+					log.Warnf(getError(ReadBodyError, err).Error())
 					return
 				}
-				body, err := decriptMessage(key, data)
+				body, err := decriptByKeys(keys, data)
 				if err != nil {
 					w.WriteHeader(http.StatusBadRequest)
-					logger.Warnf("decript error: %w", err)
+					log.Warnf("decript error: %v", err)
 					return
 				}
 				r.Body = io.NopCloser(bytes.NewReader(body))
@@ -29,3 +29,34 @@ func Test_decriptMessage(t *testing.T) {
 		t.Errorf("decription errror. Decript value not equal to manual: %s", string(decr))
 	}
 }
+
+func Test_decriptByKeys(t *testing.T) {
+	data := []byte("test")
+	keys := make([]*rsa.PrivateKey, 0)
+	for _, size := range []int{2048, 3072} {
+		key, err := rsa.GenerateKey(rand.Reader, size)
+		if err != nil {
+			t.Errorf("create key errror: %v", err)
+			return
+		}
+		keys = append(keys, key)
+	}
+	for i, key := range keys {
+		enc, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, &key.PublicKey, data, []byte(""))
+		if err != nil {
+			t.Errorf("encript errror: %v", err)
+			return
+		}
+		decr, err := decriptByKeys(keys, enc)
+		if err != nil {
+			t.Errorf("decript by key %d errror: %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(decr, data) {
+			t.Errorf("decription errror. Decript value not equal to manual: %s", string(decr))
+		}
+		if _, err = decriptByKeys(keys[i+1:], enc); err == nil {
+			t.Errorf("message decripted without key %d", i)
+		}
+	}
+}
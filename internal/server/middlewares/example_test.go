@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/gostuding/go-metrics/internal/agentkeys"
 	"github.com/gostuding/go-metrics/internal/server/middlewares/mocks"
 	"go.uber.org/zap"
 )
@@ -116,7 +117,9 @@ func ExampleHashCheckMiddleware() {
 		fmt.Printf("create logger errror: %v", err)
 		return
 	}
-	HashCheckMiddleware([]byte("key"), logger.Sugar())
+	// Registry is nil, so shared key is used for all agents.
+	var registry *agentkeys.Registry
+	HashCheckMiddleware(registry.KeyFunc([]byte("key")), nil, logger.Sugar())
 
 	// Output:
 	//
@@ -144,7 +147,7 @@ func ExampleDecriptMiddleware() {
 		fmt.Printf("create logger errror: %v", err)
 		return
 	}
-	DecriptMiddleware(key, logger.Sugar())
+	DecriptMiddleware([]*rsa.PrivateKey{key}, logger.Sugar())
 
 	// Output:
 	//
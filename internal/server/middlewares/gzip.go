@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/gostuding/go-metrics/internal/logging"
 	"go.uber.org/zap"
 )
 
@@ -100,18 +101,19 @@ func (c *gzipReader) Close() error {
 func GzipMiddleware(logger *zap.SugaredLogger) func(h http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		fn := func(w http.ResponseWriter, r *http.Request) {
+			log := logging.FromContext(r.Context(), logger)
 			if strings.Contains(r.Header.Get(contentEncoding), gzipString) {
 				cr, err := NewGzipReader(r.Body)
 				if err != nil {
 					w.WriteHeader(http.StatusInternalServerError)
-					logger.Warnf(getError(GzipReaderError, err).Error())
+					log.Warnf(getError(GzipReaderError, err).Error())
 					return
 				}
 				r.Body = cr
 				defer cr.Close() //nolint:errcheck //<-senselessly
 			}
 			if strings.Contains(r.Header.Get(acceptEncoding), gzipString) {
-				next.ServeHTTP(NewGzipWriter(w, logger), r)
+				next.ServeHTTP(NewGzipWriter(w, log), r)
 			} else {
 				next.ServeHTTP(w, r)
 			}
@@ -9,6 +9,9 @@ import (
 	"io"
 	"net/http"
 
+	"github.com/gostuding/go-metrics/internal/agentkeys"
+	"github.com/gostuding/go-metrics/internal/logging"
+	"github.com/gostuding/go-metrics/internal/signing"
 	"go.uber.org/zap"
 )
 
@@ -42,49 +45,62 @@ func (r *hashWriter) Write(b []byte) (int, error) {
 	return size, nil
 }
 
-func checkHash(data, key []byte, hash string) error {
-	if len(data) > 0 && hash != "" {
-		h := hmac.New(sha256.New, key)
-		_, err := h.Write(data)
-		if err != nil {
-			return fmt.Errorf("write hash summ error: %w", err)
-		}
-		if hash != hex.EncodeToString(h.Sum(nil)) {
-			return fmt.Errorf("incorrect hash summ: %s", hash)
-		}
-	}
-	return nil
-}
-
 // HashCheckMiddleware checks hash summ for request body.
 // Hash must be in request Header: "HashSHA256": "...hassumm...".
+// Key is selected by agent ID from "X-Agent-ID" header. Requests with agent ID must contain hash.
+// Hash is not checked if key is nil, requests are rejected if key is not found.
+// Timestamp and nonce are taken from "X-Timestamp" and "X-Nonce" headers and are checked by verifier,
+// nil verifier checks only body hash.
 func HashCheckMiddleware(
-	hashKey []byte,
+	keys agentkeys.KeyFunc,
+	verifier *signing.Verifier,
 	logger *zap.SugaredLogger,
 ) func(h http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		fn := func(w http.ResponseWriter, r *http.Request) {
-			if len(hashKey) > 0 && r.Method == http.MethodPost {
-				data, err := io.ReadAll(r.Body)
-				if err != nil {
-					logger.Warnf(getError(ReadBodyError, err).Error())
-					return
-				}
-				if err = r.Body.Close(); err != nil {
-					logger.Warnf(getError(CloseBodyError, err).Error())
-					return
-				}
-				err = checkHash(data, hashKey, r.Header.Get(hashVarName))
-				if err != nil {
-					w.WriteHeader(http.StatusBadRequest)
-					logger.Warnf("hash checker error: %w", err)
-					return
-				}
-				r.Body = io.NopCloser(bytes.NewReader(data))
-				next.ServeHTTP(NewHashWriter(w, hashKey), r)
-			} else {
+			log := logging.FromContext(r.Context(), logger)
+			if r.Method != http.MethodPost {
 				next.ServeHTTP(w, r)
+				return
+			}
+			agentID := r.Header.Get(agentkeys.HeaderAgentID)
+			hashKey, err := keys(agentID)
+			if err != nil {
+				w.WriteHeader(http.StatusUnauthorized)
+				log.Warnf("hash key error: %v", err)
+				return
+			}
+			if len(hashKey) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			data, err := io.ReadAll(r.Body)
+			if err != nil {
+				log.Warnf(getError(ReadBodyError, err).Error())
+				return
+			}
+			if err = r.Body.Close(); err != nil {
+				log.Warnf(getError(CloseBodyError, err).Error())
+				return
+			}
+			sign := signing.Signature{
+				Hash:      r.Header.Get(hashVarName),
+				Timestamp: r.Header.Get(signing.HeaderTimestamp),
+				Nonce:     r.Header.Get(signing.HeaderNonce),
+			}
+			if agentID != "" && sign.Hash == "" {
+				w.WriteHeader(http.StatusBadRequest)
+				log.Warnf("hash checker error: agent '%s' request hash undefined", agentID)
+				return
+			}
+			err = verifier.Verify(hashKey, data, sign)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				log.Warnf("hash checker error: %v", err)
+				return
 			}
+			r.Body = io.NopCloser(bytes.NewReader(data))
+			next.ServeHTTP(NewHashWriter(w, hashKey), r)
 		}
 		return http.HandlerFunc(fn)
 	}
@@ -2,9 +2,19 @@ package middlewares
 
 import (
 	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/gostuding/go-metrics/internal/agentkeys"
 	"github.com/gostuding/go-metrics/internal/server/middlewares/mocks"
+	"github.com/gostuding/go-metrics/internal/signing"
 )
 
 func Test_hashWriter_Write(t *testing.T) {
@@ -66,42 +76,79 @@ func Test_hashWriter_Write(t *testing.T) {
 	}
 }
 
-func Test_checkHash(t *testing.T) {
-	type args struct {
-		data []byte
-		key  []byte
-		hash string
-	}
+func TestHashCheckMiddleware(t *testing.T) {
+	registry, err := agentkeys.NewRegistry(filepath.Join(t.TempDir(), "keys.json"))
+	require.NoError(t, err, "create registry error")
+	_, err = registry.Add("agent-1", "default", "")
+	require.NoError(t, err, "add key error")
+	verifier := signing.NewVerifier(signing.Options{Scheme: signing.SchemeAny, Skew: signing.DefaultSkew},
+		signing.NewNonceCache())
+	handler := HashCheckMiddleware(registry.KeyFunc(nil), verifier, zap.NewNop().Sugar())(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+	)
+	legacy := signing.Signature{Hash: "de79cc62d7da11c1f3049dbf73ba060497e3d4e7a07029fa6f48e75cfc681042"}
+	bad := signing.Signature{Hash: "d1"}
+	sign, err := signing.Sign([]byte("default"), []byte("test"), signing.SchemeNonce, time.Now())
+	require.NoError(t, err, "sign error")
+	old, err := signing.Sign([]byte("default"), []byte("test"), signing.SchemeNonce, time.Now().Add(-time.Hour))
+	require.NoError(t, err, "sign error")
+
 	tests := []struct {
 		name    string
-		args    args
-		wantErr bool
+		method  string
+		agentID string
+		sign    signing.Signature
+		want    int
 	}{
-		{
-			name:    "Null data",
-			args:    args{data: nil, key: nil, hash: ""},
-			wantErr: false,
-		},
-		{
-			name: "Test data",
-			args: args{
-				data: []byte("test"),
-				key:  []byte("default"),
-				hash: "de79cc62d7da11c1f3049dbf73ba060497e3d4e7a07029fa6f48e75cfc681042",
-			},
-			wantErr: false,
-		},
-		{
-			name:    "Bad hash",
-			args:    args{data: []byte("test"), key: []byte("default"), hash: "d1"},
-			wantErr: true,
-		},
+		{name: "Agent key", method: http.MethodPost, agentID: "agent-1", sign: legacy, want: http.StatusOK},
+		{name: "Bad hash", method: http.MethodPost, agentID: "agent-1", sign: bad, want: http.StatusBadRequest},
+		{name: "Hash undefined", method: http.MethodPost, agentID: "agent-1", want: http.StatusBadRequest},
+		{name: "Nonce", method: http.MethodPost, agentID: "agent-1", sign: sign, want: http.StatusOK},
+		{name: "Replay", method: http.MethodPost, agentID: "agent-1", sign: sign, want: http.StatusBadRequest},
+		{name: "Old timestamp", method: http.MethodPost, agentID: "agent-1", sign: old, want: http.StatusBadRequest},
+		{name: "Unknown agent", method: http.MethodPost, agentID: "agent-2", sign: sign, want: http.StatusUnauthorized},
+		{name: "Agent required", method: http.MethodPost, sign: sign, want: http.StatusUnauthorized},
+		{name: "Not POST", method: http.MethodGet, want: http.StatusOK},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := checkHash(tt.args.data, tt.args.key, tt.args.hash); (err != nil) != tt.wantErr {
-				t.Errorf("checkHash() error = %v, wantErr %v", err, tt.wantErr)
-			}
+			req := httptest.NewRequest(tt.method, "/", strings.NewReader("test"))
+			req.Header.Set(agentkeys.HeaderAgentID, tt.agentID)
+			req.Header.Set(hashVarName, tt.sign.Hash)
+			req.Header.Set(signing.HeaderTimestamp, tt.sign.Timestamp)
+			req.Header.Set(signing.HeaderNonce, tt.sign.Nonce)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			assert.Equal(t, tt.want, w.Code, "response status")
 		})
 	}
 }
+
+func TestHashCheckMiddleware_replayOtherAgent(t *testing.T) {
+	registry, err := agentkeys.NewRegistry(filepath.Join(t.TempDir(), "keys.json"))
+	require.NoError(t, err, "create registry error")
+	verifier := signing.NewVerifier(signing.Options{Scheme: signing.SchemeNonce, Skew: signing.DefaultSkew},
+		signing.NewNonceCache())
+	handler := HashCheckMiddleware(registry.KeyFunc([]byte("shared")), verifier, zap.NewNop().Sugar())(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+	)
+	sign, err := signing.Sign([]byte("shared"), []byte("test"), signing.SchemeNonce, time.Now())
+	require.NoError(t, err, "sign error")
+	for _, item := range []struct {
+		agentID string
+		want    int
+	}{
+		{agentID: "agent-1", want: http.StatusOK},
+		{agentID: "agent-2", want: http.StatusBadRequest},
+		{agentID: "", want: http.StatusBadRequest},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("test"))
+		req.Header.Set(agentkeys.HeaderAgentID, item.agentID)
+		req.Header.Set(hashVarName, sign.Hash)
+		req.Header.Set(signing.HeaderTimestamp, sign.Timestamp)
+		req.Header.Set(signing.HeaderNonce, sign.Nonce)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		assert.Equal(t, item.want, w.Code, "agent '%s' response status", item.agentID)
+	}
+}
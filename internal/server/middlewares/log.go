@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/gostuding/go-metrics/internal/logging"
 	"go.uber.org/zap"
 )
 
@@ -34,30 +35,42 @@ func (r *myLogWriter) Header() http.Header {
 	return r.ResponseWriter.Header()
 }
 
-// LoggerMiddleware writes in logger status code and size of responce data.
+// RequestIDMiddleware takes request ID from 'X-Request-ID' header or generates new one.
+// Request ID is written in response header and request context with logger,
+// which adds 'request_id' field to all lines. Use logging.FromContext to get the logger.
+func RequestIDMiddleware(logger *zap.SugaredLogger) func(h http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(logging.HeaderRequestID)
+			if !logging.ValidRequestID(id) {
+				id = logging.NewRequestID()
+			}
+			w.Header().Set(logging.HeaderRequestID, id)
+			next.ServeHTTP(w, r.WithContext(logging.WithLogger(r.Context(), logger, id)))
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// LoggerMiddleware writes in logger one line for request: method, url, status code,
+// size of responce data and duration.
 func LoggerMiddleware(logger *zap.SugaredLogger) func(h http.Handler) http.Handler {
-	var (
-		typeString = "type"
-		urlString  = "url"
-	)
 	return func(next http.Handler) http.Handler {
 		fn := func(w http.ResponseWriter, r *http.Request) {
 			rWriter := NewLogWriter(w)
 			start := time.Now()
 			next.ServeHTTP(rWriter, r)
-			logger.Infow(
-				"Request logger",
-				typeString, "request",
-				urlString, r.RequestURI,
+			status := rWriter.Status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			logging.FromContext(r.Context(), logger).Infow(
+				"Request",
 				"method", r.Method,
-				"duration", time.Since(start),
-			)
-			defer logger.Infow(
-				"Response logger",
-				typeString, "responce",
-				urlString, r.RequestURI,
-				"status", rWriter.Status,
+				"url", r.RequestURI,
+				"status", status,
 				"size", rWriter.Size,
+				"duration", time.Since(start),
 			)
 		}
 		return http.HandlerFunc(fn)
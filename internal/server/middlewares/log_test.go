@@ -2,10 +2,15 @@ package middlewares
 
 import (
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/go-chi/chi"
+	"github.com/gostuding/go-metrics/internal/logging"
 	"github.com/gostuding/go-metrics/internal/server/middlewares/mocks"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func Test_myLogWriter_WriteHeader(t *testing.T) {
@@ -36,3 +41,48 @@ func Test_myLogWriter_Header(t *testing.T) {
 		t.Errorf("logger header get error")
 	}
 }
+
+func TestRequestIDMiddleware(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	logger := zap.New(core).Sugar()
+	router := chi.NewRouter()
+	router.Use(RequestIDMiddleware(logger), LoggerMiddleware(logger))
+	router.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		logging.FromContext(r.Context(), logger).Debug("handler")
+		w.WriteHeader(http.StatusAccepted)
+	})
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{name: "Propagated", header: "agent-request-1", want: "agent-request-1"},
+		{name: "Generated", header: ""},
+		{name: "Invalid", header: "bad id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_ = logs.TakeAll()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(logging.HeaderRequestID, tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			id := w.Header().Get(logging.HeaderRequestID)
+			if tt.want != "" {
+				assert.Equal(t, tt.want, id, "request id")
+			} else {
+				assert.NotEmpty(t, id, "request id must be generated")
+				assert.NotEqual(t, tt.header, id, "request id must be generated")
+			}
+			entries := logs.AllUntimed()
+			if assert.Equal(t, 2, len(entries), "log lines count") {
+				for _, e := range entries {
+					assert.Equal(t, id, e.ContextMap()[logging.FieldRequestID], "request id field")
+				}
+				assert.Equal(t, int64(http.StatusAccepted), entries[1].ContextMap()["status"], "status field")
+			}
+		})
+	}
+}
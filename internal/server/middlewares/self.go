@@ -0,0 +1,42 @@
+package middlewares
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/gostuding/go-metrics/internal/server/storage"
+)
+
+// SelfMetricsMiddleware counts requests and errors and observes requests latency for each route.
+// Metrics names are like 'http_<route>_requests_total', 'http_<route>_errors_total' and 'http_<route>_seconds'.
+// Responses with status code 400 and greater are counted as errors.
+func SelfMetricsMiddleware(self *storage.SelfMetrics) func(h http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			rWriter := NewLogWriter(w)
+			start := time.Now()
+			next.ServeHTTP(rWriter, r)
+			route := "unknown"
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				route = RouteName(rctx.RoutePattern())
+			}
+			self.ObserveSince("http_"+route+"_seconds", start)
+			self.Add("http_"+route+"_requests_total", 1)
+			if rWriter.Status >= http.StatusBadRequest {
+				self.Add("http_"+route+"_errors_total", 1)
+			}
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// RouteName converts route pattern like '/value/{mType}/{mName}' to metric name part like 'value_mType_mName'.
+func RouteName(pattern string) string {
+	name := strings.NewReplacer("{", "", "}", "", "*", "all", "/", "_").Replace(strings.Trim(pattern, "/"))
+	if name == "" {
+		return "root"
+	}
+	return name
+}
@@ -0,0 +1,49 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/gostuding/go-metrics/internal/server/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouteName(t *testing.T) {
+	tests := map[string]string{
+		"/":                                "root",
+		"/updates/":                        "updates",
+		"/value/{mType}/{mName}":           "value_mType_mName",
+		"/debug/*":                         "debug_all",
+		"/update/{mType}/{mName}/{mValue}": "update_mType_mName_mValue",
+	}
+	for pattern, want := range tests {
+		if got := RouteName(pattern); got != want {
+			t.Errorf("RouteName(%s) = %s, want %s", pattern, got, want)
+		}
+	}
+}
+
+func TestSelfMetricsMiddleware(t *testing.T) {
+	ms, err := storage.NewMemStorage(false, "", 1)
+	assert.NoError(t, err, "error making new MemStorage")
+	router := chi.NewRouter()
+	router.Use(SelfMetricsMiddleware(ms.SelfMetrics()))
+	router.Get("/value/{mName}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	for i := 0; i < 2; i++ {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/value/item", nil))
+	}
+	body, err := ms.GetMetricsPrometheus(httptest.NewRequest(http.MethodGet, "/", nil).Context())
+	assert.NoError(t, err, "get prometheus metrics error")
+	for _, item := range []string{
+		storage.SelfNamespace + "http_value_mName_requests_total 2",
+		storage.SelfNamespace + "http_value_mName_errors_total 2",
+		storage.SelfNamespace + "http_value_mName_seconds_count 2",
+	} {
+		assert.True(t, strings.Contains(body, item), "metric '%s' expected in: %s", item, body)
+	}
+}
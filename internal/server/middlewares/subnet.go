@@ -5,6 +5,7 @@ import (
 	"net"
 	"net/http"
 
+	"github.com/gostuding/go-metrics/internal/logging"
 	"go.uber.org/zap"
 )
 
@@ -40,9 +41,10 @@ func SubNetCheckMiddleware(
 ) func(h http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		fn := func(w http.ResponseWriter, r *http.Request) {
+			log := logging.FromContext(r.Context(), logger)
 			if err := checkSubnet(subnet, r); err != nil {
 				w.WriteHeader(http.StatusForbidden)
-				logger.Warnln(err.Error())
+				log.Warnln(err.Error())
 			} else {
 				next.ServeHTTP(w, r)
 			}
@@ -0,0 +1,44 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/gostuding/go-metrics/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is tracer name for server's HTTP spans.
+const tracerName = "github.com/gostuding/go-metrics/internal/server/middlewares"
+
+// TracingMiddleware starts span for request with trace context from request headers.
+// Span name is request method and route pattern. Responses with status code 500 and greater are marked as errors.
+func TracingMiddleware() func(h http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			ctx := tracing.ExtractHTTP(r.Context(), r.Header)
+			ctx, span := tracing.Start(ctx, tracerName, r.Method, trace.SpanKindServer,
+				attribute.String("http.method", r.Method),
+				attribute.String("http.target", r.URL.Path),
+			)
+			defer span.End()
+			rWriter := NewLogWriter(w)
+			next.ServeHTTP(rWriter, r.WithContext(ctx))
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				span.SetName(r.Method + " " + rctx.RoutePattern())
+				span.SetAttributes(attribute.String("http.route", rctx.RoutePattern()))
+			}
+			status := rWriter.Status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			span.SetAttributes(attribute.Int("http.status_code", status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+		}
+		return http.HandlerFunc(fn)
+	}
+}
@@ -0,0 +1,45 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/gostuding/go-metrics/internal/tracing"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracingMiddleware(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	shutdown, err := tracing.Setup(context.Background(), "test", tracing.Options{})
+	assert.NoError(t, err, "setup tracing error")
+	defer shutdown(context.Background()) //nolint:errcheck //<-senselessly
+
+	router := chi.NewRouter()
+	router.Use(TracingMiddleware())
+	router.Post("/update/{mType}/{mName}/{mValue}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	ctx, parent := provider.Tracer("agent").Start(context.Background(), "send")
+	req := httptest.NewRequest(http.MethodPost, "/update/counter/item/1", nil)
+	tracing.InjectHTTP(ctx, req.Header)
+	parent.End()
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if assert.Equal(t, 2, len(spans), "spans count") {
+		span := spans[1]
+		assert.Equal(t, "POST /update/{mType}/{mName}/{mValue}", span.Name(), "span name")
+		assert.Equal(t, parent.SpanContext().TraceID(), span.SpanContext().TraceID(), "trace id must be propagated")
+		assert.Equal(t, trace.SpanKindServer, span.SpanKind(), "span kind")
+		assert.Equal(t, "Error", span.Status().Code.String(), "span status")
+	}
+}
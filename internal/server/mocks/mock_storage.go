@@ -92,6 +92,21 @@ func (mr *MockStorageMockRecorder) GetMetricsHTML(arg0 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMetricsHTML", reflect.TypeOf((*MockStorage)(nil).GetMetricsHTML), arg0)
 }
 
+// GetMetricsPrometheus mocks base method
+func (m *MockStorage) GetMetricsPrometheus(arg0 context.Context) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMetricsPrometheus", arg0)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMetricsPrometheus indicates an expected call of GetMetricsPrometheus
+func (mr *MockStorageMockRecorder) GetMetricsPrometheus(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMetricsPrometheus", reflect.TypeOf((*MockStorage)(nil).GetMetricsPrometheus), arg0)
+}
+
 // PingDB mocks base method
 func (m *MockStorage) PingDB(arg0 context.Context) error {
 	m.ctrl.T.Helper()
@@ -0,0 +1,195 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/gostuding/go-metrics/internal/agentkeys"
+	"github.com/gostuding/go-metrics/internal/logging"
+	"github.com/gostuding/go-metrics/internal/server/interseptors"
+	"github.com/gostuding/go-metrics/internal/signing"
+)
+
+// saver is private struct. Runs saveStorageInterval and restarts it when interval is changed.
+type saver struct {
+	cancel context.CancelFunc
+}
+
+// start is private func. Stops previous saveStorageInterval gorutine and runs new one.
+func (sv *saver) start(ctx context.Context, interval int, storage Saver, logger *zap.SugaredLogger) {
+	if sv.cancel != nil {
+		sv.cancel()
+	}
+	ctx, sv.cancel = context.WithCancel(ctx)
+	go saveStorageInterval(ctx, interval, storage, logger)
+}
+
+// parseSubnet is private func. Returns nil if value is empty.
+func parseSubnet(value string) (*net.IPNet, error) {
+	if value == "" {
+		return nil, nil //nolint:nilnil //<-no subnet check
+	}
+	_, subnet, err := net.ParseCIDR(value)
+	if err != nil {
+		return nil, fmt.Errorf("parse subnet error: %w", err)
+	}
+	return subnet, nil
+}
+
+// watchReload is private func. Calls reload on every SIGHUP until ctx is done.
+func watchReload(ctx context.Context, logger *zap.SugaredLogger, reload func(context.Context)) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			logger.Infoln("SIGHUP received, reload config")
+			reload(ctx)
+		}
+	}
+}
+
+// reloadConfig is private func. Reads config again and returns c with changes, which can be applied
+// without restart: key, hash_scheme, hash_skew, admin_token, crypto_key, trusted_subnet, log_level and store_interval.
+// Other changes are logged and rejected.
+func reloadConfig(c *Config, logger *zap.SugaredLogger) (*Config, error) {
+	n, err := c.Reload()
+	if err != nil {
+		return nil, fmt.Errorf("read config error: %w", err)
+	}
+	names := c.restartChanges(n)
+	if len(names) > 0 {
+		logger.Warnf("config changes require restart and are rejected: %s", strings.Join(names, ", "))
+	}
+	cfg := *c
+	cfg.Key = n.Key
+	cfg.HashScheme = n.HashScheme
+	cfg.HashSkew = n.HashSkew
+	cfg.AdminToken = n.AdminToken
+	cfg.PrivateKeys = n.PrivateKeys
+	cfg.PrivateKeyPath = n.PrivateKeyPath
+	cfg.TrustedSubnet = n.TrustedSubnet
+	cfg.LogLevel = n.LogLevel
+	if !contains(names, "store_interval") {
+		cfg.StoreInterval = n.StoreInterval
+	}
+	return &cfg, nil
+}
+
+// reloadKeys is private func. Reads agents keys registry file again, if registry is used.
+func reloadKeys(keys *agentkeys.Registry, logger *zap.SugaredLogger) {
+	if keys == nil {
+		return
+	}
+	if err := keys.Load(); err != nil {
+		logger.Warnf("reload agents keys rejected: %v", err)
+	}
+}
+
+// contains is private func. Checks if name is in names list.
+func contains(names []string, name string) bool {
+	for _, item := range names {
+		if item == name {
+			return true
+		}
+	}
+	return false
+}
+
+// reload is private func. Applies config changes to running server.
+func (s *Server) reload(ctx context.Context) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	cfg, err := reloadConfig(s.Config, s.Logger)
+	if err != nil {
+		s.Logger.Warnf("reload config rejected: %v", err)
+		return
+	}
+	subnet, _ := parseSubnet(cfg.TrustedSubnet) //nolint:errcheck //<-checked by Config.Validate
+	reloadKeys(s.keys, s.Logger)
+	verifier := signing.NewVerifier(cfg.Signing(), s.nonces)
+	s.handler.Store(makeRouter(s.timed, s.Logger, s.keys.KeyFunc(cfg.hashKey()), verifier, cfg.PrivateKeys, subnet,
+		s.self, s.auditor, cfg.AdminToken, s.admin(cfg)))
+	if cfg.ConnectDBString == "" && cfg.StoreInterval != s.Config.StoreInterval {
+		s.saver.start(ctx, cfg.StoreInterval, s.Storage, s.Logger)
+	}
+	if err = logging.SetLevel(cfg.LogLevel); err != nil {
+		s.Logger.Warnln(err)
+	}
+	s.Config = cfg
+	s.Logger.Infoln("config reloaded")
+}
+
+// reload is private func. Applies config changes to running RPC server.
+func (s *RPCServer) reload(ctx context.Context) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	cfg, err := reloadConfig(s.Config, s.Logger)
+	if err != nil {
+		s.Logger.Warnf("reload config rejected: %v", err)
+		return
+	}
+	reloadKeys(s.keys, s.Logger)
+	s.interceptor.Store(s.makeInterceptor(cfg))
+	if cfg.ConnectDBString == "" && cfg.StoreInterval != s.Config.StoreInterval {
+		s.saver.start(ctx, cfg.StoreInterval, s.Storage, s.Logger)
+	}
+	if err = logging.SetLevel(cfg.LogLevel); err != nil {
+		s.Logger.Warnln(err)
+	}
+	s.Config = cfg
+	s.Logger.Infoln("config reloaded")
+}
+
+// makeInterceptor is private func. Returns chain of interceptors with config options.
+func (s *RPCServer) makeInterceptor(cfg *Config) grpc.UnaryServerInterceptor {
+	return chainInterceptors(
+		interseptors.RequestIDInterceptor(s.Logger),
+		interseptors.TracingInterceptor,
+		// Response error is encrypted, so self metrics must see the response before encryption.
+		interseptors.ResponseInterceptor(s.keys.KeyFunc(cfg.hashKey()), s.keys.PublicKey),
+		interseptors.SelfMetricsInterceptor(s.self),
+		interseptors.HashInterceptor(
+			s.keys.KeyFunc(cfg.hashKey()),
+			signing.NewVerifier(cfg.Signing(), s.nonces),
+		),
+		interseptors.GzipInterceptor,
+		interseptors.DecriptInterceptor(cfg.PrivateKeys),
+		interseptors.LogInterceptor(s.Logger),
+	)
+}
+
+// intercept is private func. Calls current interceptors chain, which is replaced on config reload.
+func (s *RPCServer) intercept(
+	ctx context.Context,
+	req any,
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	return s.interceptor.Load().(grpc.UnaryServerInterceptor)(ctx, req, info, handler) //nolint:forcetypeassert //<-only type
+}
+
+// chainInterceptors is private func. Combines interceptors in one, the first one is outer.
+func chainInterceptors(items ...grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		next := handler
+		for i := len(items) - 1; i >= 0; i-- {
+			item, h := items[i], next
+			next = func(ctx context.Context, req any) (any, error) {
+				return item(ctx, req, info, h)
+			}
+		}
+		return next(ctx, req)
+	}
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gostuding/go-metrics/internal/agentkeys"
+	"github.com/gostuding/go-metrics/internal/logging"
+	pb "github.com/gostuding/go-metrics/internal/proto"
+	"github.com/gostuding/go-metrics/internal/server/storage"
+	"github.com/gostuding/go-metrics/internal/signing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func Test_reloadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	write := func(data string) {
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+	}
+	write(`{"address": ":8081", "key": "first", "store_interval": 10, "log_level": "info"}`)
+	t.Setenv("CONFIG", path)
+	logger, err := NewLogger(logging.Options{})
+	require.NoError(t, err)
+	cfg, err := NewConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "first", cfg.Key)
+
+	write(`{"address": ":8082", "key": "second", "store_interval": 20, "log_level": "warn", "trusted_subnet": "10.0.0.0/8"}`)
+	got, err := reloadConfig(cfg, logger)
+	require.NoError(t, err)
+	assert.Equal(t, ":8081", got.IPAddress, "address must not be changed")
+	assert.Equal(t, "second", got.Key)
+	assert.Equal(t, 20, got.StoreInterval)
+	assert.Equal(t, "warn", got.LogLevel)
+	assert.Equal(t, "10.0.0.0/8", got.TrustedSubnet)
+	assert.Equal(t, "first", cfg.Key, "old config must not be changed")
+
+	write(`{"key": "third", "trusted_subnet": "10.0.0.0"}`)
+	_, err = reloadConfig(got, logger)
+	assert.Error(t, err, "incorrect subnet must be rejected")
+
+	write(`{"key": "third", "log_level": "verbose"}`)
+	_, err = reloadConfig(got, logger)
+	assert.Error(t, err, "incorrect log level must be rejected")
+}
+
+func Test_chainInterceptors(t *testing.T) {
+	var calls []string
+	item := func(name string) grpc.UnaryServerInterceptor {
+		return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, h grpc.UnaryHandler) (any, error) {
+			calls = append(calls, name)
+			return h(ctx, req)
+		}
+	}
+	chain := chainInterceptors(item("first"), item("second"))
+	resp, err := chain(context.Background(), "request", &grpc.UnaryServerInfo{},
+		func(ctx context.Context, req any) (any, error) {
+			calls = append(calls, "handler")
+			return req, nil
+		})
+	require.NoError(t, err)
+	assert.Equal(t, "request", resp)
+	assert.Equal(t, []string{"first", "second", "handler"}, calls)
+}
+
+// headerStream is a fake gRPC stream for response headers.
+type headerStream struct {
+	header metadata.MD
+}
+
+func (s *headerStream) Method() string { return "/proto.Metrics/AddMetrics" }
+func (s *headerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+func (s *headerStream) SendHeader(md metadata.MD) error { return s.SetHeader(md) }
+func (s *headerStream) SetTrailer(metadata.MD) error    { return nil }
+
+func TestRPCServer_makeInterceptor(t *testing.T) {
+	dir := t.TempDir()
+	strg, err := storage.NewMemStorage(false, filepath.Join(dir, "metrics.json"), 300)
+	require.NoError(t, err, "storage create error")
+	key, err := rsa.GenerateKey(rand.Reader, 2048) //nolint:gomnd //<-test key size
+	require.NoError(t, err, "generate key error")
+	pub, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err, "marshal public key error")
+	registry, err := agentkeys.NewRegistry(filepath.Join(dir, "keys.json"))
+	require.NoError(t, err, "registry create error")
+	_, err = registry.Add("agent-1", "secret", string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub})))
+	require.NoError(t, err, "add key error")
+	s := RPCServer{Logger: zap.NewNop().Sugar(), self: strg.SelfMetrics(), keys: registry, nonces: signing.NewNonceCache()}
+	cfg := Config{HashScheme: signing.SchemeNonce, HashSkew: signing.DefaultSkew}
+
+	body := []byte("[]")
+	sign, err := signing.Sign([]byte("secret"), body, signing.SchemeNonce, time.Now())
+	require.NoError(t, err, "sign error")
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		agentkeys.MetadataAgentID, "agent-1",
+		hashVarName, sign.Hash,
+		signing.MetadataTimestamp, sign.Timestamp,
+		signing.MetadataNonce, sign.Nonce,
+	))
+	ctx = grpc.NewContextWithServerTransportStream(ctx, &headerStream{})
+	info := grpc.UnaryServerInfo{FullMethod: "/proto.Metrics/AddMetrics"}
+	resp, err := s.makeInterceptor(&cfg)(ctx, &pb.MetricsRequest{Metrics: body}, &info,
+		func(context.Context, any) (any, error) {
+			return &pb.MetricsResponse{Error: "update error"}, nil
+		})
+	require.NoError(t, err, "interceptors error")
+	data, ok := resp.(*pb.MetricsResponse)
+	require.True(t, ok, "response type")
+	assert.Empty(t, data.Error, "response error must be encrypted")
+	assert.NotEmpty(t, data.Data, "encrypted response error")
+	value, err := strg.GetMetric(context.Background(), "counter",
+		storage.SelfNamespace+"grpc_proto_Metrics_AddMetrics_errors_total")
+	require.NoError(t, err, "get self metric error")
+	assert.Equal(t, "1", value, "encrypted response error must be counted")
+}
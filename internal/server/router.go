@@ -10,12 +10,17 @@ import (
 	"github.com/go-chi/chi/middleware"
 	"go.uber.org/zap"
 
+	"github.com/gostuding/go-metrics/internal/agentkeys"
+	"github.com/gostuding/go-metrics/internal/logging"
+	"github.com/gostuding/go-metrics/internal/server/audit"
 	"github.com/gostuding/go-metrics/internal/server/middlewares"
+	"github.com/gostuding/go-metrics/internal/server/storage"
+	"github.com/gostuding/go-metrics/internal/signing"
 )
 
 // Internal constants.
 const (
-	writeErrorString = "write data to client error: %w"
+	writeErrorString = "write data to client error: %v"
 	mTypeString      = "mType"
 	mNameString      = "mName"
 
@@ -24,6 +29,7 @@ const (
 	gzipString      = "gzip"
 	applicationJSON = "application/json"
 	textHTML        = "text/html"
+	textPrometheus  = "text/plain; version=0.0.4"
 	hashVarName     = "HashSHA256"
 )
 
@@ -31,15 +37,23 @@ const (
 func makeRouter(
 	storage Storage,
 	logger *zap.SugaredLogger,
-	hashKey []byte,
-	pk *rsa.PrivateKey,
+	keys agentkeys.KeyFunc,
+	verifier *signing.Verifier,
+	pk []*rsa.PrivateKey,
 	subnet *net.IPNet,
+	self *storage.SelfMetrics,
+	auditor audit.Auditor,
+	adminToken string,
+	admin http.Handler,
 ) http.Handler {
 	router := chi.NewRouter()
 	router.Use(
+		middlewares.RequestIDMiddleware(logger),
+		middlewares.TracingMiddleware(),
+		middlewares.SelfMetricsMiddleware(self),
 		middleware.RealIP,
 		middlewares.SubNetCheckMiddleware(subnet, logger),
-		middlewares.HashCheckMiddleware(hashKey, logger),
+		middlewares.HashCheckMiddleware(keys, verifier, logger),
 		middlewares.GzipMiddleware(logger),
 		middlewares.DecriptMiddleware(pk, logger),
 		middlewares.LoggerMiddleware(logger),
@@ -47,39 +61,57 @@ func makeRouter(
 	)
 
 	router.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		log := logging.FromContext(r.Context(), logger)
 		body, err := GetAllMetrics(r.Context(), storage)
 		if err != nil {
 			w.WriteHeader(http.StatusBadRequest)
-			logger.Warnf("get all metrics error: %w", w)
+			log.Warnf("get all metrics error: %v", err)
 		} else {
 			w.Header().Set(contentType, textHTML)
 			_, err = w.Write(body)
 			if err != nil {
-				logger.Warnf("write metrics data to client error: %w", err)
+				log.Warnf("write metrics data to client error: %v", err)
 			}
 		}
 	})
 
+	router.Get("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		log := logging.FromContext(r.Context(), logger)
+		body, err := GetAllMetricsPrometheus(r.Context(), storage)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			log.Warnf("get metrics in prometheus format error: %v", err)
+			return
+		}
+		w.Header().Set(contentType, textPrometheus)
+		_, err = w.Write(body)
+		if err != nil {
+			log.Warnf(writeErrorString, err)
+		}
+	})
+
 	router.Post("/value/", func(w http.ResponseWriter, r *http.Request) {
+		log := logging.FromContext(r.Context(), logger)
 		body, err := io.ReadAll(r.Body)
 		if err != nil {
 			w.WriteHeader(http.StatusBadRequest)
-			logger.Warnf("get metric json, read request body error: %w", err)
+			log.Warnf("get metric json, read request body error: %v", err)
 			return
 		}
 		body, status, err := GetMetricJSON(r.Context(), storage, body)
 		w.Header().Set(contentType, applicationJSON)
 		w.WriteHeader(status)
 		if err != nil {
-			logger.Warnf("get metric by json error: %w", err)
+			log.Warnf("get metric by json error: %v", err)
 		}
 		_, err = w.Write(body)
 		if err != nil {
-			logger.Warnf(writeErrorString, err)
+			log.Warnf(writeErrorString, err)
 		}
 	})
 
 	router.Get("/value/{mType}/{mName}", func(w http.ResponseWriter, r *http.Request) {
+		log := logging.FromContext(r.Context(), logger)
 		body, err := GetMetric(
 			r.Context(),
 			storage,
@@ -90,16 +122,17 @@ func makeRouter(
 		)
 		if err != nil {
 			w.WriteHeader(http.StatusNotFound)
-			logger.Warnf("get metric error: %w", err)
+			log.Warnf("get metric error: %v", err)
 		} else {
 			_, err = w.Write(body)
 			if err != nil {
-				logger.Warnf(writeErrorString, err)
+				log.Warnf(writeErrorString, err)
 			}
 		}
 	})
 
 	router.Post("/update/{mType}/{mName}/{mValue}", func(w http.ResponseWriter, r *http.Request) {
+		log := logging.FromContext(r.Context(), logger)
 		m := updateMetricsArgs{
 			base: getMetricsArgs{
 				mType: chi.URLParam(r, mTypeString),
@@ -108,76 +141,105 @@ func makeRouter(
 			mValue: chi.URLParam(r, "mValue"),
 		}
 		status, err := Update(r.Context(), storage, m)
+		newHTTPAudit(auditor, log, r).write(r.Context(), audit.ActionUpdate, 1, err)
 		w.WriteHeader(status)
 		if err != nil {
-			logger.Warnf(err.Error())
+			log.Warnf(err.Error())
 		} else {
-			logger.Debugf("update metric '%s' success", m.base.mType)
+			log.Debugf("update metric '%s' success", m.base.mType)
 		}
 	})
 
 	router.Post("/update/", func(w http.ResponseWriter, r *http.Request) {
+		log := logging.FromContext(r.Context(), logger)
 		body, err := io.ReadAll(r.Body)
 		if err != nil {
 			w.WriteHeader(http.StatusBadRequest)
-			logger.Warnf("update read request body error: %w", err)
+			log.Warnf("update read request body error: %v", err)
 			return
 		}
 		data, err := UpdateJSON(r.Context(), body, storage)
+		newHTTPAudit(auditor, log, r).write(r.Context(), audit.ActionUpdateJSON, 1, err)
 		if err != nil {
 			w.WriteHeader(http.StatusBadRequest)
-			logger.Warnf("update metric request error: %w", err)
+			log.Warnf("update metric request error: %v", err)
 		} else {
-			logger.Debug("update metric by json success")
+			log.Debug("update metric by json success")
 			w.Header().Set(contentType, applicationJSON)
 			_, err = w.Write(data)
 			if err != nil {
-				logger.Warnf(writeErrorString, err)
+				log.Warnf(writeErrorString, err)
 			}
 		}
 	})
 
 	router.Post("/updates/", func(w http.ResponseWriter, r *http.Request) {
+		log := logging.FromContext(r.Context(), logger)
 		w.Header().Set(contentType, textHTML)
 		body, err := io.ReadAll(r.Body)
 		if err != nil {
 			w.WriteHeader(http.StatusBadRequest)
-			logger.Warnf("updates read request body error: %w", err)
+			log.Warnf("updates read request body error: %v", err)
 			return
 		}
 		data, err := UpdateJSONSLice(r.Context(), body, storage)
+		newHTTPAudit(auditor, log, r).write(r.Context(), audit.ActionUpdates, countMetrics(body), err)
 		if err != nil {
 			w.WriteHeader(http.StatusBadRequest)
-			logger.Warnf("update metrics by slice error: %w", err)
+			log.Warnf("update metrics by slice error: %v", err)
 		} else {
-			logger.Debug("update metrics by json list success")
+			log.Debug("update metrics by json list success")
 			_, err = w.Write(data)
 			if err != nil {
-				logger.Warnf(writeErrorString, err)
+				log.Warnf(writeErrorString, err)
 			}
 		}
 	})
 
 	router.Get("/ping", func(w http.ResponseWriter, r *http.Request) {
+		log := logging.FromContext(r.Context(), logger)
 		status, err := Ping(r.Context(), storage)
 		w.WriteHeader(status)
 		w.Header().Set(contentType, "")
 		if err != nil {
-			logger.Warnf(err.Error())
+			log.Warnf(err.Error())
 		} else {
-			logger.Debug("database ping success")
+			log.Debug("database ping success")
 		}
 	})
 
 	router.Get("/clear", func(w http.ResponseWriter, r *http.Request) {
+		log := logging.FromContext(r.Context(), logger)
 		w.Header().Set(contentType, "")
 		status, err := Clear(r.Context(), storage)
+		newHTTPAudit(auditor, log, r).write(r.Context(), audit.ActionClear, 0, err)
 		w.WriteHeader(status)
 		if err != nil {
-			logger.Warnf("clear request error: %w", err)
+			log.Warnf("clear request error: %v", err)
 		}
 	})
 
+	// Audit records are available only with admin token.
+	if auditor != nil && adminToken != "" {
+		router.With(adminAuth(adminToken, logger)).Get("/audit", func(w http.ResponseWriter, r *http.Request) {
+			log := logging.FromContext(r.Context(), logger)
+			data, status, err := GetAudit(r.Context(), auditor, r.URL.Query())
+			if err != nil {
+				w.WriteHeader(status)
+				log.Warnf("audit request error: %v", err)
+				return
+			}
+			w.Header().Set(contentType, applicationJSON)
+			if _, err = w.Write(data); err != nil {
+				log.Warnf(writeErrorString, err)
+			}
+		})
+	}
+
+	if admin != nil {
+		router.Mount("/admin", admin)
+	}
+
 	router.Mount("/debug", middleware.Profiler())
 	return router
 }
@@ -0,0 +1,32 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/gostuding/go-metrics/internal/server/mocks"
+	"github.com/gostuding/go-metrics/internal/server/storage"
+)
+
+func TestRouter_getAllMetricsError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	strg := mocks.NewMockStorage(ctrl)
+	strg.EXPECT().GetMetricsHTML(gomock.Any()).Return("", errors.New("storage error"))
+	core, logs := observer.New(zap.WarnLevel)
+	router := makeRouter(strg, zap.New(core).Sugar(), nil, nil, nil, nil, storage.NewSelfMetrics(), nil, "", nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	entries := logs.FilterMessageSnippet("get all metrics error").All()
+	require.Len(t, entries, 1, "error must be logged")
+	assert.Contains(t, entries[0].Message, "storage error", "storage error must be logged")
+}
@@ -9,11 +9,16 @@ import (
 	"os"
 	"os/signal"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
+	"github.com/gostuding/go-metrics/internal/agentkeys"
+	"github.com/gostuding/go-metrics/internal/logging"
 	pb "github.com/gostuding/go-metrics/internal/proto"
-	"github.com/gostuding/go-metrics/internal/server/interseptors"
+	"github.com/gostuding/go-metrics/internal/server/audit"
+	"github.com/gostuding/go-metrics/internal/server/storage"
+	"github.com/gostuding/go-metrics/internal/signing"
 	"google.golang.org/grpc"
 
 	"go.uber.org/zap"
@@ -21,17 +26,24 @@ import (
 
 const (
 	shutdownTimeout        = 10                       // timeout to stop server
-	stopServerString       = "stop server error: %w"  // internal value
-	stopStorageErrorString = "stop storage error: %w" //
+	stopServerString       = "stop server error: %v"  // internal value
+	stopStorageErrorString = "stop storage error: %v" //
 	storageFinishedString  = "Storage finished"
 )
 
 // Server is struct for object.
 type Server struct {
-	Config  *Config            // server's options
-	Storage Storage            // Storage interface
-	Logger  *zap.SugaredLogger // server's logger
-	srv     http.Server        // internal server
+	Config  *Config              // server's options
+	Storage Storage              // Storage interface
+	Logger  *zap.SugaredLogger   // server's logger
+	self    *storage.SelfMetrics // server's own metrics
+	timed   Storage              // Storage with operations timings
+	srv     http.Server          // internal server
+	handler atomic.Value         // current http.Handler, replaced on config reload
+	saver   saver                // save storage by interval gorutine
+	auditor audit.Auditor        // mutations audit, nil if audit is disabled
+	keys    *agentkeys.Registry  // agents keys, nil if only shared key is used
+	nonces  *signing.NonceCache  // used requests nonces, kept on config reload
 	mutex   sync.Mutex
 	isRun   bool // flag to check is server run
 }
@@ -46,7 +58,15 @@ type Storage interface {
 
 // NewServer creates new server object.
 func NewServer(config *Config, logger *zap.SugaredLogger, storage Storage) *Server {
-	return &Server{Config: config, Logger: logger, Storage: storage}
+	self := selfMetrics(storage)
+	return &Server{
+		Config:  config,
+		Logger:  logger,
+		Storage: storage,
+		self:    self,
+		timed:   newTimedStorage(storage, self),
+		nonces:  signing.NewNonceCache(),
+	}
 }
 
 func checkConfig(r bool, c *Config, l *zap.SugaredLogger, s Storage) error {
@@ -72,13 +92,15 @@ func (s *Server) RunServer() error {
 	if err := checkConfig(s.isRun, s.Config, s.Logger, s.Storage); err != nil {
 		return err
 	}
-	var subnet *net.IPNet
-	if s.Config.TrustedSubnet != "" {
-		_, mask, err := net.ParseCIDR(s.Config.TrustedSubnet)
-		if err != nil {
-			return fmt.Errorf("parse subnet error: %w", err)
-		}
-		subnet = mask
+	subnet, err := parseSubnet(s.Config.TrustedSubnet)
+	if err != nil {
+		return err
+	}
+	if s.keys, err = newKeys(s.Config.AgentKeysPath); err != nil {
+		return err
+	}
+	if s.auditor, err = audit.New(s.Config.Audit()); err != nil {
+		return fmt.Errorf("create audit error: %w", err)
 	}
 
 	s.Logger.Infoln("Run server at adress: ", s.Config.IPAddress)
@@ -88,9 +110,14 @@ func (s *Server) RunServer() error {
 	)
 	defer cancelFunc()
 	srvChan := make(chan error, 1)
+	verifier := signing.NewVerifier(s.Config.Signing(), s.nonces)
+	s.handler.Store(makeRouter(s.timed, s.Logger, s.keys.KeyFunc(s.Config.hashKey()), verifier, s.Config.PrivateKeys,
+		subnet, s.self, s.auditor, s.Config.AdminToken, s.admin(s.Config)))
 	s.srv = http.Server{
-		Addr:    s.Config.IPAddress,
-		Handler: makeRouter(s.Storage, s.Logger, []byte(s.Config.Key), s.Config.PrivateKey, subnet),
+		Addr: s.Config.IPAddress,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s.handler.Load().(http.Handler).ServeHTTP(w, r) //nolint:forcetypeassert //<-only type
+		}),
 	}
 	s.mutex.Lock()
 	s.isRun = true
@@ -103,8 +130,9 @@ func (s *Server) RunServer() error {
 		}
 	}()
 	if s.Config.ConnectDBString == "" {
-		go saveStorageInterval(ctx, s.Config.StoreInterval, s.Storage, s.Logger)
+		s.saver.start(ctx, s.Config.StoreInterval, s.Storage, s.Logger)
 	}
+	go watchReload(ctx, s.Logger, s.reload)
 	return <-srvChan
 }
 
@@ -121,6 +149,7 @@ func (s *Server) StopServer() error {
 	if err := s.srv.Shutdown(shtCtx); err != nil {
 		return fmt.Errorf("shutdown server erorr: %w", err)
 	}
+	closeAudit(s.auditor, s.Logger)
 	s.mutex.Lock()
 	s.isRun = false
 	s.mutex.Unlock()
@@ -138,7 +167,8 @@ func (s *Server) startServe(srvChan chan error) {
 	if errors.Is(err, http.ErrServerClosed) {
 		srvChan <- nil
 	} else {
-		s.Logger.Warnf("server listen error: %w", err)
+		closeAudit(s.auditor, s.Logger)
+		s.Logger.Warnf("server listen error: %v", err)
 		srvChan <- err
 	}
 	s.Logger.Debugln("Server listen finished")
@@ -167,7 +197,7 @@ func saveStorageInterval(
 		case <-ticker.C:
 			err := storage.Save()
 			if err != nil {
-				logger.Warnf("save storage error: %w", err)
+				logger.Warnf("save storage error: %v", err)
 			} else {
 				logger.Info("save storage by interval")
 			}
@@ -177,30 +207,44 @@ func saveStorageInterval(
 
 type RPCServer struct {
 	pb.UnimplementedMetricsServer
-	Config  *Config            // server's options
-	Storage Storage            // Storage interface
-	Logger  *zap.SugaredLogger // server's logger
-	srv     *grpc.Server       //
-	isRun   bool               // flag to check is server run
+	Config      *Config              // server's options
+	Storage     Storage              // Storage interface
+	Logger      *zap.SugaredLogger   // server's logger
+	self        *storage.SelfMetrics // server's own metrics
+	timed       Storage              // Storage with operations timings
+	srv         *grpc.Server         //
+	interceptor atomic.Value         // current grpc.UnaryServerInterceptor, replaced on config reload
+	saver       saver                // save storage by interval gorutine
+	auditor     audit.Auditor        // mutations audit, nil if audit is disabled
+	keys        *agentkeys.Registry  // agents keys, nil if only shared key is used
+	nonces      *signing.NonceCache  // used requests nonces, kept on config reload
+	mutex       sync.Mutex           //
+	isRun       bool                 // flag to check is server run
 }
 
 func (s *RPCServer) AddMetrics(ctx context.Context, in *pb.MetricsRequest) (*pb.MetricsResponse, error) {
 	var response pb.MetricsResponse
-	s.Logger.Debugln("Update metrics bytes")
+	log := logging.FromContext(ctx, s.Logger)
+	log.Debugln("Update metrics bytes")
 
-	_, err := bytesErrorRepeater(ctx, s.Storage.UpdateJSONSlice, in.Metrics)
+	_, err := bytesErrorRepeater(ctx, s.timed.UpdateJSONSlice, in.Metrics)
+	newRPCAudit(ctx, s.auditor, log).write(ctx, audit.ActionUpdates, countMetrics(in.Metrics), err)
 	if err != nil {
-		s.Logger.Debugln("Update metrics error", err)
+		log.Debugln("Update metrics error", err)
 		response.Error = fmt.Sprintf("update metrics list error: %v", err)
 	}
 	return &response, nil
 }
 
 func NewRPCServer(config *Config, logger *zap.SugaredLogger, storage Storage) *RPCServer {
+	self := selfMetrics(storage)
 	return &RPCServer{
 		Config:  config,
 		Logger:  logger,
 		Storage: storage,
+		self:    self,
+		timed:   newTimedStorage(storage, self),
+		nonces:  signing.NewNonceCache(),
 	}
 }
 
@@ -208,17 +252,23 @@ func (s *RPCServer) RunServer() error {
 	if err := checkConfig(s.isRun, s.Config, s.Logger, s.Storage); err != nil {
 		return err
 	}
+	keys, err := newKeys(s.Config.AgentKeysPath)
+	if err != nil {
+		return err
+	}
+	s.keys = keys
+	auditor, err := audit.New(s.Config.Audit())
+	if err != nil {
+		return fmt.Errorf("create audit error: %w", err)
+	}
+	s.auditor = auditor
 	listen, err := net.Listen("tcp", s.Config.IPAddress)
 	if err != nil {
+		closeAudit(s.auditor, s.Logger)
 		return fmt.Errorf("start RPC server error: %w", err)
 	}
-	s.srv = grpc.NewServer(
-		grpc.ChainUnaryInterceptor(
-			interseptors.HashInterceptor([]byte(s.Config.Key)),
-			interseptors.GzipInterceptor,
-			interseptors.DecriptInterceptor(s.Config.PrivateKey),
-			interseptors.LogInterceptor(s.Logger),
-		))
+	s.interceptor.Store(s.makeInterceptor(s.Config))
+	s.srv = grpc.NewServer(grpc.UnaryInterceptor(s.intercept))
 	pb.RegisterMetricsServer(s.srv, s)
 
 	ctx, cancelFunc := signal.NotifyContext(
@@ -227,8 +277,9 @@ func (s *RPCServer) RunServer() error {
 	)
 	defer cancelFunc()
 	if s.Config.ConnectDBString == "" {
-		go saveStorageInterval(ctx, s.Config.StoreInterval, s.Storage, s.Logger)
+		s.saver.start(ctx, s.Config.StoreInterval, s.Storage, s.Logger)
 	}
+	go watchReload(ctx, s.Logger, s.reload)
 	s.Logger.Debugln("Server gRPC run at", s.Config.IPAddress)
 	s.isRun = true
 	go func() {
@@ -254,5 +305,36 @@ func (s *RPCServer) StopServer() error {
 		s.Logger.Debugln(storageFinishedString)
 	}
 	s.srv.Stop()
+	closeAudit(s.auditor, s.Logger)
 	return nil
 }
+
+// newKeys is private func. Creates agents keys registry, nil if path is empty.
+func newKeys(path string) (*agentkeys.Registry, error) {
+	if path == "" {
+		return nil, nil //nolint:nilnil //<-only shared key is used
+	}
+	keys, err := agentkeys.NewRegistry(path)
+	if err != nil {
+		return nil, fmt.Errorf("create agents keys registry error: %w", err)
+	}
+	return keys, nil
+}
+
+// admin is private func. Returns admin API handler, nil if agents keys registry or admin token is not set.
+func (s *Server) admin(cfg *Config) http.Handler {
+	if s.keys == nil || cfg.AdminToken == "" {
+		return nil
+	}
+	return makeAdminRouter(s.keys, cfg.AdminToken, s.Logger)
+}
+
+// closeAudit is private func. Closes audit if it is enabled, errors are logged.
+func closeAudit(auditor audit.Auditor, logger *zap.SugaredLogger) {
+	if auditor == nil {
+		return
+	}
+	if err := auditor.Close(); err != nil {
+		logger.Warnf("close audit error: %v", err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/gostuding/go-metrics/internal/logging"
 	"github.com/gostuding/go-metrics/internal/server/storage"
 	"github.com/stretchr/testify/assert"
 )
@@ -21,7 +22,7 @@ func (s *saverInterface) Save() error {
 }
 
 func createMemServer(ip string) (*Server, error) {
-	logger, err := NewLogger()
+	logger, err := NewLogger(logging.Options{})
 	if err != nil {
 		return nil, fmt.Errorf("logger create error: %w", err)
 	}
@@ -133,7 +134,7 @@ func TestServer_RunServer(t *testing.T) {
 func Test_saveStorageInterval(t *testing.T) {
 	var sleepTime = 3
 	var wantValue = 1
-	logger, err := NewLogger()
+	logger, err := NewLogger(logging.Options{})
 	assert.NoError(t, err, "create logger error")
 	strg := saverInterface{Count: 0}
 	ctx, cancelFunc := context.WithCancel(context.Background())
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/gostuding/go-metrics/internal/server/storage"
+)
+
+// repeaterRetries is count of storage requests retries in bytesErrorRepeater.
+var repeaterRetries atomic.Int64
+
+// selfMetrics is private func. Returns storage's SelfMetrics, so server's own metrics
+// are returned with storage metrics. If storage doesn't have it, new SelfMetrics is created.
+func selfMetrics(s Storage) *storage.SelfMetrics {
+	self := storage.NewSelfMetrics()
+	if v, ok := s.(interface{ SelfMetrics() *storage.SelfMetrics }); ok && v.SelfMetrics() != nil {
+		self = v.SelfMetrics()
+	}
+	self.CounterFunc("repeater_retries_total", repeaterRetries.Load)
+	return self
+}
+
+// timedStorage is private type. Observes storage operations timings.
+// Save is observed by storage, because it knows saved data size.
+type timedStorage struct {
+	Storage
+	self *storage.SelfMetrics
+}
+
+// newTimedStorage is private func. Wraps storage for operations timings.
+func newTimedStorage(s Storage, self *storage.SelfMetrics) *timedStorage {
+	return &timedStorage{Storage: s, self: self}
+}
+
+// observe is private func. Observes operation duration and counts errors.
+func (t *timedStorage) observe(op string, start time.Time, err error) {
+	t.self.ObserveSince("storage_"+op+"_seconds", start)
+	if err != nil {
+		t.self.Add("storage_"+op+"_errors_total", 1)
+	}
+}
+
+// Update observes storage Update duration.
+func (t *timedStorage) Update(ctx context.Context, mType, mName, mValue string) error {
+	start := time.Now()
+	err := t.Storage.Update(ctx, mType, mName, mValue)
+	t.observe("update", start, err)
+	return err //nolint:wrapcheck //<-senselessly
+}
+
+// UpdateJSON observes storage UpdateJSON duration.
+func (t *timedStorage) UpdateJSON(ctx context.Context, data []byte) ([]byte, error) {
+	start := time.Now()
+	value, err := t.Storage.UpdateJSON(ctx, data)
+	t.observe("update_json", start, err)
+	return value, err //nolint:wrapcheck //<-senselessly
+}
+
+// UpdateJSONSlice observes storage UpdateJSONSlice duration.
+func (t *timedStorage) UpdateJSONSlice(ctx context.Context, data []byte) ([]byte, error) {
+	start := time.Now()
+	value, err := t.Storage.UpdateJSONSlice(ctx, data)
+	t.observe("update_json_slice", start, err)
+	return value, err //nolint:wrapcheck //<-senselessly
+}
+
+// GetMetric observes storage GetMetric duration.
+func (t *timedStorage) GetMetric(ctx context.Context, mType, mName string) (string, error) {
+	start := time.Now()
+	value, err := t.Storage.GetMetric(ctx, mType, mName)
+	t.observe("get_metric", start, err)
+	return value, err //nolint:wrapcheck //<-senselessly
+}
+
+// GetMetricJSON observes storage GetMetricJSON duration.
+func (t *timedStorage) GetMetricJSON(ctx context.Context, data []byte) ([]byte, error) {
+	start := time.Now()
+	value, err := t.Storage.GetMetricJSON(ctx, data)
+	t.observe("get_metric_json", start, err)
+	return value, err //nolint:wrapcheck //<-senselessly
+}
+
+// GetMetricsHTML observes storage GetMetricsHTML duration.
+func (t *timedStorage) GetMetricsHTML(ctx context.Context) (string, error) {
+	start := time.Now()
+	value, err := t.Storage.GetMetricsHTML(ctx)
+	t.observe("get_metrics_html", start, err)
+	return value, err //nolint:wrapcheck //<-senselessly
+}
+
+// GetMetricsPrometheus observes storage GetMetricsPrometheus duration.
+func (t *timedStorage) GetMetricsPrometheus(ctx context.Context) (string, error) {
+	start := time.Now()
+	value, err := t.Storage.GetMetricsPrometheus(ctx)
+	t.observe("get_metrics_prometheus", start, err)
+	return value, err //nolint:wrapcheck //<-senselessly
+}
+
+// PingDB observes storage PingDB duration.
+func (t *timedStorage) PingDB(ctx context.Context) error {
+	start := time.Now()
+	err := t.Storage.PingDB(ctx)
+	t.observe("ping", start, err)
+	return err //nolint:wrapcheck //<-senselessly
+}
+
+// Clear observes storage Clear duration.
+func (t *timedStorage) Clear(ctx context.Context) error {
+	start := time.Now()
+	err := t.Storage.Clear(ctx)
+	t.observe("clear", start, err)
+	return err //nolint:wrapcheck //<-senselessly
+}
@@ -143,6 +143,67 @@ func ExampleSQLStorage_UpdateJSONSlice() {
 	// Update json slice success
 }
 
+func ExampleSQLStorage_UpdateJSONSlice_rejected() {
+	sqlStrg, err := NewSQLStorage(dbDSN)
+	if err != nil {
+		fmt.Printf("Create sql storage error: %v", err)
+		return
+	}
+	if err := sqlStrg.Clear(ctx); err != nil {
+		fmt.Printf("storage clear error: %v", err)
+		return
+	}
+	// Rejected metrics are reported as ERROR lines like in MemStorage, other metrics are updated.
+	jSlice := `[{"id":"pause","type":"histogram","histogram":{"buckets":[1,2],"counts":[1,0,1],"count":2,"sum":3.5}},
+		{"id":"pause","type":"histogram","histogram":{"buckets":[5],"counts":[1,0],"count":1,"sum":1}},
+		{"id":"pause","type":"histogram","histogram":{"buckets":[1],"counts":[1],"count":1,"sum":1}}]`
+	resp, err := sqlStrg.UpdateJSONSlice(ctx, []byte(jSlice))
+	if err != nil {
+		fmt.Printf("update storage by JSON slice (%s) error: %v", jSlice, err)
+		return
+	}
+	for _, line := range strings.Split(string(resp), "\n") {
+		if strings.Contains(line, "ERROR") {
+			fmt.Println(line)
+		}
+	}
+
+	// Output:
+	// 2. 'pause' update ERROR: histogram 'pause' update error: histogram buckets mismatch
+	// 3. 'pause' update ERROR: histogram counts length must be 2
+}
+
+func ExampleSQLStorage_GetMetricsPrometheus_labels() {
+	sqlStrg, err := NewSQLStorage(dbDSN)
+	if err != nil {
+		fmt.Printf("Create sql storage error: %v", err)
+		return
+	}
+	if err := sqlStrg.Clear(ctx); err != nil {
+		fmt.Printf("storage clear error: %v", err)
+		return
+	}
+	// Labels of the last update are returned with metric values.
+	jSlice := `[{"id":"polls","type":"counter","delta":1,"labels":{"host":"web1"}}]`
+	if _, err = sqlStrg.UpdateJSONSlice(ctx, []byte(jSlice)); err != nil {
+		fmt.Printf("update storage by JSON slice (%s) error: %v", jSlice, err)
+		return
+	}
+	text, err := sqlStrg.GetMetricsPrometheus(ctx)
+	if err != nil {
+		fmt.Printf("get metrics error: %v", err)
+		return
+	}
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(line, "polls") {
+			fmt.Println(line)
+		}
+	}
+
+	// Output:
+	// polls{host="web1"} 1
+}
+
 func ExampleSQLStorage_GetMetricJSON() {
 	sqlStrg, err := NewSQLStorage(dbDSN)
 	if err != nil {
@@ -0,0 +1,192 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	histogramType      = "histogram" // name for histogram type
+	histogramTableName = "histograms"
+	prometheusInf      = "+Inf"
+)
+
+// errBucketsMismatch is returned when merged histograms have different buckets.
+var errBucketsMismatch = errors.New("histogram buckets mismatch")
+
+// defaultBuckets are used for histogram created by one value update.
+var defaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10} //nolint:gomnd //<-def values
+
+// Histogram contains observations count in buckets.
+// Counts has one item more then Buckets, the last item is count of observations greater then the last bucket.
+type histogram struct {
+	Buckets []float64 `json:"buckets"` // buckets upper bounds in ascending order
+	Counts  []uint64  `json:"counts"`  // observations count in each bucket, not cumulative
+	Count   uint64    `json:"count"`   // observations count
+	Sum     float64   `json:"sum"`     // observations sum
+}
+
+// newHistogram is private func. Creates empty histogram with buckets.
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{
+		Buckets: append([]float64{}, buckets...),
+		Counts:  make([]uint64, len(buckets)+1),
+	}
+}
+
+// validate is private func. Checks histogram structure.
+func (h *histogram) validate() error {
+	if len(h.Counts) != len(h.Buckets)+1 {
+		return fmt.Errorf("histogram counts length must be %d", len(h.Buckets)+1)
+	}
+	for i := 1; i < len(h.Buckets); i++ {
+		if h.Buckets[i] <= h.Buckets[i-1] {
+			return errors.New("histogram buckets must be in ascending order")
+		}
+	}
+	var count uint64
+	for _, c := range h.Counts {
+		count += c
+	}
+	if count != h.Count {
+		return fmt.Errorf("histogram count %d is not equal to buckets counts sum %d", h.Count, count)
+	}
+	return nil
+}
+
+// observe is private func. Adds one value to histogram.
+func (h *histogram) observe(value float64) {
+	i := sort.SearchFloat64s(h.Buckets, value)
+	h.Counts[i]++
+	h.Count++
+	h.Sum += value
+}
+
+// merge is private func. Adds other histogram observations to h.
+// Histograms must have the same buckets.
+func (h *histogram) merge(other *histogram) error {
+	if len(h.Buckets) != len(other.Buckets) {
+		return errBucketsMismatch
+	}
+	for i, b := range h.Buckets {
+		if b != other.Buckets[i] {
+			return errBucketsMismatch
+		}
+	}
+	for i, c := range other.Counts {
+		h.Counts[i] += c
+	}
+	h.Count += other.Count
+	h.Sum += other.Sum
+	return nil
+}
+
+// String returns histogram as text for HTML.
+func (h *histogram) String() string {
+	items := make([]string, 0, len(h.Counts))
+	for i, c := range h.Counts {
+		items = append(items, fmt.Sprintf("le %s: %d", bucketBound(h.Buckets, i), c))
+	}
+	return fmt.Sprintf("count: %d, sum: %f, %s", h.Count, h.Sum, strings.Join(items, ", "))
+}
+
+// bucketBound is private func. Returns bucket upper bound as string.
+func bucketBound(buckets []float64, i int) string {
+	if i >= len(buckets) {
+		return prometheusInf
+	}
+	return strconv.FormatFloat(buckets[i], 'f', -1, 64)
+}
+
+// updateHistogram is private func. Merges or creates histogram in items.
+func updateHistogram(items map[string]*histogram, name string, h *histogram) (*histogram, error) {
+	if err := h.validate(); err != nil {
+		return nil, err
+	}
+	item, ok := items[name]
+	if !ok {
+		item = newHistogram(h.Buckets)
+	}
+	if err := item.merge(h); err != nil {
+		return nil, fmt.Errorf("histogram '%s' update error: %w", name, err)
+	}
+	items[name] = item
+	return item, nil
+}
+
+// parseHistogram is private func. Converts JSON to histogram.
+func parseHistogram(data []byte) (*histogram, error) {
+	var h histogram
+	if err := json.Unmarshal(data, &h); err != nil {
+		return nil, makeError(jsonConverError, err)
+	}
+	return &h, nil
+}
+
+// prometheusName is private func. Replaces symbols which are not allowed in Prometheus metric name.
+func prometheusName(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_', r == ':':
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteRune('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
+
+// prometheusFloat is private func. Formats value in Prometheus text format.
+func prometheusFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return prometheusInf
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+// makePrometheus is private func. Returns metrics in Prometheus text exposition format with metrics labels.
+func makePrometheus(
+	gauges map[string]float64, counters map[string]int64, histograms map[string]*histogram, labels metricLabels,
+) string {
+	var b strings.Builder
+	for _, key := range getSortedKeysFloat(gauges) {
+		name := prometheusName(key)
+		fmt.Fprintf(&b, "# TYPE %s gauge\n%s%s %s\n", name, name, labels.get(gaugeType, key),
+			prometheusFloat(gauges[key]))
+	}
+	for _, key := range getSortedKeysInt(counters) {
+		name := prometheusName(key)
+		fmt.Fprintf(&b, "# TYPE %s counter\n%s%s %d\n", name, name, labels.get(counterType, key), counters[key])
+	}
+	for _, key := range getSortedKeysHistogram(histograms) {
+		h := histograms[key]
+		name := prometheusName(key)
+		fmt.Fprintf(&b, "# TYPE %s histogram\n", name)
+		var cumulative uint64
+		for i, c := range h.Counts {
+			cumulative += c
+			fmt.Fprintf(&b, "%s_bucket%s %d\n", name,
+				labels.get(histogramType, key, labelBucket, bucketBound(h.Buckets, i)), cumulative)
+		}
+		l := labels.get(histogramType, key)
+		fmt.Fprintf(&b, "%s_sum%s %s\n%s_count%s %d\n", name, l, prometheusFloat(h.Sum), name, l, h.Count)
+	}
+	return b.String()
+}
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_histogram_validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		h       histogram
+		wantErr bool
+	}{
+		{
+			name: "Correct histogram",
+			h:    histogram{Buckets: []float64{1, 2}, Counts: []uint64{1, 0, 2}, Count: 3, Sum: 10},
+		},
+		{
+			name:    "Counts length error",
+			h:       histogram{Buckets: []float64{1, 2}, Counts: []uint64{1, 0}, Count: 1},
+			wantErr: true,
+		},
+		{
+			name:    "Buckets order error",
+			h:       histogram{Buckets: []float64{2, 1}, Counts: []uint64{1, 0, 0}, Count: 1},
+			wantErr: true,
+		},
+		{
+			name:    "Count error",
+			h:       histogram{Buckets: []float64{1}, Counts: []uint64{1, 1}, Count: 1},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantErr, tt.h.validate() != nil, "validate error")
+		})
+	}
+}
+
+func TestMemStorage_histogram(t *testing.T) {
+	ms, err := NewMemStorage(false, "", 1)
+	assert.NoError(t, err, "create storage error")
+	ctx := context.Background()
+	data := `[{"id":"pause","type":"histogram","histogram":{"buckets":[1,2],"counts":[1,0,1],"count":2,"sum":3.5}},
+		{"id":"pause","type":"histogram","histogram":{"buckets":[1,2],"counts":[0,1,0],"count":1,"sum":1.5}},
+		{"id":"pause","type":"histogram","histogram":{"buckets":[5],"counts":[1,0],"count":1,"sum":1}}]`
+	resp, err := ms.UpdateJSONSlice(ctx, []byte(data))
+	assert.NoError(t, err, "update error")
+	assert.Contains(t, string(resp), "3. 'pause' update ERROR", "buckets mismatch error expected")
+	assert.Equal(t, []uint64{1, 1, 1}, ms.Histograms["pause"].Counts, "merged counts")
+	assert.Equal(t, uint64(3), ms.Histograms["pause"].Count, "merged count")
+
+	assert.NoError(t, ms.Update(ctx, histogramType, "latency", "0.2"), "update by value error")
+	value, err := ms.GetMetric(ctx, histogramType, "latency")
+	assert.NoError(t, err, "get metric error")
+	assert.Contains(t, value, `"count":1`, "histogram value")
+
+	html, err := ms.GetMetricsHTML(ctx)
+	assert.NoError(t, err, "get html error")
+	assert.Contains(t, html, "Histograms", "html histograms section")
+
+	text, err := ms.GetMetricsPrometheus(ctx)
+	assert.NoError(t, err, "get prometheus error")
+	for _, line := range []string{
+		"# TYPE pause histogram",
+		`pause_bucket{le="1"} 1`,
+		`pause_bucket{le="2"} 2`,
+		`pause_bucket{le="+Inf"} 3`,
+		"pause_sum 5",
+		"pause_count 3",
+	} {
+		assert.True(t, strings.Contains(text, line+"\n"), "prometheus line '%s' not found", line)
+	}
+}
+
+func Test_prometheusName(t *testing.T) {
+	assert.Equal(t, "runtime_gc_heap_allocs_bytes", prometheusName("runtime.gc.heap.allocs.bytes"), "name error")
+	assert.Equal(t, "_1xx", prometheusName("1xx"), "name error")
+}
@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	labelsTableName = "labels" // table name in database
+	labelBucket     = "le"     // histogram bucket label name
+)
+
+// labelName is Prometheus label name pattern.
+var labelName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// labelValueReplacer escapes label values in Prometheus text format.
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// metricLabels contains labels of metrics by labelsKey.
+type metricLabels map[string]map[string]string
+
+// checkLabels is private func. Checks labels names.
+// Names with '__' prefix and 'le' name are reserved by Prometheus.
+func checkLabels(labels map[string]string) error {
+	for name := range labels {
+		if !labelName.MatchString(name) || strings.HasPrefix(name, "__") || name == labelBucket {
+			return fmt.Errorf("label name '%s' is incorrect", name)
+		}
+	}
+	return nil
+}
+
+// labelsKey is private func. Returns key of metric labels.
+func labelsKey(mType, name string) string {
+	return mType + "/" + name
+}
+
+// set is private func. Saves labels of metric, labels are deleted if metric has no labels.
+func (l metricLabels) set(m metric) {
+	if len(m.Labels) == 0 {
+		delete(l, labelsKey(m.MType, m.ID))
+		return
+	}
+	labels := make(map[string]string, len(m.Labels))
+	for k, v := range m.Labels {
+		labels[k] = v
+	}
+	l[labelsKey(m.MType, m.ID)] = labels
+}
+
+// copy is private func. Returns copy of labels map, labels of metric are not changed by set.
+func (l metricLabels) copy() metricLabels {
+	items := make(metricLabels, len(l))
+	for k, v := range l {
+		items[k] = v
+	}
+	return items
+}
+
+// get is private func. Returns metric labels in Prometheus format, empty string if metric has no labels.
+// Pairs of name and value are added after metric labels.
+func (l metricLabels) get(mType, name string, pairs ...string) string {
+	labels := l[labelsKey(mType, name)]
+	names := make([]string, 0, len(labels))
+	for k := range labels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	items := make([]string, 0, len(names)+len(pairs)/2)
+	for _, k := range names {
+		items = append(items, fmt.Sprintf(`%s="%s"`, k, labelValueReplacer.Replace(labels[k])))
+	}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		items = append(items, fmt.Sprintf(`%s="%s"`, pairs[i], labelValueReplacer.Replace(pairs[i+1])))
+	}
+	if len(items) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(items, ",") + "}"
+}
+
+// updateLabels is private func. Saves labels of metrics in database, labels are deleted if metric has no labels.
+// The last labels are saved if metrics list contains the same metric several times.
+func (ms *SQLStorage) updateLabels(ctx context.Context, metrics []metric, connect SQLQueryInterface) error {
+	items := make(metricLabels)
+	deleted := make(map[string]bool)
+	for _, m := range metrics {
+		key := labelsKey(m.MType, m.ID)
+		items.set(m)
+		_, ok := items[key]
+		deleted[key] = !ok
+	}
+	values := make(map[string]string, len(items))
+	for key, labels := range items {
+		data, err := json.Marshal(labels)
+		if err != nil {
+			return makeError(jsonConverError, err)
+		}
+		values[key] = string(data)
+	}
+	if len(values) > 0 {
+		rs := make([]string, 0, len(values))
+		args := make([]any, 0, len(values)*2) //nolint:gomnd //<-name and value
+		for key, value := range values {
+			rs = append(rs, fmt.Sprintf("($%d, $%d)", len(args)+1, len(args)+2)) //nolint:gomnd //<-def values
+			args = append(args, key, value)
+		}
+		_, err := connect.ExecContext(ctx, "INSERT INTO "+labelsTableName+" (name, value) values "+
+			strings.Join(rs, sqlValueSpliter)+" ON CONFLICT (name) DO UPDATE SET value=EXCLUDED.value;", args...)
+		if err != nil {
+			return fmt.Errorf("update labels error: %w", err)
+		}
+	}
+	rs := make([]string, 0, len(deleted))
+	args := make([]any, 0, len(deleted))
+	for key, ok := range deleted {
+		if ok {
+			args = append(args, key)
+			rs = append(rs, fmt.Sprintf("$%d", len(args)))
+		}
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	_, err := connect.ExecContext(ctx, "DELETE FROM "+labelsTableName+" WHERE name IN ("+
+		strings.Join(rs, sqlValueSpliter)+");", args...)
+	if err != nil {
+		return fmt.Errorf("delete labels error: %w", err)
+	}
+	return nil
+}
+
+// getLabels is private func. Returns labels of all metrics from database.
+func (ms *SQLStorage) getLabels(ctx context.Context) (metricLabels, error) {
+	rows, err := ms.con.QueryContext(ctx, "Select name, value from "+labelsTableName+";")
+	if err != nil {
+		return nil, fmt.Errorf("get labels query error: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck //<-senselessly
+	items := make(metricLabels)
+	for rows.Next() {
+		var key string
+		var data []byte
+		if err = rows.Scan(&key, &data); err != nil {
+			return nil, fmt.Errorf("scan labels error: %w", err)
+		}
+		var labels map[string]string
+		if err = json.Unmarshal(data, &labels); err != nil {
+			return nil, makeError(jsonConverError, err)
+		}
+		items[key] = labels
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("get labels rows error: %w", err)
+	}
+	return items, nil
+}
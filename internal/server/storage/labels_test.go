@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_checkLabels(t *testing.T) {
+	assert.NoError(t, checkLabels(nil), "empty labels")
+	assert.NoError(t, checkLabels(map[string]string{"host": "web1", "_env": "prod"}), "correct labels")
+	for _, name := range []string{"", "1host", "host-name", "le", "__name__"} {
+		assert.Error(t, checkLabels(map[string]string{name: "value"}), "label name '%s' error expected", name)
+	}
+}
+
+func TestMemStorage_labels(t *testing.T) {
+	ms, err := NewMemStorage(false, "", 1)
+	require.NoError(t, err, "create storage error")
+	ctx := context.Background()
+	data := `[{"id":"polls","type":"counter","delta":2,"labels":{"host":"web\"1","env":"prod"}},
+		{"id":"heap","type":"gauge","value":1.5,"labels":{"host":"web1"}},
+		{"id":"pause","type":"histogram","labels":{"host":"web1"},
+			"histogram":{"buckets":[1],"counts":[1,0],"count":1,"sum":0.5}},
+		{"id":"bad","type":"gauge","value":1,"labels":{"le":"1"}}]`
+	resp, err := ms.UpdateJSONSlice(ctx, []byte(data))
+	require.NoError(t, err, "update error")
+	assert.Contains(t, string(resp), "4. 'bad' update ERROR", "reserved label name error expected")
+
+	text, err := ms.GetMetricsPrometheus(ctx)
+	require.NoError(t, err, "get prometheus error")
+	for _, line := range []string{
+		`polls{env="prod",host="web\"1"} 2`,
+		`heap{host="web1"} 1.5`,
+		`pause_bucket{host="web1",le="1"} 1`,
+		`pause_bucket{host="web1",le="+Inf"} 1`,
+		`pause_sum{host="web1"} 0.5`,
+		`pause_count{host="web1"} 1`,
+	} {
+		assert.Contains(t, text, line+"\n", "prometheus line")
+	}
+	assert.NotContains(t, text, "bad", "rejected metric")
+
+	html, err := ms.GetMetricsHTML(ctx)
+	require.NoError(t, err, "get html error")
+	assert.Contains(t, html, `'heap'{host="web1"}= 1.500000`, "html labels")
+
+	_, err = ms.UpdateJSON(ctx, []byte(`{"id":"heap","type":"gauge","value":2}`))
+	require.NoError(t, err, "update without labels error")
+	text, err = ms.GetMetricsPrometheus(ctx)
+	require.NoError(t, err, "get prometheus error")
+	assert.Contains(t, text, "heap 2\n", "labels must be removed by update without labels")
+	assert.Contains(t, text, `polls{env="prod",host="web\"1"} 2`, "labels of other metrics must be kept")
+
+	require.NoError(t, ms.Clear(ctx), "clear error")
+	assert.Empty(t, ms.Labels, "labels must be cleared")
+}
@@ -10,6 +10,7 @@ import (
 	"sort"
 	"strconv"
 	"sync"
+	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 )
@@ -31,11 +32,11 @@ func makeError(errorType int, vals ...any) error {
 	case converError:
 		return fmt.Errorf("%s value convert error: %w", vals...)
 	case metricTypeIncorrect:
-		return errors.New("metric type incorrect. Availible types are: guage or counter")
+		return errors.New("metric type incorrect. Availible types are: guage, counter or histogram")
 	case metricNotFoud:
 		return fmt.Errorf("metric '%s' with type '%s' not found", vals...)
 	case metricTypeError:
-		return errors.New("metric type error, use counter like int64 or gauge like float64")
+		return errors.New("metric type error, use counter like int64, gauge like float64 or histogram")
 	case saveMetricError:
 		return fmt.Errorf("save metric error: %w", vals...)
 	case jsonConverError:
@@ -48,20 +49,25 @@ func makeError(errorType int, vals ...any) error {
 type (
 	// MemStorage contains metrics data in memory.
 	MemStorage struct {
-		Gauges       map[string]float64 `json:"gauges"`   // gauge metrics
-		Counters     map[string]int64   `json:"counters"` // counter metrics
-		SavePath     string             `json:"-"`        // path to file for save storage data
-		SaveInterval int                `json:"-"`        // save data interval. If is 0 - storage saves in runtime.
-		mx           sync.RWMutex       `json:"-"`        // mutex for storage
-		Restore      bool               `json:"-"`        // flag for restore data from file
+		Gauges       map[string]float64    `json:"gauges"`     // gauge metrics
+		Counters     map[string]int64      `json:"counters"`   // counter metrics
+		Histograms   map[string]*histogram `json:"histograms"` // histogram metrics
+		Labels       metricLabels          `json:"labels"`     // metrics labels by type and name
+		SavePath     string                `json:"-"`          // path to file for save storage data
+		SaveInterval int                   `json:"-"`          // save data interval. If is 0 - storage saves in runtime.
+		mx           sync.RWMutex          `json:"-"`          // mutex for storage
+		Restore      bool                  `json:"-"`          // flag for restore data from file
+		self         *SelfMetrics          `json:"-"`          // server's own metrics
 	}
 
 	// Metric contains data about one metric.
 	metric struct {
-		Delta *int64   `json:"delta,omitempty"` // counter value
-		Value *float64 `json:"value,omitempty"` // gauge value
-		ID    string   `json:"id"`              // name
-		MType string   `json:"type"`            // can be 'gauge' or 'counter'
+		Delta     *int64            `json:"delta,omitempty"`     // counter value
+		Value     *float64          `json:"value,omitempty"`     // gauge value
+		Histogram *histogram        `json:"histogram,omitempty"` // histogram value
+		Labels    map[string]string `json:"labels,omitempty"`    // static labels of agent
+		ID        string            `json:"id"`                  // name
+		MType     string            `json:"type"`                // can be 'gauge', 'counter' or 'histogram'
 	}
 )
 
@@ -72,13 +78,21 @@ func NewMemStorage(restore bool, filePath string, saveInterval int) (*MemStorage
 	storage := MemStorage{
 		Gauges:       make(map[string]float64),
 		Counters:     make(map[string]int64),
+		Histograms:   make(map[string]*histogram),
+		Labels:       make(metricLabels),
 		Restore:      restore,
 		SavePath:     filePath,
 		SaveInterval: saveInterval,
+		self:         NewSelfMetrics(),
 	}
 	return &storage, storage.restore()
 }
 
+// SelfMetrics returns server's own metrics, which are returned with storage metrics.
+func (ms *MemStorage) SelfMetrics() *SelfMetrics {
+	return ms.self
+}
+
 // Update creates or updates metric value in storage.
 // Context doesn't have mean. Used to satisfy the interface.
 func (ms *MemStorage) Update(
@@ -87,6 +101,9 @@ func (ms *MemStorage) Update(
 	mName string,
 	mValue string,
 ) error {
+	if err := checkName(mName); err != nil {
+		return err
+	}
 	switch mType {
 	case gaugeType:
 		val, err := strconv.ParseFloat(mValue, 64)
@@ -104,6 +121,14 @@ func (ms *MemStorage) Update(
 		ms.mx.Lock()
 		ms.Counters[mName] += val
 		ms.mx.Unlock()
+	case histogramType:
+		val, err := strconv.ParseFloat(mValue, 64)
+		if err != nil {
+			return makeError(converError, histogramType, err)
+		}
+		ms.mx.Lock()
+		ms.observe(mName, val)
+		ms.mx.Unlock()
 	default:
 		return makeError(metricTypeIncorrect)
 	}
@@ -120,6 +145,9 @@ func (ms *MemStorage) GetMetric(
 	mType string,
 	mName string,
 ) (string, error) {
+	if value, ok, err := ms.self.value(mType, mName); ok {
+		return value, err
+	}
 	ms.mx.RLock()
 	defer ms.mx.RUnlock()
 	switch mType {
@@ -135,6 +163,14 @@ func (ms *MemStorage) GetMetric(
 				return strconv.FormatInt(val, 10), nil
 			}
 		}
+	case histogramType:
+		if h, ok := ms.Histograms[mName]; ok {
+			data, err := json.Marshal(h)
+			if err != nil {
+				return "", makeError(jsonConverError, err)
+			}
+			return string(data), nil
+		}
 	}
 	return "", makeError(metricNotFoud, mName, mType)
 }
@@ -142,21 +178,36 @@ func (ms *MemStorage) GetMetric(
 // GetMetricsHTML returns all metrics values as HTML string.
 // Context doesn't have mean. Used to satisfy the interface.
 func (ms *MemStorage) GetMetricsHTML(ctx context.Context) (string, error) {
-	gauges := make([]string, 0, len(ms.Gauges))
-	counters := make([]string, 0, len(ms.Counters))
 	ms.mx.RLock()
-	defer ms.mx.RUnlock()
-	for _, key := range getSortedKeysFloat(ms.Gauges) {
-		gauges = append(gauges, fmt.Sprintf("'%s'= %f", key, ms.Gauges[key]))
-	}
-	for _, key := range getSortedKeysInt(ms.Counters) {
-		counters = append(counters, fmt.Sprintf("'%s'= %d", key, ms.Counters[key]))
-	}
+	gaugeItems, counterItems, histogramItems := ms.self.withSelf(ms.Gauges, ms.Counters, ms.Histograms)
+	labels := ms.Labels.copy()
+	ms.mx.RUnlock()
+	gauges := make([]string, 0, len(gaugeItems))
+	counters := make([]string, 0, len(counterItems))
+	for _, key := range getSortedKeysFloat(gaugeItems) {
+		gauges = append(gauges, fmt.Sprintf("'%s'%s= %f", key, labels.get(gaugeType, key), gaugeItems[key]))
+	}
+	for _, key := range getSortedKeysInt(counterItems) {
+		counters = append(counters, fmt.Sprintf("'%s'%s= %d", key, labels.get(counterType, key), counterItems[key]))
+	}
+	histograms := make([]string, 0, len(histogramItems))
+	for _, key := range getSortedKeysHistogram(histogramItems) {
+		histograms = append(histograms,
+			fmt.Sprintf("'%s'%s= %s", key, labels.get(histogramType, key), histogramItems[key]))
+	}
+	return makeHTML(&gauges, &counters, &histograms), nil
+}
 
-	return makeHTML(&gauges, &counters), nil
+// GetMetricsPrometheus returns all metrics values in Prometheus text format.
+// Context doesn't have mean. Used to satisfy the interface.
+func (ms *MemStorage) GetMetricsPrometheus(ctx context.Context) (string, error) {
+	ms.mx.RLock()
+	defer ms.mx.RUnlock()
+	gauges, counters, histograms := ms.self.withSelf(ms.Gauges, ms.Counters, ms.Histograms)
+	return makePrometheus(gauges, counters, histograms, ms.Labels), nil
 }
 
-func makeHTML(gauges, counters *[]string) string {
+func makeHTML(gauges, counters, histograms *[]string) string {
 	body := "<!doctype html> <html lang='en'> <head> <meta charset='utf-8'> <title>Список метрик</title></head>"
 	body += "<body><header><h1><p>Metrics list</p></h1></header>"
 	body += "<h1><p>Gauges</p></h1>"
@@ -167,6 +218,12 @@ func makeHTML(gauges, counters *[]string) string {
 	for index, value := range *counters {
 		body += makeMetricString(index+1, value)
 	}
+	if len(*histograms) > 0 {
+		body += "<h1><p>Histograms</p></h1>"
+		for index, value := range *histograms {
+			body += makeMetricString(index+1, value)
+		}
+	}
 	body += "</body></html>"
 	return body
 }
@@ -177,6 +234,12 @@ func makeMetricString(index int, value string) string {
 
 // UpdateOneMetric is private func for update storage.
 func (ms *MemStorage) updateOneMetric(m metric) (*metric, error) {
+	if err := checkName(m.ID); err != nil {
+		return nil, err
+	}
+	if err := checkLabels(m.Labels); err != nil {
+		return nil, err
+	}
 	switch m.MType {
 	case counterType:
 		if m.Delta != nil {
@@ -192,12 +255,43 @@ func (ms *MemStorage) updateOneMetric(m metric) (*metric, error) {
 		} else {
 			return nil, errors.New("value indefined")
 		}
+	case histogramType:
+		if m.Histogram == nil {
+			return nil, errors.New("histogram indefined")
+		}
+		if ms.Histograms == nil {
+			ms.Histograms = make(map[string]*histogram)
+		}
+		h, err := updateHistogram(ms.Histograms, m.ID, m.Histogram)
+		if err != nil {
+			return nil, err
+		}
+		value := *h
+		m.Histogram = &value
 	default:
 		return nil, makeError(metricTypeError)
 	}
+	if ms.Labels == nil {
+		ms.Labels = make(metricLabels)
+	}
+	ms.Labels.set(m)
 	return &m, nil
 }
 
+// observe is private func. Adds one value to histogram, creates histogram with default buckets if it is absent.
+// Must be called under ms.mx lock.
+func (ms *MemStorage) observe(name string, value float64) {
+	if ms.Histograms == nil {
+		ms.Histograms = make(map[string]*histogram)
+	}
+	h, ok := ms.Histograms[name]
+	if !ok {
+		h = newHistogram(defaultBuckets)
+		ms.Histograms[name] = h
+	}
+	h.observe(value)
+}
+
 // UpdateJSON creates or updates metric value in storage.
 // Gets []byte with JSON.
 // Context doesn't have mean. Used to satisfy the interface.
@@ -235,6 +329,9 @@ func (ms *MemStorage) GetMetricJSON(ctx context.Context, data []byte) ([]byte, e
 	if err != nil {
 		return nil, makeError(jsonConverError, err)
 	}
+	if resp, ok, err := ms.self.json(m.MType, m.ID); ok {
+		return resp, err
+	}
 	resp := make([]byte, 0)
 	err = fmt.Errorf("metric not found. id: '%s', type: '%s'", m.ID, m.MType)
 	ms.mx.RLock()
@@ -256,6 +353,11 @@ func (ms *MemStorage) GetMetricJSON(ctx context.Context, data []byte) ([]byte, e
 				resp, err = json.Marshal(m)
 			}
 		}
+	case histogramType:
+		if h, ok := ms.Histograms[m.ID]; ok {
+			m.Histogram = h
+			resp, err = json.Marshal(m)
+		}
 	default:
 		return nil, fmt.Errorf("metric type ('%s') error", m.MType)
 	}
@@ -284,6 +386,8 @@ func (ms *MemStorage) Clear(ctx context.Context) error {
 	}
 	ms.Gauges = make(map[string]float64)
 	ms.Counters = make(map[string]int64)
+	ms.Histograms = make(map[string]*histogram)
+	ms.Labels = make(metricLabels)
 	ms.mx.Unlock()
 	return ms.Save()
 }
@@ -301,11 +405,7 @@ func (ms *MemStorage) UpdateJSONSlice(ctx context.Context, data []byte) ([]byte,
 	ms.mx.Lock()
 	for index, value := range metrics {
 		_, err := ms.updateOneMetric(value)
-		if err != nil {
-			resp += fmt.Sprintf("%d. '%s' update ERROR: %v\n", index+1, value.ID, err)
-		} else {
-			resp += fmt.Sprintf("%d. '%s' update SUCCESS \n", index+1, value.ID)
-		}
+		resp += sliceResult(index, value.ID, err)
 	}
 	ms.mx.Unlock()
 	if ms.SaveInterval == 0 {
@@ -316,11 +416,20 @@ func (ms *MemStorage) UpdateJSONSlice(ctx context.Context, data []byte) ([]byte,
 	return []byte(resp), nil
 }
 
+// sliceResult is private func. Returns line of metrics slice update response.
+func sliceResult(index int, name string, err error) string {
+	if err != nil {
+		return fmt.Sprintf("%d. '%s' update ERROR: %v\n", index+1, name, err)
+	}
+	return fmt.Sprintf("%d. '%s' update SUCCESS \n", index+1, name)
+}
+
 // Save writes storage data to file.
 func (ms *MemStorage) Save() error {
 	if ms.SavePath == "" {
 		return nil
 	}
+	start := time.Now()
 	file, err := os.OpenFile(ms.SavePath, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, fileOpenMode)
 	if err != nil {
 		return fmt.Errorf("open file for save error: %w", err)
@@ -336,6 +445,8 @@ func (ms *MemStorage) Save() error {
 	if err != nil {
 		return fmt.Errorf("write file error: %w", err)
 	}
+	ms.self.ObserveSince("storage_save_seconds", start)
+	ms.self.Set("storage_save_bytes", float64(len(data)))
 	return nil
 }
 
@@ -359,6 +470,16 @@ func getSortedKeysInt(items map[string]int64) []string {
 	return keys
 }
 
+// GetSortedKeysHistogram private func. Returns sorted list of map keys.
+func getSortedKeysHistogram(items map[string]*histogram) []string {
+	keys := make([]string, 0, len(items))
+	for k := range items {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // Restore is private func. Restores storage data from file.
 func (ms *MemStorage) restore() error {
 	if !ms.Restore {
@@ -0,0 +1,199 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SelfNamespace is reserved names prefix for server's own metrics.
+// Metrics with this prefix can't be updated by clients.
+const SelfNamespace = "go_metrics_server_"
+
+// SelfMetrics contains server's own metrics: requests counters, latency histograms,
+// storage operations timings. The metrics are returned by storage's read methods with other metrics.
+// Nil SelfMetrics is valid and doesn't collect anything.
+type SelfMetrics struct {
+	gauges     map[string]float64      // gauge metrics
+	counters   map[string]int64        // counter metrics
+	histograms map[string]*histogram   // histogram metrics
+	funcs      map[string]func() int64 // counters which values are read by funcs
+	mx         sync.Mutex              // mutex
+}
+
+// NewSelfMetrics creates empty SelfMetrics.
+func NewSelfMetrics() *SelfMetrics {
+	return &SelfMetrics{
+		gauges:     make(map[string]float64),
+		counters:   make(map[string]int64),
+		histograms: make(map[string]*histogram),
+		funcs:      make(map[string]func() int64),
+	}
+}
+
+// Add adds delta to counter. SelfNamespace is added to the name.
+func (s *SelfMetrics) Add(name string, delta int64) {
+	if s == nil {
+		return
+	}
+	s.mx.Lock()
+	s.counters[SelfNamespace+name] += delta
+	s.mx.Unlock()
+}
+
+// Set sets gauge value. SelfNamespace is added to the name.
+func (s *SelfMetrics) Set(name string, value float64) {
+	if s == nil {
+		return
+	}
+	s.mx.Lock()
+	s.gauges[SelfNamespace+name] = value
+	s.mx.Unlock()
+}
+
+// Observe adds value to histogram. SelfNamespace is added to the name.
+func (s *SelfMetrics) Observe(name string, value float64) {
+	if s == nil {
+		return
+	}
+	s.mx.Lock()
+	h, ok := s.histograms[SelfNamespace+name]
+	if !ok {
+		h = newHistogram(defaultBuckets)
+		s.histograms[SelfNamespace+name] = h
+	}
+	h.observe(value)
+	s.mx.Unlock()
+}
+
+// ObserveSince adds time since start in seconds to histogram.
+func (s *SelfMetrics) ObserveSince(name string, start time.Time) {
+	s.Observe(name, time.Since(start).Seconds())
+}
+
+// CounterFunc registers counter, which value is returned by f on every read.
+func (s *SelfMetrics) CounterFunc(name string, f func() int64) {
+	if s == nil {
+		return
+	}
+	s.mx.Lock()
+	s.funcs[SelfNamespace+name] = f
+	s.mx.Unlock()
+}
+
+// values is private func. Returns copies of metrics.
+func (s *SelfMetrics) values() (map[string]float64, map[string]int64, map[string]*histogram) {
+	gauges, counters, histograms := make(map[string]float64), make(map[string]int64), make(map[string]*histogram)
+	if s == nil {
+		return gauges, counters, histograms
+	}
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	for k, v := range s.gauges {
+		gauges[k] = v
+	}
+	for k, v := range s.counters {
+		counters[k] = v
+	}
+	for k, f := range s.funcs {
+		counters[k] = f()
+	}
+	for k, v := range s.histograms {
+		value := *v
+		value.Counts = append([]uint64{}, v.Counts...)
+		histograms[k] = &value
+	}
+	return gauges, counters, histograms
+}
+
+// withSelf is private func. Returns metrics maps with server's own metrics.
+// Arguments are not changed.
+func (s *SelfMetrics) withSelf(
+	gauges map[string]float64, counters map[string]int64, histograms map[string]*histogram,
+) (map[string]float64, map[string]int64, map[string]*histogram) {
+	g, c, h := s.values()
+	for k, v := range gauges {
+		g[k] = v
+	}
+	for k, v := range counters {
+		c[k] = v
+	}
+	for k, v := range histograms {
+		h[k] = v
+	}
+	return g, c, h
+}
+
+// value is private func. Returns server's own metric value as string.
+// The second value is false if the name is not in SelfNamespace.
+func (s *SelfMetrics) value(mType, name string) (string, bool, error) {
+	m, ok := s.metric(mType, name)
+	if !ok {
+		return "", false, nil
+	}
+	switch {
+	case m.Value != nil:
+		return strconv.FormatFloat(*m.Value, 'f', -1, 64), true, nil
+	case m.Delta != nil:
+		return strconv.FormatInt(*m.Delta, 10), true, nil
+	case m.Histogram != nil:
+		data, err := json.Marshal(m.Histogram)
+		if err != nil {
+			return "", true, makeError(jsonConverError, err)
+		}
+		return string(data), true, nil
+	default:
+		return "", true, makeError(metricNotFoud, name, mType)
+	}
+}
+
+// json is private func. Returns server's own metric as JSON.
+// The second value is false if the name is not in SelfNamespace.
+func (s *SelfMetrics) json(mType, name string) ([]byte, bool, error) {
+	m, ok := s.metric(mType, name)
+	if !ok {
+		return nil, false, nil
+	}
+	if m.Value == nil && m.Delta == nil && m.Histogram == nil {
+		return []byte(""), true, makeError(metricNotFoud, name, mType)
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, true, makeError(jsonConverError, err)
+	}
+	return data, true, nil
+}
+
+// metric is private func. Returns server's own metric.
+// The second value is false if the name is not in SelfNamespace.
+func (s *SelfMetrics) metric(mType, name string) (*metric, bool) {
+	if !strings.HasPrefix(name, SelfNamespace) {
+		return nil, false
+	}
+	m := metric{ID: name, MType: mType}
+	gauges, counters, histograms := s.values()
+	switch mType {
+	case gaugeType:
+		if v, ok := gauges[name]; ok {
+			m.Value = &v
+		}
+	case counterType:
+		if v, ok := counters[name]; ok {
+			m.Delta = &v
+		}
+	case histogramType:
+		m.Histogram = histograms[name]
+	}
+	return &m, true
+}
+
+// checkName is private func. Returns error if metric name is in SelfNamespace.
+func checkName(name string) error {
+	if strings.HasPrefix(name, SelfNamespace) {
+		return fmt.Errorf("metric name prefix '%s' is reserved", SelfNamespace)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemStorage_SelfMetrics(t *testing.T) {
+	ms, err := NewMemStorage(false, "", 1)
+	assert.NoError(t, err, "error making new MemStorage")
+	self := ms.SelfMetrics()
+	self.Add("http_update_requests_total", 2)
+	self.Observe("http_update_seconds", 0.02)
+	self.CounterFunc("repeater_retries_total", func() int64 { return 3 })
+
+	value, err := ms.GetMetric(ctx, counterType, SelfNamespace+"http_update_requests_total")
+	assert.NoError(t, err, "get self metric error")
+	assert.Equal(t, "2", value, "self counter value")
+	_, err = ms.GetMetric(ctx, gaugeType, SelfNamespace+"absent")
+	assert.Error(t, err, "absent self metric error expected")
+	data, err := ms.GetMetricJSON(ctx, []byte(`{"id":"`+SelfNamespace+`repeater_retries_total","type":"counter"}`))
+	assert.NoError(t, err, "get self metric json error")
+	assert.Contains(t, string(data), `"delta":3`, "self counter func value")
+
+	body, err := ms.GetMetricsPrometheus(ctx)
+	assert.NoError(t, err, "get prometheus metrics error")
+	assert.True(t, strings.Contains(body, SelfNamespace+"http_update_seconds_count 1"), "self histogram expected: %s", body)
+	body, err = ms.GetMetricsHTML(ctx)
+	assert.NoError(t, err, "get html metrics error")
+	assert.Contains(t, body, SelfNamespace+"http_update_requests_total", "self counter expected in HTML")
+
+	assert.Error(t, ms.Update(ctx, counterType, SelfNamespace+"item", "1"), "reserved name error expected")
+	_, err = ms.UpdateJSON(ctx, []byte(`{"id":"`+SelfNamespace+`item","type":"counter","delta":1}`))
+	assert.Error(t, err, "reserved name error expected")
+}
+
+func TestSelfMetrics_nil(t *testing.T) {
+	var self *SelfMetrics
+	self.Add("item", 1)
+	self.Set("item", 1)
+	self.Observe("item", 1)
+	gauges, counters, histograms := self.values()
+	assert.Empty(t, gauges, "nil self metrics gauges")
+	assert.Empty(t, counters, "nil self metrics counters")
+	assert.Empty(t, histograms, "nil self metrics histograms")
+}
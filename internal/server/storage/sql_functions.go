@@ -3,6 +3,8 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -32,7 +34,7 @@ type (
 
 // SqlTablesMaps is private func. Creates sqlColumns for create tables.
 func sqlTablesMaps() *map[string]sqlColumns {
-	counters, gauges := make(sqlColumns), make(sqlColumns)
+	counters, gauges, histograms := make(sqlColumns), make(sqlColumns), make(sqlColumns)
 	counters["ID"] = 0
 	counters["name"] = "50"
 	counters["value"] = 0
@@ -41,9 +43,21 @@ func sqlTablesMaps() *map[string]sqlColumns {
 	gauges["name"] = "50"
 	gauges["value"] = 0.0
 
+	histograms["ID"] = 0
+	histograms["name"] = "50"
+	histograms["value"] = []byte{}
+
+	// labels name is labelsKey of metric
+	labels := make(sqlColumns)
+	labels["ID"] = 0
+	labels["name"] = "70"
+	labels["value"] = []byte{}
+
 	result := make(map[string]sqlColumns)
 	result[counterTableName] = counters
 	result[gaugeTableName] = gauges
+	result[histogramTableName] = histograms
+	result[labelsTableName] = labels
 	return &result
 }
 
@@ -67,6 +81,8 @@ func createTable(
 			items = append(items, fmt.Sprintf("%s timestamp", key))
 		case float32, float64:
 			items = append(items, fmt.Sprintf("%s double precision", key))
+		case []byte:
+			items = append(items, fmt.Sprintf("%s text", key))
 		}
 	}
 	context, cansel := context.WithTimeout(ctx, time.Duration(createTableTimeout)*time.Second)
@@ -107,10 +123,10 @@ func (ms *SQLStorage) getCounter(ctx context.Context, name string) (*int64, erro
 		return nil, fmt.Errorf("get conter value error: %w", err)
 	}
 	defer rows.Close() //nolint:errcheck //<-senselessly
-	if rows.Err() != nil {
-		return nil, fmt.Errorf("get counter metric rows error: %w", err)
-	}
 	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return nil, fmt.Errorf("get counter metric rows error: %w", err)
+		}
 		value := int64(0)
 		return &value, fmt.Errorf("counter value (%s) is absent", name)
 	}
@@ -130,10 +146,10 @@ func (ms *SQLStorage) getGauge(ctx context.Context, name string) (*float64, erro
 		return nil, fmt.Errorf("select gauge value error: %w", err)
 	}
 	defer rows.Close() //nolint:errcheck //<-senselessly
-	if rows.Err() != nil {
-		return nil, fmt.Errorf("get gauge metric rows error: %w", err)
-	}
 	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return nil, fmt.Errorf("get gauge metric rows error: %w", err)
+		}
 		return &value, fmt.Errorf("gauge value (%s) is absent", name)
 	}
 	err = rows.Scan(&value)
@@ -175,18 +191,27 @@ func (ms *SQLStorage) updateGauge(
 	return &value, nil
 }
 
-func scanValue(table string, rows *sql.Rows) (string, error) {
+func scanValue(table string, rows *sql.Rows, labels metricLabels) (string, error) {
 	var err error
 	var name string
 	var strValue string
-	if table == gaugeTableName {
+	switch table {
+	case gaugeTableName:
 		var value float64
 		err = rows.Scan(&name, &value)
-		strValue = fmt.Sprintf("'%s' = %f", name, value)
-	} else {
+		strValue = fmt.Sprintf("'%s'%s = %f", name, labels.get(gaugeType, name), value)
+	case histogramTableName:
+		var value []byte
+		err = rows.Scan(&name, &value)
+		if err == nil {
+			var h *histogram
+			h, err = parseHistogram(value)
+			strValue = fmt.Sprintf("'%s'%s = %s", name, labels.get(histogramType, name), h)
+		}
+	default:
 		var value int64
 		err = rows.Scan(&name, &value)
-		strValue = fmt.Sprintf("'%s' = %d", name, value)
+		strValue = fmt.Sprintf("'%s'%s = %d", name, labels.get(counterType, name), value)
 	}
 	if err != nil {
 		return "", fmt.Errorf("get scan value error: %w", err)
@@ -194,28 +219,164 @@ func scanValue(table string, rows *sql.Rows) (string, error) {
 	return strValue, nil
 }
 
-func (ms *SQLStorage) getAllMetricOfType(ctx context.Context, table string) (*[]string, error) {
+func (ms *SQLStorage) getAllMetricOfType(ctx context.Context, table string, labels metricLabels) (*[]string, error) {
 	values := make([]string, 0)
 
 	query := "Select name, value from counters order by name;"
-	if table == gaugeTableName {
+	switch table {
+	case gaugeTableName:
 		query = "Select name, value from gauges order by name;"
+	case histogramTableName:
+		query = "Select name, value from histograms order by name;"
 	}
 	rows, err := ms.con.QueryContext(ctx, query)
 	if err != nil {
 		return &values, fmt.Errorf("get all metrics query error: %w", err)
 	}
 	defer rows.Close() //nolint:errcheck //<-senselessly
-	if rows.Err() != nil {
-		return &values, fmt.Errorf("get all metrics rows error: %w", err)
-	}
 
 	for rows.Next() {
-		val, err := scanValue(table, rows)
+		val, err := scanValue(table, rows, labels)
 		if err != nil {
 			return &values, fmt.Errorf("scan value error: %w", err)
 		}
 		values = append(values, val)
 	}
+	if err = rows.Err(); err != nil {
+		return &values, fmt.Errorf("get all metrics rows error: %w", err)
+	}
 	return &values, nil
 }
+
+// GetHistogram is private func. Returns histogram value from database.
+func (ms *SQLStorage) getHistogram(ctx context.Context, name string) (*histogram, error) {
+	var data []byte
+	err := ms.con.QueryRowContext(ctx, "Select value from histograms where name=$1;", name).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return newHistogram(nil), fmt.Errorf("histogram value (%s) is absent", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("select histogram value error: %w", err)
+	}
+	return parseHistogram(data)
+}
+
+// UpdateHistogram is private func. Locks histogram row, changes its value by update func and saves it.
+// Update func gets nil if histogram is absent. Connect must be a transaction for the row lock.
+func (ms *SQLStorage) updateHistogram(
+	ctx context.Context,
+	name string,
+	update func(*histogram) (*histogram, error),
+	connect SQLQueryInterface,
+) (*histogram, error) {
+	var item *histogram
+	var data []byte
+	err := connect.QueryRowContext(ctx, "Select value from histograms where name=$1 FOR UPDATE;", name).Scan(&data)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return nil, fmt.Errorf("select histogram value error: %w", err)
+	default:
+		if item, err = parseHistogram(data); err != nil {
+			return nil, err
+		}
+	}
+	item, err = update(item)
+	if err != nil {
+		return nil, fmt.Errorf("histogram '%s' update error: %w", name, err)
+	}
+	data, err = json.Marshal(item)
+	if err != nil {
+		return nil, makeError(jsonConverError, err)
+	}
+	_, err = connect.ExecContext(ctx,
+		`INSERT INTO histograms(name, value) values($1, $2) 
+		ON CONFLICT (name) DO UPDATE SET value=EXCLUDED.value;`, name, string(data))
+	if err != nil {
+		return nil, fmt.Errorf("histograms update error: %w", err)
+	}
+	return item, nil
+}
+
+// UpdateHistogramTx is private func. Calls updateHistogram in new transaction.
+func (ms *SQLStorage) updateHistogramTx(
+	ctx context.Context,
+	name string,
+	update func(*histogram) (*histogram, error),
+) (*histogram, error) {
+	sqtx, err := ms.con.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("transaction create error: %w", err)
+	}
+	defer sqtx.Rollback() //nolint:errcheck //<-senselessly
+	item, err := ms.updateHistogram(ctx, name, update, sqtx)
+	if err != nil {
+		return nil, err
+	}
+	if err = sqtx.Commit(); err != nil {
+		return nil, fmt.Errorf("transaction commit error: %w", err)
+	}
+	return item, nil
+}
+
+// mergeFunc is private func. Returns update func which merges h to histogram.
+func mergeFunc(h *histogram) func(*histogram) (*histogram, error) {
+	return func(item *histogram) (*histogram, error) {
+		if item == nil {
+			item = newHistogram(h.Buckets)
+		}
+		return item, item.merge(h)
+	}
+}
+
+// observeFunc is private func. Returns update func which adds value to histogram.
+func observeFunc(value float64) func(*histogram) (*histogram, error) {
+	return func(item *histogram) (*histogram, error) {
+		if item == nil {
+			item = newHistogram(defaultBuckets)
+		}
+		item.observe(value)
+		return item, nil
+	}
+}
+
+// GetAllValues is private func. Returns all metrics values from database.
+func (ms *SQLStorage) getAllValues(ctx context.Context) (
+	map[string]float64, map[string]int64, map[string]*histogram, error,
+) {
+	gauges, counters, histograms := make(map[string]float64), make(map[string]int64), make(map[string]*histogram)
+	for _, table := range []string{gaugeTableName, counterTableName, histogramTableName} {
+		rows, err := ms.con.QueryContext(ctx, "Select name, value from "+table+";")
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("get all %s query error: %w", table, err)
+		}
+		for rows.Next() {
+			var name string
+			switch table {
+			case gaugeTableName:
+				var value float64
+				err = rows.Scan(&name, &value)
+				gauges[name] = value
+			case counterTableName:
+				var value int64
+				err = rows.Scan(&name, &value)
+				counters[name] = value
+			default:
+				var value []byte
+				if err = rows.Scan(&name, &value); err == nil {
+					histograms[name], err = parseHistogram(value)
+				}
+			}
+			if err != nil {
+				rows.Close() //nolint:errcheck,gosec //<-senselessly
+				return nil, nil, nil, fmt.Errorf("scan %s value error: %w", table, err)
+			}
+		}
+		err = rows.Err()
+		rows.Close() //nolint:errcheck,gosec //<-senselessly
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("get all %s rows error: %w", table, err)
+		}
+	}
+	return gauges, counters, histograms, nil
+}
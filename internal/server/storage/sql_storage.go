@@ -9,12 +9,19 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/gostuding/go-metrics/internal/tracing"
 	_ "github.com/jackc/pgx/v5/stdlib"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// tracerName is tracer name for database spans.
+const tracerName = "github.com/gostuding/go-metrics/internal/server/storage"
+
 // SQLStorage contains metrics data in database.
 type SQLStorage struct {
-	con *sql.DB
+	con  *sql.DB
+	self *SelfMetrics // server's own metrics
 }
 
 // NewSQLStorage creates SQLStorage.
@@ -24,18 +31,35 @@ func NewSQLStorage(dsn string) (*SQLStorage, error) {
 		return nil, fmt.Errorf("connect database crate error: %w", err)
 	}
 	storage := SQLStorage{
-		con: db,
+		con:  db,
+		self: NewSelfMetrics(),
 	}
 	return &storage, checkDatabaseStructure(dsn)
 }
 
+// SelfMetrics returns server's own metrics, which are returned with storage metrics.
+func (ms *SQLStorage) SelfMetrics() *SelfMetrics {
+	return ms.self
+}
+
+// startSpan is private func. Starts span for database operation.
+func startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, tracerName, "SQLStorage."+name, trace.SpanKindClient,
+		attribute.String("db.system", "postgresql"))
+}
+
 // Update creates or updates metric value in storage.
 func (ms *SQLStorage) Update(
 	ctx context.Context,
 	mType string,
 	mName string,
 	mValue string,
-) error {
+) (err error) {
+	ctx, span := startSpan(ctx, "Update")
+	defer func() { tracing.End(span, err) }()
+	if err := checkName(mName); err != nil {
+		return err
+	}
 	switch mType {
 	case counterType:
 		counter, err := strconv.ParseInt(mValue, 10, 64)
@@ -51,6 +75,13 @@ func (ms *SQLStorage) Update(
 		}
 		_, err = ms.updateGauge(ctx, mName, gauges, ms.con)
 		return err
+	case histogramType:
+		value, err := strconv.ParseFloat(mValue, 64)
+		if err != nil {
+			return makeError(converError, histogramType, err)
+		}
+		_, err = ms.updateHistogramTx(ctx, mName, observeFunc(value))
+		return err
 	default:
 		return makeError(metricTypeIncorrect)
 	}
@@ -61,7 +92,12 @@ func (ms *SQLStorage) GetMetric(
 	ctx context.Context,
 	mType string,
 	mName string,
-) (string, error) {
+) (_ string, err error) {
+	ctx, span := startSpan(ctx, "GetMetric")
+	defer func() { tracing.End(span, err) }()
+	if value, ok, err := ms.self.value(mType, mName); ok {
+		return value, err
+	}
 	switch mType {
 	case gaugeType:
 		value, err := ms.getGauge(ctx, mName)
@@ -69,26 +105,78 @@ func (ms *SQLStorage) GetMetric(
 	case counterType:
 		value, err := ms.getCounter(ctx, mName)
 		return fmt.Sprintf("%d", *value), err
+	case histogramType:
+		value, err := ms.getHistogram(ctx, mName)
+		if err != nil {
+			return "", err
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return "", makeError(jsonConverError, err)
+		}
+		return string(data), nil
 	default:
 		return "", makeError(metricNotFoud, mName, mType)
 	}
 }
 
 // GetMetricsHTML returns all metrics values as HTML string.
-func (ms *SQLStorage) GetMetricsHTML(ctx context.Context) (string, error) {
-	gauges, err := ms.getAllMetricOfType(ctx, gaugeTableName)
+func (ms *SQLStorage) GetMetricsHTML(ctx context.Context) (_ string, err error) {
+	ctx, span := startSpan(ctx, "GetMetricsHTML")
+	defer func() { tracing.End(span, err) }()
+	labels, err := ms.getLabels(ctx)
+	if err != nil {
+		return "", err
+	}
+	gauges, err := ms.getAllMetricOfType(ctx, gaugeTableName, labels)
 	if err != nil {
 		return "", fmt.Errorf("get gauges metrics error: %w", err)
 	}
-	counters, err := ms.getAllMetricOfType(ctx, counterTableName)
+	counters, err := ms.getAllMetricOfType(ctx, counterTableName, labels)
 	if err != nil {
 		return "", fmt.Errorf("get counters metrics error: %w", err)
 	}
-	return makeHTML(gauges, counters), nil
+	histograms, err := ms.getAllMetricOfType(ctx, histogramTableName, labels)
+	if err != nil {
+		return "", fmt.Errorf("get histograms metrics error: %w", err)
+	}
+	selfGauges, selfCounters, selfHistograms := ms.self.values()
+	for _, key := range getSortedKeysFloat(selfGauges) {
+		*gauges = append(*gauges, fmt.Sprintf("'%s' = %f", key, selfGauges[key]))
+	}
+	for _, key := range getSortedKeysInt(selfCounters) {
+		*counters = append(*counters, fmt.Sprintf("'%s' = %d", key, selfCounters[key]))
+	}
+	for _, key := range getSortedKeysHistogram(selfHistograms) {
+		*histograms = append(*histograms, fmt.Sprintf("'%s' = %s", key, selfHistograms[key]))
+	}
+	return makeHTML(gauges, counters, histograms), nil
+}
+
+// GetMetricsPrometheus returns all metrics values in Prometheus text format.
+func (ms *SQLStorage) GetMetricsPrometheus(ctx context.Context) (_ string, err error) {
+	ctx, span := startSpan(ctx, "GetMetricsPrometheus")
+	defer func() { tracing.End(span, err) }()
+	gauges, counters, histograms, err := ms.getAllValues(ctx)
+	if err != nil {
+		return "", err
+	}
+	labels, err := ms.getLabels(ctx)
+	if err != nil {
+		return "", err
+	}
+	gauges, counters, histograms = ms.self.withSelf(gauges, counters, histograms)
+	return makePrometheus(gauges, counters, histograms, labels), nil
 }
 
 // updateOneMetric is private func for update storage.
 func (ms *SQLStorage) updateOneMetric(ctx context.Context, m metric, connect SQLQueryInterface) (*metric, error) {
+	if err := checkName(m.ID); err != nil {
+		return nil, err
+	}
+	if err := checkLabels(m.Labels); err != nil {
+		return nil, err
+	}
 	switch m.MType {
 	case counterType:
 		if m.Delta != nil {
@@ -110,16 +198,33 @@ func (ms *SQLStorage) updateOneMetric(ctx context.Context, m metric, connect SQL
 		} else {
 			return nil, errors.New("metric's value indefined")
 		}
+	case histogramType:
+		if m.Histogram == nil {
+			return nil, errors.New("metric's histogram indefined")
+		}
+		if err := m.Histogram.validate(); err != nil {
+			return nil, err
+		}
+		value, err := ms.updateHistogramTx(ctx, m.ID, mergeFunc(m.Histogram))
+		if err != nil {
+			return nil, err
+		}
+		m.Histogram = value
 	default:
 		return nil, makeError(metricTypeError)
 	}
+	if err := ms.updateLabels(ctx, []metric{m}, connect); err != nil {
+		return nil, err
+	}
 	return &m, nil
 }
 
 // UpdateJSON creates or updates metric value in storage.
-func (ms *SQLStorage) UpdateJSON(ctx context.Context, data []byte) ([]byte, error) {
+func (ms *SQLStorage) UpdateJSON(ctx context.Context, data []byte) (_ []byte, err error) {
+	ctx, span := startSpan(ctx, "UpdateJSON")
+	defer func() { tracing.End(span, err) }()
 	var m metric
-	err := json.Unmarshal(data, &m)
+	err = json.Unmarshal(data, &m)
 	if err != nil {
 		return nil, makeError(jsonConverError, err)
 	}
@@ -137,12 +242,17 @@ func (ms *SQLStorage) UpdateJSON(ctx context.Context, data []byte) ([]byte, erro
 }
 
 // GetMetricJSON returns the metric value as string.
-func (ms *SQLStorage) GetMetricJSON(ctx context.Context, data []byte) ([]byte, error) {
+func (ms *SQLStorage) GetMetricJSON(ctx context.Context, data []byte) (_ []byte, err error) {
+	ctx, span := startSpan(ctx, "GetMetricJSON")
+	defer func() { tracing.End(span, err) }()
 	var m metric
-	err := json.Unmarshal(data, &m)
+	err = json.Unmarshal(data, &m)
 	if err != nil {
 		return nil, makeError(jsonConverError, err)
 	}
+	if resp, ok, err := ms.self.json(m.MType, m.ID); ok {
+		return resp, err
+	}
 	switch m.MType {
 	case counterType:
 		value, err := ms.getCounter(ctx, m.ID)
@@ -172,8 +282,22 @@ func (ms *SQLStorage) GetMetricJSON(ctx context.Context, data []byte) ([]byte, e
 			return nil, fmt.Errorf("marshal gauge metric error: %w", err)
 		}
 		return resp, nil
+	case histogramType:
+		value, err := ms.getHistogram(ctx, m.ID)
+		if err != nil {
+			if value != nil {
+				return []byte(""), err
+			}
+			return nil, err
+		}
+		m.Histogram = value
+		resp, err := json.Marshal(m)
+		if err != nil {
+			return nil, fmt.Errorf("marshal histogram metric error: %w", err)
+		}
+		return resp, nil
 	default:
-		return nil, fmt.Errorf("metric type ('%s') error, use counter like int64 or gauge like float64", m.MType)
+		return nil, fmt.Errorf("metric type ('%s') error, use counter like int64, gauge like float64 or histogram", m.MType)
 	}
 }
 
@@ -183,7 +307,9 @@ func (ms *SQLStorage) Save() error {
 }
 
 // PingDB checks connection to database server.
-func (ms *SQLStorage) PingDB(ctx context.Context) error {
+func (ms *SQLStorage) PingDB(ctx context.Context) (err error) {
+	ctx, span := startSpan(ctx, "PingDB")
+	defer func() { tracing.End(span, err) }()
 	if err := ms.con.PingContext(ctx); err != nil {
 		return fmt.Errorf("check database ping error: %w", err)
 	}
@@ -191,8 +317,10 @@ func (ms *SQLStorage) PingDB(ctx context.Context) error {
 }
 
 // Clear deletes all metrics data from the database.
-func (ms *SQLStorage) Clear(ctx context.Context) error {
-	_, err := ms.con.ExecContext(ctx, "Delete from gauges;")
+func (ms *SQLStorage) Clear(ctx context.Context) (err error) {
+	ctx, span := startSpan(ctx, "Clear")
+	defer func() { tracing.End(span, err) }()
+	_, err = ms.con.ExecContext(ctx, "Delete from gauges;")
 	if err != nil {
 		return fmt.Errorf("clear gauges table error: %w", err)
 	}
@@ -200,6 +328,14 @@ func (ms *SQLStorage) Clear(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("clear counters table error: %w", err)
 	}
+	_, err = ms.con.ExecContext(ctx, "Delete from histograms;")
+	if err != nil {
+		return fmt.Errorf("clear histograms table error: %w", err)
+	}
+	_, err = ms.con.ExecContext(ctx, "Delete from labels;")
+	if err != nil {
+		return fmt.Errorf("clear labels table error: %w", err)
+	}
 	return nil
 }
 
@@ -223,20 +359,45 @@ func sliceInsert(ctx context.Context, sqtx *sql.Tx, tbl string, mp map[string]st
 	return nil
 }
 
+// checkSliceMetric is private func. Checks metric from slice like MemStorage update does.
+func checkSliceMetric(m metric) error {
+	if err := checkName(m.ID); err != nil {
+		return err
+	}
+	if err := checkLabels(m.Labels); err != nil {
+		return err
+	}
+	switch m.MType {
+	case counterType:
+		if m.Delta == nil {
+			return errors.New("delta indefined")
+		}
+	case gaugeType:
+		if m.Value == nil {
+			return errors.New("value indefined")
+		}
+	case histogramType:
+		if m.Histogram == nil {
+			return errors.New("histogram indefined")
+		}
+		return m.Histogram.validate()
+	default:
+		return makeError(metricTypeError)
+	}
+	return nil
+}
+
 func mkMetricsMaps(metrics []metric) (map[string]string, map[string]string) {
 	countersLst := make(map[string]int64)
 	gaugeLst := make(map[string]string)
 	for _, item := range metrics {
+		if checkSliceMetric(item) != nil {
+			continue
+		}
 		switch item.MType {
 		case counterType:
-			if item.Delta == nil {
-				continue
-			}
 			countersLst[item.ID] += *item.Delta
 		case gaugeType:
-			if item.Value == nil {
-				continue
-			}
 			gaugeLst[item.ID] = strconv.FormatFloat(*item.Value, 'f', -1, 64)
 		}
 	}
@@ -249,18 +410,21 @@ func mkMetricsMaps(metrics []metric) (map[string]string, map[string]string) {
 
 // UpdateJSONSlice updates the repository with metrics that are obtained
 // by translating the received JSON into a list of metrics.
+// Returns update result of each metric as MemStorage does, rejected metrics are reported as ERROR lines.
 func (ms *SQLStorage) UpdateJSONSlice(
 	ctx context.Context,
 	data []byte,
-) ([]byte, error) {
+) (_ []byte, err error) {
+	ctx, span := startSpan(ctx, "UpdateJSONSlice")
+	defer func() { tracing.End(span, err) }()
 	var metrics []metric
-	err := json.Unmarshal(data, &metrics)
+	err = json.Unmarshal(data, &metrics)
 	if err != nil {
 		return nil, makeError(jsonConverError, err)
 	}
 
 	// запись данных в БД
-	sqtx, err := ms.con.Begin()
+	sqtx, err := ms.con.BeginTx(ctx, nil)
 	defer sqtx.Rollback() //nolint:errcheck //<-senselessly
 	if err != nil {
 		return nil, fmt.Errorf("transaction create error: %w", err)
@@ -275,12 +439,30 @@ func (ms *SQLStorage) UpdateJSONSlice(
 	if err != nil {
 		return nil, fmt.Errorf("insert gauges slice error: %w", err)
 	}
+	resp := ""
+	updated := make([]metric, 0, len(metrics))
+	for index, item := range metrics {
+		merr := checkSliceMetric(item)
+		if merr == nil && item.MType == histogramType {
+			_, merr = ms.updateHistogram(ctx, item.ID, mergeFunc(item.Histogram), sqtx)
+			if merr != nil && !errors.Is(merr, errBucketsMismatch) {
+				return nil, fmt.Errorf("update histograms slice error: %w", merr)
+			}
+		}
+		if merr == nil {
+			updated = append(updated, item)
+		}
+		resp += sliceResult(index, item.ID, merr)
+	}
+	if err = ms.updateLabels(ctx, updated, sqtx); err != nil {
+		return nil, err
+	}
 
 	err = sqtx.Commit()
 	if err != nil {
 		return nil, fmt.Errorf("transaction commit error: %w", err)
 	}
-	return nil, nil
+	return []byte(resp), nil
 }
 
 // Stop is closing connection to database.
@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_checkSliceMetric(t *testing.T) {
+	delta := int64(1)
+	value := float64(1)
+	tests := []struct {
+		name    string
+		m       metric
+		wantErr bool
+	}{
+		{name: "counter", m: metric{ID: "c", MType: counterType, Delta: &delta}},
+		{name: "gauge", m: metric{ID: "g", MType: gaugeType, Value: &value}},
+		{name: "histogram", m: metric{ID: "h", MType: histogramType,
+			Histogram: &histogram{Buckets: []float64{1}, Counts: []uint64{1, 0}, Count: 1}}},
+		{name: "counter without delta", m: metric{ID: "c", MType: counterType}, wantErr: true},
+		{name: "gauge without value", m: metric{ID: "g", MType: gaugeType}, wantErr: true},
+		{name: "histogram without value", m: metric{ID: "h", MType: histogramType}, wantErr: true},
+		{name: "invalid histogram", m: metric{ID: "h", MType: histogramType,
+			Histogram: &histogram{Buckets: []float64{1}, Counts: []uint64{1}, Count: 1}}, wantErr: true},
+		{name: "reserved name", m: metric{ID: SelfNamespace + "c", MType: counterType, Delta: &delta}, wantErr: true},
+		{name: "labels", m: metric{ID: "c", MType: counterType, Delta: &delta, Labels: map[string]string{"host": "a"}}},
+		{name: "reserved label", m: metric{ID: "c", MType: counterType, Delta: &delta, Labels: map[string]string{"le": "1"}},
+			wantErr: true},
+		{name: "unknown type", m: metric{ID: "u", MType: "unknown"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantErr, checkSliceMetric(tt.m) != nil, "check error")
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package signing
+
+import (
+	"sync"
+	"time"
+)
+
+// cleanInterval is interval of expired nonces removing.
+const cleanInterval = time.Minute
+
+// NonceCache contains used nonces until their requests are out of clock skew window.
+type NonceCache struct {
+	items map[string]time.Time // nonce -> expiration time
+	clean time.Time            // next expired nonces removing time
+	mx    sync.Mutex           // mutex
+}
+
+// NewNonceCache creates empty cache.
+func NewNonceCache() *NonceCache {
+	return &NonceCache{items: make(map[string]time.Time)}
+}
+
+// Add saves nonce until expire time. Returns false if nonce is already saved and is not expired.
+func (c *NonceCache) Add(nonce string, expire, now time.Time) bool {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	if now.After(c.clean) {
+		for item, t := range c.items {
+			if now.After(t) {
+				delete(c.items, item)
+			}
+		}
+		c.clean = now.Add(cleanInterval)
+	}
+	if t, ok := c.items[nonce]; ok && !now.After(t) {
+		return false
+	}
+	c.items[nonce] = expire
+	return true
+}
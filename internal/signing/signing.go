@@ -0,0 +1,158 @@
+// Package signing contains requests signature schemes.
+// Legacy 'body' scheme signs only request body, so captured request can be replayed.
+// 'nonce' scheme signs timestamp, random nonce and body. Server rejects requests with timestamp
+// out of clock skew window and requests with nonce, which was already used.
+// Timestamp and nonce are sent in 'X-Timestamp' and 'X-Nonce' HTTP headers ('x-timestamp' and 'x-nonce' gRPC metadata).
+package signing
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Signature schemes.
+const (
+	SchemeNonce = "nonce" // HMAC of timestamp, nonce and body
+	SchemeBody  = "body"  // HMAC of body only, for legacy agents
+	SchemeAny   = "any"   // server accepts both schemes, 'nonce' is checked if request contains timestamp or nonce
+)
+
+// Signature values.
+const (
+	HeaderTimestamp   = "X-Timestamp" // HTTP header with unix time in seconds
+	HeaderNonce       = "X-Nonce"     // HTTP header with nonce
+	MetadataTimestamp = "x-timestamp" // gRPC metadata key with unix time in seconds
+	MetadataNonce     = "x-nonce"     // gRPC metadata key with nonce
+	DefaultSkew       = 300           // default clock skew window in seconds
+	nonceBytes        = 16            // random bytes in nonce
+	maxNonceLen       = 64            // max length of nonce
+)
+
+var (
+	// ErrHashUndefined is returned, if request doesn't contain hash, timestamp or nonce.
+	ErrHashUndefined = errors.New("hash undefined")
+	// ErrBadHash is returned, if request hash is incorrect.
+	ErrBadHash = errors.New("incorrect hash summ")
+	// ErrTimestamp is returned, if request timestamp is out of clock skew window.
+	ErrTimestamp = errors.New("request timestamp is out of skew window")
+	// ErrReplay is returned, if request nonce was already used.
+	ErrReplay = errors.New("request nonce is already used")
+)
+
+type (
+	// Signature contains request's hash. Timestamp and Nonce are empty for 'body' scheme.
+	Signature struct {
+		Hash      string // hex HMAC-SHA256
+		Timestamp string // unix time in seconds
+		Nonce     string // random hex string
+	}
+
+	// Options contains server's signature settings.
+	Options struct {
+		Scheme string // accepted scheme: nonce, body or any
+		Skew   int    // clock skew window in seconds
+	}
+
+	// Verifier checks requests signatures.
+	Verifier struct {
+		scheme string           // accepted scheme
+		skew   time.Duration    // clock skew window
+		nonces *NonceCache      // used nonces, replays are not checked if nil
+		now    func() time.Time // current time
+	}
+)
+
+// Validate checks options.
+func (o Options) Validate() error {
+	switch o.Scheme {
+	case SchemeNonce, SchemeBody, SchemeAny:
+	default:
+		return fmt.Errorf("hash scheme must be '%s', '%s' or '%s'", SchemeNonce, SchemeBody, SchemeAny)
+	}
+	if o.Skew < 1 {
+		return errors.New("hash skew window must be positive")
+	}
+	return nil
+}
+
+// Hash returns hex HMAC-SHA256. Only body is signed if timestamp and nonce are empty.
+func Hash(key, body []byte, timestamp, nonce string) string {
+	h := hmac.New(sha256.New, key)
+	if timestamp != "" || nonce != "" {
+		h.Write([]byte(timestamp + "\n" + nonce + "\n")) //nolint:errcheck //<-hash never returns error
+	}
+	h.Write(body) //nolint:errcheck //<-hash never returns error
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Sign creates body signature. Timestamp and nonce are generated for every scheme except 'body'.
+func Sign(key, body []byte, scheme string, now time.Time) (Signature, error) {
+	if scheme == SchemeBody {
+		return Signature{Hash: Hash(key, body, "", "")}, nil
+	}
+	b := make([]byte, nonceBytes)
+	if _, err := rand.Read(b); err != nil {
+		return Signature{}, fmt.Errorf("generate nonce error: %w", err)
+	}
+	s := Signature{Timestamp: strconv.FormatInt(now.Unix(), 10), Nonce: hex.EncodeToString(b)}
+	s.Hash = Hash(key, body, s.Timestamp, s.Nonce)
+	return s, nil
+}
+
+// nonceKey is private func. Returns nonces cache key for key and nonce, the key itself is not kept in cache.
+func nonceKey(key []byte, nonce string) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:]) + "\n" + nonce
+}
+
+// NewVerifier creates Verifier. Nonces cache must be shared between verifiers, which are recreated on config reload.
+func NewVerifier(o Options, nonces *NonceCache) *Verifier {
+	return &Verifier{scheme: o.Scheme, skew: time.Duration(o.Skew) * time.Second, nonces: nonces, now: time.Now}
+}
+
+// Verify checks request signature by the agent's key. Nil Verifier checks 'body' scheme.
+// For 'body' scheme requests with empty hash or empty body are not checked, as before.
+// Nonces are kept per key, not per agent ID: agent ID is not signed, so the same request
+// with other agent ID, which uses the same shared key, is a replay too.
+func (v *Verifier) Verify(key, body []byte, s Signature) error {
+	scheme := SchemeBody
+	if v != nil {
+		scheme = v.scheme
+	}
+	if scheme == SchemeAny {
+		scheme = SchemeBody
+		if s.Timestamp != "" || s.Nonce != "" {
+			scheme = SchemeNonce
+		}
+	}
+	if scheme == SchemeBody {
+		if len(body) > 0 && s.Hash != "" && !hmac.Equal([]byte(s.Hash), []byte(Hash(key, body, "", ""))) {
+			return fmt.Errorf("%w: %s", ErrBadHash, s.Hash)
+		}
+		return nil
+	}
+	if s.Hash == "" || s.Timestamp == "" || s.Nonce == "" || len(s.Nonce) > maxNonceLen {
+		return ErrHashUndefined
+	}
+	if !hmac.Equal([]byte(s.Hash), []byte(Hash(key, body, s.Timestamp, s.Nonce))) {
+		return fmt.Errorf("%w: %s", ErrBadHash, s.Hash)
+	}
+	sec, err := strconv.ParseInt(s.Timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrTimestamp, s.Timestamp)
+	}
+	now, ts := v.now(), time.Unix(sec, 0)
+	if ts.Before(now.Add(-v.skew)) || ts.After(now.Add(v.skew)) {
+		return fmt.Errorf("%w: %s", ErrTimestamp, s.Timestamp)
+	}
+	if v.nonces != nil && !v.nonces.Add(nonceKey(key, s.Nonce), ts.Add(v.skew), now) {
+		return fmt.Errorf("%w: %s", ErrReplay, s.Nonce)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package signing
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptions_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		o       Options
+		wantErr bool
+	}{
+		{name: "Nonce", o: Options{Scheme: SchemeNonce, Skew: DefaultSkew}},
+		{name: "Body", o: Options{Scheme: SchemeBody, Skew: 1}},
+		{name: "Any", o: Options{Scheme: SchemeAny, Skew: 1}},
+		{name: "Unknown scheme", o: Options{Scheme: "md5", Skew: 1}, wantErr: true},
+		{name: "Zero skew", o: Options{Scheme: SchemeNonce}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.o.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Options.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHash(t *testing.T) {
+	assert.Equal(t, "de79cc62d7da11c1f3049dbf73ba060497e3d4e7a07029fa6f48e75cfc681042",
+		Hash([]byte("default"), []byte("test"), "", ""), "body hash")
+	assert.NotEqual(t, Hash([]byte("default"), []byte("test"), "1", "a"),
+		Hash([]byte("default"), []byte("test"), "1", "b"), "nonce must be signed")
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	key, body := []byte("key"), []byte("body")
+	now := time.Unix(1700000000, 0)
+	sign := func(scheme string, at time.Time) Signature {
+		s, err := Sign(key, body, scheme, at)
+		require.NoError(t, err, "sign error")
+		return s
+	}
+	replay := sign(SchemeNonce, now)
+	newVerifier := func(scheme string) *Verifier {
+		v := NewVerifier(Options{Scheme: scheme, Skew: DefaultSkew}, NewNonceCache())
+		v.now = func() time.Time { return now }
+		return v
+	}
+	nonce, anyScheme := newVerifier(SchemeNonce), newVerifier(SchemeAny)
+	require.NoError(t, nonce.Verify(key, body, replay), "first request")
+
+	tests := []struct {
+		name     string
+		verifier *Verifier
+		sign     Signature
+		wantErr  error
+	}{
+		{name: "Nonce", verifier: nonce, sign: sign(SchemeNonce, now)},
+		{name: "Replay", verifier: nonce, sign: replay, wantErr: ErrReplay},
+		{name: "Skew", verifier: nonce, sign: sign(SchemeNonce, now.Add(-DefaultSkew*time.Second))},
+		{name: "Old", verifier: nonce, sign: sign(SchemeNonce, now.Add(-time.Hour)), wantErr: ErrTimestamp},
+		{name: "Future", verifier: nonce, sign: sign(SchemeNonce, now.Add(time.Hour)), wantErr: ErrTimestamp},
+		{name: "Bad hash", verifier: nonce, sign: Signature{Hash: "a", Timestamp: "1", Nonce: "a"}, wantErr: ErrBadHash},
+		{name: "Legacy rejected", verifier: nonce, sign: sign(SchemeBody, now), wantErr: ErrHashUndefined},
+		{name: "Legacy", verifier: newVerifier(SchemeBody), sign: sign(SchemeBody, now)},
+		{name: "Legacy bad hash", verifier: newVerifier(SchemeBody), sign: Signature{Hash: "a"}, wantErr: ErrBadHash},
+		{name: "Nil verifier", sign: sign(SchemeBody, now)},
+		{name: "Any legacy", verifier: anyScheme, sign: sign(SchemeBody, now)},
+		{name: "Any nonce", verifier: anyScheme, sign: sign(SchemeNonce, now.Add(time.Hour)), wantErr: ErrTimestamp},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.verifier.Verify(key, body, tt.sign)
+			assert.True(t, errors.Is(err, tt.wantErr), "Verify() error = %v, want %v", err, tt.wantErr)
+		})
+	}
+}
+
+func TestVerifier_Verify_otherKey(t *testing.T) {
+	body := []byte("body")
+	now := time.Now()
+	v := NewVerifier(Options{Scheme: SchemeNonce, Skew: DefaultSkew}, NewNonceCache())
+	first, err := Sign([]byte("first"), body, SchemeNonce, now)
+	require.NoError(t, err, "sign error")
+	second := first
+	second.Hash = Hash([]byte("second"), body, first.Timestamp, first.Nonce)
+	require.NoError(t, v.Verify([]byte("first"), body, first), "first key request")
+	assert.NoError(t, v.Verify([]byte("second"), body, second), "the same nonce of other key is not a replay")
+	assert.True(t, errors.Is(v.Verify([]byte("first"), body, first), ErrReplay), "replay error expected")
+}
+
+func TestNonceCache_Add(t *testing.T) {
+	c := NewNonceCache()
+	now := time.Now()
+	assert.True(t, c.Add("a", now.Add(time.Minute), now), "new nonce")
+	assert.False(t, c.Add("a", now.Add(time.Minute), now), "repeated nonce")
+	later := now.Add(2 * cleanInterval)
+	assert.True(t, c.Add("b", later.Add(time.Minute), later), "new nonce")
+	assert.Equal(t, 1, len(c.items), "expired nonces must be removed")
+	assert.True(t, c.Add("a", later.Add(time.Minute), later), "expired nonce can be used again")
+}
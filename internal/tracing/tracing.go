@@ -0,0 +1,155 @@
+// Package tracing configures OpenTelemetry tracing for agent and server.
+// Trace context is propagated by W3C 'traceparent' HTTP headers and gRPC metadata.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+// Exporters names.
+const (
+	ExporterNone   = ""       // spans are not exported, trace context is propagated only
+	ExporterStdout = "stdout" // spans are written as JSON, for local testing
+	ExporterOTLP   = "otlp"   // spans are sent to OTLP gRPC collector
+)
+
+// Options contains tracing settings.
+type Options struct {
+	Writer   io.Writer // stdout exporter output, os.Stdout if nil
+	Exporter string    // exporter name
+	Endpoint string    // OTLP collector address like 'host:4317'
+	Insecure bool      // flag to use OTLP without TLS
+}
+
+// Validate checks options.
+func (o Options) Validate() error {
+	switch o.Exporter {
+	case ExporterNone, ExporterStdout:
+	case ExporterOTLP:
+		if o.Endpoint == "" {
+			return fmt.Errorf("trace endpoint must be set for '%s' exporter", ExporterOTLP)
+		}
+	default:
+		return fmt.Errorf("trace exporter must be empty, '%s' or '%s'", ExporterStdout, ExporterOTLP)
+	}
+	return nil
+}
+
+// Setup sets global tracer provider and propagator.
+// Returned func flushes not exported spans and must be called before exit.
+func Setup(ctx context.Context, service string, o Options) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{},
+	))
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch o.Exporter {
+	case ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		w := o.Writer
+		if w == nil {
+			w = os.Stdout
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(w))
+	case ExporterOTLP:
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(o.Endpoint)}
+		if o.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		exporter, err = otlptracegrpc.New(ctx, opts...)
+	default:
+		return nil, o.Validate()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create trace exporter error: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(service))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start starts span with tracer of the package name.
+func Start(
+	ctx context.Context, pkg, name string, kind trace.SpanKind, attrs ...attribute.KeyValue,
+) (context.Context, trace.Span) {
+	return otel.Tracer(pkg).Start(ctx, name, trace.WithSpanKind(kind), trace.WithAttributes(attrs...))
+}
+
+// End sets span status by err and ends span.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// MetadataCarrier adapts gRPC metadata to propagation.TextMapCarrier.
+type MetadataCarrier metadata.MD
+
+// Get returns the first value of key.
+func (c MetadataCarrier) Get(key string) string {
+	values := metadata.MD(c).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// Set sets key value.
+func (c MetadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+// Keys returns all keys.
+func (c MetadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// InjectHTTP writes ctx trace context in HTTP headers.
+func InjectHTTP(ctx context.Context, h http.Header) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(h))
+}
+
+// ExtractHTTP returns ctx with trace context from HTTP headers.
+func ExtractHTTP(ctx context.Context, h http.Header) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(h))
+}
+
+// InjectGRPC writes ctx trace context in gRPC metadata.
+func InjectGRPC(ctx context.Context, md metadata.MD) {
+	otel.GetTextMapPropagator().Inject(ctx, MetadataCarrier(md))
+}
+
+// ExtractGRPC returns ctx with trace context from incoming gRPC metadata.
+func ExtractGRPC(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, MetadataCarrier(md))
+}
@@ -0,0 +1,58 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestOptions_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		o       Options
+		wantErr bool
+	}{
+		{name: "Disabled", o: Options{}},
+		{name: "Stdout", o: Options{Exporter: ExporterStdout}},
+		{name: "OTLP", o: Options{Exporter: ExporterOTLP, Endpoint: "localhost:4317"}},
+		{name: "OTLP without endpoint", o: Options{Exporter: ExporterOTLP}, wantErr: true},
+		{name: "Unknown exporter", o: Options{Exporter: "jaeger"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.o.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Options.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPropagation(t *testing.T) {
+	shutdown, err := Setup(context.Background(), "test", Options{})
+	if err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	defer shutdown(context.Background()) //nolint:errcheck //<-senselessly
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1, 2, 3},
+		SpanID:     trace.SpanID{4, 5, 6},
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), sc)
+
+	h := http.Header{}
+	InjectHTTP(ctx, h)
+	if got := trace.SpanContextFromContext(ExtractHTTP(context.Background(), h)); got.TraceID() != sc.TraceID() {
+		t.Errorf("ExtractHTTP() trace id = %s, want %s", got.TraceID(), sc.TraceID())
+	}
+
+	md := metadata.New(nil)
+	InjectGRPC(ctx, md)
+	in := metadata.NewIncomingContext(context.Background(), md)
+	if got := trace.SpanContextFromContext(ExtractGRPC(in)); got.TraceID() != sc.TraceID() {
+		t.Errorf("ExtractGRPC() trace id = %s, want %s", got.TraceID(), sc.TraceID())
+	}
+}